@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/audit"
+)
+
+// 按日期范围和用户导出播放审计日志为 csv
+//
+// 示例: go run ./cmd/audit-export -dir ./audit -from 2025-01-01 -to 2025-01-31 -o audit.csv
+func main() {
+	dir := flag.String("dir", "./audit", "审计日志存放目录")
+	from := flag.String("from", "", "开始日期 (2006-01-02), 默认与结束日期相同")
+	to := flag.String("to", "", "结束日期 (2006-01-02), 默认当天")
+	user := flag.String("user", "", "只导出指定用户 id 的记录")
+	out := flag.String("o", "", "输出文件路径, 默认输出到标准输出")
+	flag.Parse()
+
+	fromDate, toDate, err := audit.ParseDateRange(*from, *to)
+	if err != nil {
+		log.Fatal(err)
+	}
+	records, err := audit.Query(*dir, fromDate, toDate, *user)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("创建输出文件失败: %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := audit.WriteCSV(w, records); err != nil {
+		log.Fatalf("导出 csv 失败: %v", err)
+	}
+	log.Printf("共导出 %d 条审计记录", len(records))
+}
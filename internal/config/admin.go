@@ -0,0 +1,28 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// Admin 管理接口配置
+type Admin struct {
+	// Token 访问管理接口 (/ge2o/admin/*) 的密钥, 为空时不开放管理接口
+	Token string `yaml:"token"`
+	// ProtectMetrics 访问 /metrics 时是否需要携带管理接口密钥
+	ProtectMetrics bool `yaml:"protect-metrics"`
+}
+
+// Init 配置初始化
+func (a *Admin) Init() error {
+	a.Token = strings.TrimSpace(a.Token)
+	if a.ProtectMetrics && !a.Enabled() {
+		return errors.New("admin.protect-metrics 配置错误: 需要同时配置 token")
+	}
+	return nil
+}
+
+// Enabled 是否开放管理接口
+func (a *Admin) Enabled() bool {
+	return a.Token != ""
+}
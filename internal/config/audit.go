@@ -0,0 +1,34 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Audit 播放审计日志配置
+//
+// 记录每一次资源重定向的决策, 按天滚动写入 json lines 文件
+type Audit struct {
+	// Enable 是否启用
+	Enable bool `yaml:"enable"`
+	// Dir 审计日志存放目录, 默认为数据目录下的 audit 目录
+	Dir string `yaml:"dir"`
+	// RetentionDays 审计日志保留天数, 默认 30 天, 0 表示永久保留
+	RetentionDays *int `yaml:"retention-days"`
+}
+
+// Init 配置初始化
+func (a *Audit) Init() error {
+	if a.Dir = strings.TrimSpace(a.Dir); a.Dir == "" {
+		a.Dir = filepath.Join(BasePath, "audit")
+	}
+	if a.RetentionDays == nil {
+		days := 30
+		a.RetentionDays = &days
+	}
+	if *a.RetentionDays < 0 {
+		return errors.New("audit.retention-days 配置错误: 不能小于 0")
+	}
+	return nil
+}
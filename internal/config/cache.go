@@ -15,34 +15,97 @@ var durationMap = map[string]time.Duration{
 }
 
 type Cache struct {
-	Enable  bool          `yaml:"enable"`  // 是否启用缓存
-	Expired string        `yaml:"expired"` // 缓存过期时间
+	Enable  bool          `yaml:"enable"`     // 是否启用缓存
+	Expired string        `yaml:"expired"`    // 缓存过期时间
+	Link    *LinkCache    `yaml:"link-cache"` // 直链缓存配置
 	expired time.Duration // 配置初始化转换之后的标准时间对象
 }
 
+// LinkCache 直链缓存配置
+//
+// 以 openlist 路径 + 客户端 UA 类型作为 key, 缓存时长根据直链自身的过期参数计算
+type LinkCache struct {
+	Enable     bool   `yaml:"enable"`      // 是否启用直链缓存
+	DefaultTTL string `yaml:"default-ttl"` // 无法从直链中解析过期时间时的缓存时长
+	MaxTTL     string `yaml:"max-ttl"`     // 缓存时长上限
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+// Init 配置初始化
+func (lc *LinkCache) Init() error {
+	lc.defaultTTL, lc.maxTTL = time.Minute*10, time.Hour*2
+	var err error
+	if lc.DefaultTTL != "" {
+		if lc.defaultTTL, err = parseDuration(lc.DefaultTTL); err != nil {
+			return fmt.Errorf("default-ttl 配置错误: %v", err)
+		}
+	}
+	if lc.MaxTTL != "" {
+		if lc.maxTTL, err = parseDuration(lc.MaxTTL); err != nil {
+			return fmt.Errorf("max-ttl 配置错误: %v", err)
+		}
+	}
+	if lc.defaultTTL > lc.maxTTL {
+		return fmt.Errorf("default-ttl 不能大于 max-ttl")
+	}
+	return nil
+}
+
+// DefaultTTLDuration 无法解析直链过期时间时的缓存时长
+func (lc *LinkCache) DefaultTTLDuration() time.Duration {
+	return lc.defaultTTL
+}
+
+// MaxTTLDuration 缓存时长上限
+func (lc *LinkCache) MaxTTLDuration() time.Duration {
+	return lc.maxTTL
+}
+
 func (c *Cache) ExpiredDuration() time.Duration {
 	return c.expired
 }
 
 func (c *Cache) Init() error {
+	if c.Link == nil {
+		c.Link = new(LinkCache)
+	}
+	if err := c.Link.Init(); err != nil {
+		return fmt.Errorf("cache.link-cache 配置错误: %v", err)
+	}
+
 	if len(c.Expired) == 0 {
 		// 缓存默认过期时间一天
 		c.expired = time.Hour * 24
-	} else {
-		timeFlag := c.Expired[len(c.Expired)-1:]
-		duration, ok := durationMap[timeFlag]
-		if !ok {
-			return fmt.Errorf("cache.expired 配置错误: %s, 支持的时间单位: s, m, h, d", timeFlag)
-		}
-		base, err := strconv.Atoi(c.Expired[:len(c.Expired)-1])
-		if err != nil {
-			return fmt.Errorf("cache.expired 配置错误: %v", err)
-		}
-		if base < 1 {
-			return fmt.Errorf("cache.exipred 配置错误: %d, 值需大于 0", base)
-		}
-		c.expired = time.Duration(base) * duration
+		return nil
 	}
 
+	expired, err := parseDuration(c.Expired)
+	if err != nil {
+		return fmt.Errorf("cache.expired 配置错误: %v", err)
+	}
+	c.expired = expired
 	return nil
 }
+
+// parseDuration 将 "数值 + 单位" 形式的字符串配置转换成 time.Duration
+//
+// 支持的单位: d(天), h(小时), m(分钟), s(秒)
+func parseDuration(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("无效的时间配置: %s", s)
+	}
+	timeFlag := s[len(s)-1:]
+	duration, ok := durationMap[timeFlag]
+	if !ok {
+		return 0, fmt.Errorf("%s, 支持的时间单位: s, m, h, d", timeFlag)
+	}
+	base, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil {
+		return 0, err
+	}
+	if base < 1 {
+		return 0, fmt.Errorf("%d, 值需大于 0", base)
+	}
+	return time.Duration(base) * duration, nil
+}
@@ -29,6 +29,14 @@ type Config struct {
 	Oss *Oss `yaml:"oss"`
 	// GoEdge GoEdge CDN 配置
 	GoEdge *GoEdge `yaml:"goedge"`
+	// Admin 管理接口配置
+	Admin *Admin `yaml:"admin"`
+	// Notify 事件通知配置
+	Notify *Notify `yaml:"notify"`
+	// Session 播放会话追踪配置
+	Session *Session `yaml:"session"`
+	// Audit 播放审计日志配置
+	Audit *Audit `yaml:"audit"`
 }
 
 // C 全局唯一配置对象
@@ -3,12 +3,17 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/mappings"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/maps"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/randoms"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
 )
 
 // PeStrategy 代理异常策略类型
@@ -28,6 +33,19 @@ const (
 	DlStrategy403    DlStrategy = "403"    // 拒绝响应
 )
 
+// ServerType 媒体服务器类型
+type ServerType string
+
+const (
+	ServerTypeEmby     ServerType = "emby"     // Emby
+	ServerTypeJellyfin ServerType = "jellyfin" // Jellyfin
+)
+
+// validServerType 用于校验用户配置的服务器类型是否合法
+var validServerType = map[ServerType]struct{}{
+	ServerTypeEmby: {}, ServerTypeJellyfin: {},
+}
+
 // validPeStrategy 用于校验用户配置的策略是否合法
 var validPeStrategy = map[PeStrategy]struct{}{
 	PeStrategyOrigin: {}, PeStrategyReject: {},
@@ -40,8 +58,14 @@ var validDlStrategy = map[DlStrategy]struct{}{
 
 // Emby 相关配置
 type Emby struct {
+	// Name 名称, 用于日志输出以及区分不同后端的缓存, 顶层配置默认为 default
+	Name string `yaml:"name"`
+	// ServerType 源服务器类型, 默认为 emby
+	ServerType ServerType `yaml:"server-type"`
 	// Emby 源服务器地址
 	Host string `yaml:"host"`
+	// ApiKey emby 管理后台生成的 api key, 用于程序主动请求 emby 接口
+	ApiKey string `yaml:"api-key"`
 	// rclone 或者 cd 的挂载目录
 	MountPath string `yaml:"mount-path"`
 	// EpisodesUnplayPrior 在获取剧集列表时是否将未播资源优先展示
@@ -58,14 +82,157 @@ type Emby struct {
 	DownloadStrategy DlStrategy `yaml:"download-strategy"`
 	// LocalMediaRoot 本地媒体根路径
 	LocalMediaRoot string `yaml:"local-media-root"`
+	// LocalMediaServe 本地媒体直接由代理程序读取文件响应
+	LocalMediaServe *LocalMediaServe `yaml:"local-media-serve"`
 	// ItemsCounts 媒体库统计自定义配置
 	ItemsCounts *ItemsCountsConfig `yaml:"items-counts"`
+	// ResolutionCache 媒体路径解析结果缓存配置
+	ResolutionCache *ResolutionCache `yaml:"resolution-cache"`
+	// Prefetch 下一集直链预取配置
+	Prefetch *Prefetch `yaml:"prefetch"`
+	// Backends 额外的 emby 后端, 根据请求的 Host 头, 监听端口或路径前缀选择, 未匹配的请求使用顶层配置
+	Backends []*EmbyBackend `yaml:"backends"`
+
+	// pathMapper 后端独立的 emby2openlist 路径映射, 为空时使用 path.emby2openlist
+	pathMapper *mappings.Mapper
+}
+
+// EmbyBackend 额外的 emby 后端配置
+//
+// 除匹配条件外, 其余配置项与顶层 emby 配置一致, 未配置的项使用默认值, 不继承顶层配置
+type EmbyBackend struct {
+	Emby `yaml:",inline"`
+	// Hosts 匹配请求的 Host 头 (不含端口)
+	Hosts []string `yaml:"hosts"`
+	// Port 额外监听的 http 端口, 从该端口进入的请求使用当前后端
+	Port string `yaml:"port"`
+	// PathPrefix 匹配请求路径前缀, 代理到 emby 之前会移除该前缀
+	PathPrefix string `yaml:"path-prefix"`
+	// Emby2Openlist 后端独立的路径映射, 规则格式与 path.emby2openlist 一致, 不配置时使用 path.emby2openlist
+	Emby2Openlist []mappings.Rule `yaml:"emby2openlist"`
+}
+
+// Init 配置初始化
+func (eb *EmbyBackend) Init() error {
+	if strings.TrimSpace(eb.Name) == "" {
+		return errors.New("name 不能为空")
+	}
+	if len(eb.Hosts) == 0 && eb.Port == "" && eb.PathPrefix == "" {
+		return errors.New("hosts, port, path-prefix 至少需要配置一项")
+	}
+	if len(eb.Backends) > 0 {
+		return errors.New("不支持嵌套配置 backends")
+	}
+	if eb.Port == webport.HTTP || eb.Port == webport.HTTPS {
+		return fmt.Errorf("port 配置错误: [%s] 与程序的监听端口冲突", eb.Port)
+	}
+	if eb.PathPrefix != "" {
+		if !strings.HasPrefix(eb.PathPrefix, "/") {
+			return fmt.Errorf("path-prefix 配置错误: [%s] 必须以 / 开头", eb.PathPrefix)
+		}
+		eb.PathPrefix = strings.TrimSuffix(eb.PathPrefix, "/")
+	}
+	for i, host := range eb.Hosts {
+		eb.Hosts[i] = strings.ToLower(strings.TrimSpace(host))
+	}
+	if err := eb.Emby.Init(); err != nil {
+		return err
+	}
+	if len(eb.Emby2Openlist) > 0 {
+		eb.pathMapper = newLenientMapper("emby.backends["+eb.Name+"].emby2openlist", eb.Emby2Openlist)
+	}
+	return nil
+}
+
+// matches 判断请求是否由当前后端处理, port 为请求进入的监听端口
+func (eb *EmbyBackend) matches(host, port, path string) bool {
+	if eb.Port != "" && eb.Port == port {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if slices.Contains(eb.Hosts, strings.ToLower(host)) {
+		return true
+	}
+	return eb.matchesPrefix(path)
+}
+
+// matchesPrefix 判断请求路径是否以后端的路径前缀开头, 前缀必须是完整的路径片段
+func (eb *EmbyBackend) matchesPrefix(path string) bool {
+	return eb.PathPrefix != "" && (path == eb.PathPrefix || strings.HasPrefix(path, eb.PathPrefix+"/"))
+}
+
+// Select 根据请求的 Host 头, 监听端口以及请求路径选择 emby 后端
+//
+// 返回选中的后端配置, 以及需要从请求路径中移除的前缀, 没有后端匹配时返回顶层配置
+func (e *Emby) Select(host, port, path string) (*Emby, string) {
+	for _, eb := range e.Backends {
+		if eb.matches(host, port, path) {
+			prefix := ""
+			if eb.matchesPrefix(path) {
+				prefix = eb.PathPrefix
+			}
+			return &eb.Emby, prefix
+		}
+	}
+	return e, ""
+}
+
+// Backend 根据名称获取 emby 后端配置, 包括顶层配置
+func (e *Emby) Backend(name string) (*Emby, bool) {
+	if name == e.Name {
+		return e, true
+	}
+	for _, eb := range e.Backends {
+		if eb.Name == name {
+			return &eb.Emby, true
+		}
+	}
+	return nil, false
+}
+
+// All 获取所有 emby 后端配置, 顶层配置排在第一个
+func (e *Emby) All() []*Emby {
+	res := []*Emby{e}
+	for _, eb := range e.Backends {
+		res = append(res, &eb.Emby)
+	}
+	return res
+}
+
+// IsJellyfin 源服务器是否为 Jellyfin
+func (e *Emby) IsJellyfin() bool {
+	return e.ServerType == ServerTypeJellyfin
+}
+
+// MapEmby2Openlist 将 emby 路径映射成 openlist 路径, 后端未配置独立映射时使用 path.emby2openlist
+func (e *Emby) MapEmby2Openlist(embyPath string) (string, bool) {
+	if e.pathMapper == nil {
+		return C.Path.MapEmby2Openlist(embyPath)
+	}
+	res, rule, ok := e.pathMapper.Map(embyPath)
+	if !ok {
+		return "", false
+	}
+	logs.Tip("命中 emby 后端 [%s] 的路径映射: %s", e.Name, rule)
+	return res, true
 }
 
 func (e *Emby) Init() error {
+	if e.Name == "" {
+		e.Name = "default"
+	}
 	if strs.AnyEmpty(e.Host) {
 		return errors.New("emby.host 配置不能为空")
 	}
+	e.ServerType = ServerType(strings.ToLower(strings.TrimSpace(string(e.ServerType))))
+	if e.ServerType == "" {
+		e.ServerType = ServerTypeEmby
+	}
+	if _, ok := validServerType[e.ServerType]; !ok {
+		return fmt.Errorf("emby.server-type 配置错误, 有效值: %v", maps.Keys(validServerType))
+	}
 	if strs.AnyEmpty(string(e.ProxyErrorStrategy)) {
 		// 失败默认回源
 		e.ProxyErrorStrategy = PeStrategyOrigin
@@ -105,6 +272,13 @@ func (e *Emby) Init() error {
 		e.LocalMediaRoot = "/" + randoms.RandomHex(32)
 	}
 
+	if e.LocalMediaServe == nil {
+		e.LocalMediaServe = new(LocalMediaServe)
+	}
+	if err := e.LocalMediaServe.Init(); err != nil {
+		return fmt.Errorf("emby.local-media-serve 配置错误: %v", err)
+	}
+
 	// 初始化 ItemsCounts 配置
 	if e.ItemsCounts == nil {
 		e.ItemsCounts = &ItemsCountsConfig{}
@@ -113,45 +287,246 @@ func (e *Emby) Init() error {
 		return fmt.Errorf("emby.items-counts 配置错误: %v", err)
 	}
 
+	if e.ResolutionCache == nil {
+		e.ResolutionCache = new(ResolutionCache)
+	}
+	if err := e.ResolutionCache.Init(); err != nil {
+		return fmt.Errorf("emby.resolution-cache 配置错误: %v", err)
+	}
+
+	if e.Prefetch == nil {
+		e.Prefetch = new(Prefetch)
+	}
+	if err := e.Prefetch.Init(); err != nil {
+		return fmt.Errorf("emby.prefetch 配置错误: %v", err)
+	}
+
+	names, ports := map[string]struct{}{e.Name: {}}, map[string]struct{}{}
+	for i, eb := range e.Backends {
+		if eb == nil {
+			return fmt.Errorf("emby.backends[%d] 配置不能为空", i)
+		}
+		if err := eb.Init(); err != nil {
+			return fmt.Errorf("emby.backends[%d] 配置错误: %v", i, err)
+		}
+		if _, ok := names[eb.Name]; ok {
+			return fmt.Errorf("emby.backends[%d] 配置错误: 名称 [%s] 重复", i, eb.Name)
+		}
+		names[eb.Name] = struct{}{}
+		if eb.Port == "" {
+			continue
+		}
+		if _, ok := ports[eb.Port]; ok {
+			return fmt.Errorf("emby.backends[%d] 配置错误: 端口 [%s] 重复", i, eb.Port)
+		}
+		ports[eb.Port] = struct{}{}
+	}
+
+	return nil
+}
+
+// ResolutionCache 媒体路径解析结果缓存配置
+//
+// 缓存 itemId + mediaSourceId 对应的 emby 路径以及 openlist 路径,
+// 重复播放时跳过 PlaybackInfo 请求以及路径转换
+type ResolutionCache struct {
+	// Enable 是否启用缓存
+	Enable bool `yaml:"enable"`
+	// Refresh 缓存刷新间隔, 超过间隔的缓存在下次使用时会重新向 emby 校验路径
+	Refresh string `yaml:"refresh"`
+	// refresh 配置初始化转换之后的标准时间对象
+	refresh time.Duration
+}
+
+// Init 配置初始化
+func (rc *ResolutionCache) Init() error {
+	if rc.Refresh == "" {
+		rc.refresh = time.Hour * 24
+		return nil
+	}
+	refresh, err := parseDuration(rc.Refresh)
+	if err != nil {
+		return fmt.Errorf("refresh 配置错误: %v", err)
+	}
+	rc.refresh = refresh
+	return nil
+}
+
+// RefreshDuration 缓存刷新间隔
+func (rc *ResolutionCache) RefreshDuration() time.Duration {
+	return rc.refresh
+}
+
+// LocalMediaServe 本地媒体服务配置
+//
+// 代理程序与 emby 能访问同一个文件系统时, 由代理程序直接读取本地媒体文件响应客户端,
+// 减轻 emby 的推流压力
+type LocalMediaServe struct {
+	// Enable 是否启用
+	Enable bool `yaml:"enable"`
+	// PathMap emby 路径到代理程序本地路径的映射, 默认按前缀进行替换, 不配置表示路径相同
+	PathMap []mappings.Rule `yaml:"path-map"`
+
+	// pathMapper 配置初始化后编译生成的映射器
+	pathMapper *mappings.Mapper
+}
+
+// Init 配置初始化
+func (lms *LocalMediaServe) Init() error {
+	m, err := mappings.New(lms.PathMap, mappings.TypePrefix)
+	if err != nil {
+		return fmt.Errorf("path-map 配置错误: %v", err)
+	}
+	lms.pathMapper = m
+	return nil
+}
+
+// MapPath 将 emby 中的媒体路径映射为代理程序可以访问的本地路径, 匹配不到规则时返回原路径
+func (lms *LocalMediaServe) MapPath(embyPath string) string {
+	if res, _, ok := lms.pathMapper.Map(embyPath); ok {
+		return res
+	}
+	return embyPath
+}
+
+// Prefetch 下一集直链预取配置
+//
+// 剧集播放进度超过指定百分比时, 异步解析并缓存下一集的直链
+type Prefetch struct {
+	// Enable 是否启用预取
+	Enable bool `yaml:"enable"`
+	// Percent 触发预取的播放进度百分比
+	Percent int `yaml:"percent"`
+}
+
+// Init 配置初始化
+func (p *Prefetch) Init() error {
+	if p.Percent == 0 {
+		p.Percent = 80
+	}
+	if p.Percent < 1 || p.Percent > 99 {
+		return fmt.Errorf("percent 配置错误: %d, 允许配置范围: [1, 99]", p.Percent)
+	}
 	return nil
 }
 
 // Strm strm 配置
 type Strm struct {
-	// PathMap 远程路径映射
-	PathMap []string `yaml:"path-map"`
+	// PathMap 远程路径映射, 默认按片段进行替换
+	PathMap []mappings.Rule `yaml:"path-map"`
 	// InternalRedirectEnable 是否启用 strm 内部重定向
 	InternalRedirectEnable bool `yaml:"internal-redirect-enable"`
+	// Headers 内部重定向时, 根据链接匹配需要携带的请求头
+	Headers []*StrmHeaderRule `yaml:"headers"`
+	// FinalLinkCacheTTL 内部重定向得到的最终链接缓存时间, 配置为 0s 表示不缓存
+	FinalLinkCacheTTL string `yaml:"final-link-cache-ttl"`
+	// OpenlistPrefixes 以这些前缀开头的 strm 内容视为 openlist 路径, 直接通过 openlist 获取直链
+	OpenlistPrefixes []string `yaml:"openlist-prefixes"`
+	// PassthroughSchemes 使用这些协议的 strm 链接不做任何处理, 直接交给 emby 回源播放
+	PassthroughSchemes []string `yaml:"passthrough-schemes"`
+
+	// pathMapper 配置初始化后编译生成的映射器
+	pathMapper *mappings.Mapper
+	// finalLinkCacheTTL 配置初始化转换之后的标准时间对象
+	finalLinkCacheTTL time.Duration
+}
 
-	// pathMap 配置初始化后转换为二维数组切片结构
-	pathMap [][2]string
+// StrmHeaderRule strm 内部重定向请求头规则
+type StrmHeaderRule struct {
+	// Match 链接中包含该片段时命中规则, 如域名
+	Match string `yaml:"match"`
+	// Header 命中规则时设置的请求头, 如 Referer, User-Agent, Cookie
+	Header map[string]string `yaml:"header"`
 }
 
 // Init 配置初始化
 func (s *Strm) Init() error {
-	s.pathMap = make([][2]string, 0, len(s.PathMap))
-	for _, path := range s.PathMap {
-		splits := strings.Split(path, "=>")
-		if len(splits) != 2 {
-			return fmt.Errorf("映射配置不规范: %s, 请使用 => 进行分割", path)
+	m, err := mappings.New(s.PathMap, mappings.TypeContains)
+	if err != nil {
+		return fmt.Errorf("path-map 配置错误: %v", err)
+	}
+	s.pathMapper = m
+
+	for i, rule := range s.Headers {
+		if rule == nil || strings.TrimSpace(rule.Match) == "" {
+			return fmt.Errorf("headers[%d] 配置错误: match 不能为空", i)
+		}
+	}
+
+	switch s.FinalLinkCacheTTL {
+	case "":
+		s.finalLinkCacheTTL = time.Minute * 10
+	case "0", "0s":
+		s.finalLinkCacheTTL = 0
+	default:
+		if s.finalLinkCacheTTL, err = parseDuration(s.FinalLinkCacheTTL); err != nil {
+			return fmt.Errorf("final-link-cache-ttl 配置错误: %v", err)
+		}
+	}
+
+	for i, prefix := range s.OpenlistPrefixes {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("openlist-prefixes[%d] 配置错误: [%s] 必须以 / 开头", i, prefix)
 		}
-		from, to := strings.TrimSpace(splits[0]), strings.TrimSpace(splits[1])
-		s.pathMap = append(s.pathMap, [2]string{from, to})
+	}
+
+	if s.PassthroughSchemes == nil {
+		s.PassthroughSchemes = []string{"rtmp", "rtmps", "rtsp", "rtsps"}
+	}
+	for i, scheme := range s.PassthroughSchemes {
+		s.PassthroughSchemes[i] = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(scheme), "://"))
 	}
 	return nil
 }
 
+// HeaderOf 获取内部重定向请求链接 link 时需要设置的请求头, 多个规则命中时按配置顺序覆盖
+func (s *Strm) HeaderOf(link string) map[string]string {
+	res := map[string]string{}
+	for _, rule := range s.Headers {
+		if !strings.Contains(link, rule.Match) {
+			continue
+		}
+		for k, v := range rule.Header {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// FinalLinkCacheTTLDuration 最终链接缓存时间, 为 0 表示不缓存
+func (s *Strm) FinalLinkCacheTTLDuration() time.Duration {
+	return s.finalLinkCacheTTL
+}
+
+// IsOpenlistPath 判断 strm 内容是否为 openlist 路径
+func (s *Strm) IsOpenlistPath(path string) bool {
+	for _, prefix := range s.OpenlistPrefixes {
+		p := strings.TrimSuffix(prefix, "/")
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPassthrough 判断 strm 链接是否使用需要直接交给 emby 处理的协议
+func (s *Strm) IsPassthrough(link string) bool {
+	idx := strings.Index(link, "://")
+	if idx == -1 {
+		return false
+	}
+	return slices.Contains(s.PassthroughSchemes, strings.ToLower(link[:idx]))
+}
+
 // MapPath 将传入路径按照预配置的映射关系从上到下按顺序进行映射,
 // 至多成功映射一次
 func (s *Strm) MapPath(path string) string {
-	for _, m := range s.pathMap {
-		from, to := m[0], m[1]
-		if strings.Contains(path, from) {
-			logs.Tip("映射路径: [%s] => [%s]", from, to)
-			return strings.Replace(path, from, to, 1)
-		}
+	res, rule, ok := s.pathMapper.Map(path)
+	if !ok {
+		return path
 	}
-	return path
+	logs.Tip("映射路径: %s", rule)
+	return res
 }
 
 // ItemsCountsConfig 媒体库统计自定义配置
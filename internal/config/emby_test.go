@@ -0,0 +1,144 @@
+package config
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestEmbyBackendMatches(t *testing.T) {
+	eb := &EmbyBackend{Hosts: []string{"jf.example.com"}, Port: "8097", PathPrefix: "/jf"}
+	tests := []struct {
+		name string
+		host string
+		port string
+		path string
+		want bool
+	}{
+		{name: "端口匹配", host: "other.com", port: "8097", path: "/emby/items", want: true},
+		{name: "host 匹配", host: "jf.example.com", port: "8095", path: "/", want: true},
+		{name: "host 带端口", host: "jf.example.com:8095", port: "8095", path: "/", want: true},
+		{name: "host 忽略大小写", host: "JF.Example.com", port: "8095", path: "/", want: true},
+		{name: "路径前缀", host: "other.com", port: "8095", path: "/jf/items/1", want: true},
+		{name: "路径等于前缀", host: "other.com", port: "8095", path: "/jf", want: true},
+		{name: "路径前缀不完整", host: "other.com", port: "8095", path: "/jfx/items", want: false},
+		{name: "均不匹配", host: "other.com", port: "8095", path: "/emby/items", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := eb.matches(tt.host, tt.port, tt.path); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbySelect(t *testing.T) {
+	e := &Emby{Name: "main", Backends: []*EmbyBackend{
+		{Emby: Emby{Name: "by-host"}, Hosts: []string{"a.com"}},
+		{Emby: Emby{Name: "by-port"}, Port: "8097"},
+		{Emby: Emby{Name: "by-prefix"}, PathPrefix: "/jf"},
+		{Emby: Emby{Name: "host-and-prefix"}, Hosts: []string{"b.com"}, PathPrefix: "/b"},
+	}}
+	tests := []struct {
+		name       string
+		host       string
+		port       string
+		path       string
+		wantName   string
+		wantPrefix string
+	}{
+		{name: "未匹配使用顶层配置", host: "c.com", port: "8095", path: "/emby/items", wantName: "main"},
+		{name: "host 匹配", host: "a.com", port: "8095", path: "/jf/items", wantName: "by-host"},
+		{name: "端口匹配", host: "c.com", port: "8097", path: "/emby/items", wantName: "by-port"},
+		{name: "路径前缀匹配", host: "c.com", port: "8095", path: "/jf/emby/items", wantName: "by-prefix", wantPrefix: "/jf"},
+		{name: "host 匹配时携带的前缀同样移除", host: "b.com", port: "8095", path: "/b/emby/items", wantName: "host-and-prefix", wantPrefix: "/b"},
+		{name: "host 匹配时未携带前缀", host: "b.com", port: "8095", path: "/emby/items", wantName: "host-and-prefix"},
+		{name: "host 匹配时前缀不完整", host: "b.com", port: "8095", path: "/bar/items", wantName: "host-and-prefix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, prefix := e.Select(tt.host, tt.port, tt.path)
+			if got.Name != tt.wantName || prefix != tt.wantPrefix {
+				t.Errorf("Select() = (%s, %q), want (%s, %q)", got.Name, prefix, tt.wantName, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestStrm(t *testing.T) {
+	s := &Strm{
+		Headers: []*StrmHeaderRule{
+			{Match: "cdn.example.com", Header: map[string]string{"Referer": "https://example.com", "User-Agent": "a"}},
+			{Match: "/vip/", Header: map[string]string{"User-Agent": "b"}},
+		},
+		OpenlistPrefixes:   []string{"/115", "/ali/"},
+		PassthroughSchemes: []string{"RTSP://", " udp "},
+	}
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("HeaderOf", func(t *testing.T) {
+		tests := []struct {
+			link string
+			want map[string]string
+		}{
+			{"http://other.com/a.mkv", map[string]string{}},
+			{"http://cdn.example.com/a.mkv", map[string]string{"Referer": "https://example.com", "User-Agent": "a"}},
+			{"http://cdn.example.com/vip/a.mkv", map[string]string{"Referer": "https://example.com", "User-Agent": "b"}},
+		}
+		for _, tt := range tests {
+			if got := s.HeaderOf(tt.link); !maps.Equal(got, tt.want) {
+				t.Errorf("HeaderOf(%s) = %v, want %v", tt.link, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("IsPassthrough", func(t *testing.T) {
+		tests := []struct {
+			link string
+			want bool
+		}{
+			{"rtsp://cam/live", true},
+			{"UDP://239.0.0.1:1234", true},
+			{"rtmp://live/a", false},
+			{"http://cdn/a.mkv", false},
+			{"/115/a.mkv", false},
+		}
+		for _, tt := range tests {
+			if got := s.IsPassthrough(tt.link); got != tt.want {
+				t.Errorf("IsPassthrough(%s) = %v, want %v", tt.link, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("IsOpenlistPath", func(t *testing.T) {
+		tests := []struct {
+			path string
+			want bool
+		}{
+			{"/115/a.mkv", true},
+			{"/115", true},
+			{"/ali/movie/a.mkv", true},
+			{"/1150/a.mkv", false},
+			{"/alist/a.mkv", false},
+			{"http://cdn/115/a.mkv", false},
+		}
+		for _, tt := range tests {
+			if got := s.IsOpenlistPath(tt.path); got != tt.want {
+				t.Errorf("IsOpenlistPath(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("默认协议", func(t *testing.T) {
+		d := &Strm{}
+		if err := d.Init(); err != nil {
+			t.Fatal(err)
+		}
+		if !d.IsPassthrough("rtmp://live/a") || d.FinalLinkCacheTTLDuration() != 10*time.Minute {
+			t.Errorf("默认配置错误: %v %v", d.PassthroughSchemes, d.FinalLinkCacheTTLDuration())
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/mappings"
 )
 
 // goedgePathCacheItem 路径缓存项（带过期时间）
@@ -22,14 +23,14 @@ type GoEdge struct {
 	// Endpoint GoEdge CDN 访问域名
 	Endpoint string `yaml:"endpoint"`
 	// PathMapping Emby路径到GoEdge路径的映射关系
-	PathMapping []string `yaml:"path-mapping"`
+	PathMapping []mappings.Rule `yaml:"path-mapping"`
 	// PathMappingCacheTTL 路径映射缓存过期时间（秒），默认 3600 秒（1小时）
 	PathMappingCacheTTL int64 `yaml:"path-mapping-cache-ttl"`
 	// Auth GoEdge 鉴权配置
 	Auth *GoEdgeAuth `yaml:"auth"`
 
-	// 内部使用的映射表
-	pathMappingTable map[string]string
+	// 内部使用的映射器
+	pathMapper *mappings.Mapper
 	// 路径映射缓存（提升性能，避免重复计算）
 	pathMappingCache sync.Map
 }
@@ -72,25 +73,9 @@ func (g *GoEdge) Init() error {
 		g.PathMappingCacheTTL = 3600
 	}
 
-	// 初始化路径映射表
-	g.pathMappingTable = make(map[string]string)
-	for _, mapping := range g.PathMapping {
-		parts := strings.Split(mapping, ":")
-		if len(parts) != 2 {
-			logs.Warn("无效的路径映射配置: %s, 跳过", mapping)
-			continue
-		}
-		embyPath := strings.TrimSpace(parts[0])
-		goedgePath := strings.TrimSpace(parts[1])
-		if embyPath == "" || goedgePath == "" {
-			logs.Warn("无效的路径映射配置: %s, 跳过", mapping)
-			continue
-		}
-		g.pathMappingTable[embyPath] = goedgePath
-		logs.Info("GoEdge 路径映射: %s -> %s", embyPath, goedgePath)
-	}
-
-	if len(g.pathMappingTable) == 0 {
+	// 初始化路径映射器
+	g.pathMapper = newLenientMapper("GoEdge", g.PathMapping)
+	if g.pathMapper.Len() == 0 {
 		logs.Warn("未配置有效的 GoEdge 路径映射")
 	}
 
@@ -115,7 +100,7 @@ func (g *GoEdge) Init() error {
 
 // MapPath 将 Emby 路径映射为 GoEdge 路径
 func (g *GoEdge) MapPath(embyPath string) (string, error) {
-	if g.pathMappingTable == nil {
+	if g.pathMapper == nil {
 		return "", fmt.Errorf("路径映射表未初始化")
 	}
 
@@ -130,19 +115,15 @@ func (g *GoEdge) MapPath(embyPath string) (string, error) {
 		g.pathMappingCache.Delete(embyPath)
 	}
 
-	// 遍历映射表,找到匹配的前缀
-	for embyPrefix, goedgePrefix := range g.pathMappingTable {
-		if strings.HasPrefix(embyPath, embyPrefix) {
-			// 替换前缀
-			goedgePath := strings.Replace(embyPath, embyPrefix, goedgePrefix, 1)
-			// 存入缓存（带过期时间）
-			item := &goedgePathCacheItem{
-				value:    goedgePath,
-				expireAt: time.Now().Add(time.Duration(g.PathMappingCacheTTL) * time.Second),
-			}
-			g.pathMappingCache.Store(embyPath, item)
-			return goedgePath, nil
+	// 按配置顺序匹配映射规则
+	if goedgePath, _, ok := g.pathMapper.Map(embyPath); ok {
+		// 存入缓存（带过期时间）
+		item := &goedgePathCacheItem{
+			value:    goedgePath,
+			expireAt: time.Now().Add(time.Duration(g.PathMappingCacheTTL) * time.Second),
 		}
+		g.pathMappingCache.Store(embyPath, item)
+		return goedgePath, nil
 	}
 
 	return "", fmt.Errorf("无法映射 Emby 路径到 GoEdge 路径: %s", embyPath)
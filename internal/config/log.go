@@ -1,19 +1,93 @@
 package config
 
-import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+)
+
+// 日志输出格式
+const (
+	LogFormatText = "text" // 文本格式
+	LogFormatJson = "json" // json 格式
+)
 
 // Log 日志配置
 type Log struct {
 	DisableColor bool `yaml:"disable-color"` // 是否禁用彩色日志输出
+	// Level 最低输出级别: debug, info, warn, error, 默认 debug
+	Level string `yaml:"level"`
+	// Format 输出格式: text, json, 默认 text
+	Format string `yaml:"format"`
+	// File 日志文件路径, 相对路径基于数据目录, 为空时只输出到控制台
+	File string `yaml:"file"`
+	// MaxSize 单个日志文件的大小上限 (MB), 默认 100
+	MaxSize int `yaml:"max-size"`
+	// MaxAge 滚动后的日志文件保留天数, 默认 7, 0 表示永久保留
+	MaxAge *int `yaml:"max-age"`
+	// DisableRedact 是否关闭日志中的敏感信息 (api_key, token, 签名等) 脱敏
+	DisableRedact bool `yaml:"disable-redact"`
+	// RedactPatterns 额外的脱敏正则表达式, 捕获组匹配的内容会被隐藏, 没有捕获组时隐藏整个匹配内容
+	RedactPatterns []string `yaml:"redact-patterns"`
 }
 
 // Init 配置初始化
 func (lc *Log) Init() error {
 	colors.SetEnabler(lc)
+
+	if lc.Level = strings.TrimSpace(lc.Level); lc.Level == "" {
+		lc.Level = logs.LevelDebug.String()
+	}
+	level, err := logs.ParseLevel(lc.Level)
+	if err != nil {
+		return fmt.Errorf("log.level 配置错误: %v", err)
+	}
+
+	if lc.Format = strings.TrimSpace(lc.Format); lc.Format == "" {
+		lc.Format = LogFormatText
+	}
+	if lc.Format != LogFormatText && lc.Format != LogFormatJson {
+		return fmt.Errorf("log.format 配置错误: %s, 可选值: %s, %s", lc.Format, LogFormatText, LogFormatJson)
+	}
+
+	if lc.MaxSize <= 0 {
+		lc.MaxSize = 100
+	}
+	if lc.MaxAge == nil {
+		days := 7
+		lc.MaxAge = &days
+	}
+	if *lc.MaxAge < 0 {
+		return fmt.Errorf("log.max-age 配置错误: 不能小于 0")
+	}
+
+	opts := logs.Options{Level: level, Json: lc.Format == LogFormatJson, DisableRedact: lc.DisableRedact}
+	for _, pattern := range lc.RedactPatterns {
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("log.redact-patterns 配置错误: %s, err: %v", pattern, err)
+		}
+		opts.RedactPatterns = append(opts.RedactPatterns, reg)
+	}
+	if lc.File = strings.TrimSpace(lc.File); lc.File != "" {
+		if !filepath.IsAbs(lc.File) {
+			lc.File = filepath.Join(BasePath, lc.File)
+		}
+		rw, err := logs.NewRotateWriter(lc.File, lc.MaxSize, *lc.MaxAge)
+		if err != nil {
+			return fmt.Errorf("log.file 配置错误: %v", err)
+		}
+		opts.File = rw
+	}
+	logs.Setup(opts)
 	return nil
 }
 
 // EnableColor 标记是否启用颜色输出
 func (lc *Log) EnableColor() bool {
-	return !lc.DisableColor
+	return !lc.DisableColor && lc.Format != LogFormatJson
 }
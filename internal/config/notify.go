@@ -0,0 +1,167 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/maps"
+)
+
+// NotifySinkType 通知渠道类型
+type NotifySinkType string
+
+const (
+	NotifySinkWebhook  NotifySinkType = "webhook"  // 通用 http webhook, 以 json 格式推送事件
+	NotifySinkTelegram NotifySinkType = "telegram" // Telegram 机器人
+	NotifySinkBark     NotifySinkType = "bark"     // Bark
+	NotifySinkNtfy     NotifySinkType = "ntfy"     // ntfy
+	NotifySinkGotify   NotifySinkType = "gotify"   // Gotify
+)
+
+// validNotifySinkType 用于校验用户配置的通知渠道类型是否合法
+var validNotifySinkType = map[NotifySinkType]struct{}{
+	NotifySinkWebhook: {}, NotifySinkTelegram: {}, NotifySinkBark: {}, NotifySinkNtfy: {}, NotifySinkGotify: {},
+}
+
+const (
+	// DefaultNotifyTitleTemplate 默认的通知标题模板
+	DefaultNotifyTitleTemplate = `{{if .Title}}{{.Title}}{{else}}{{.Type}}{{end}}`
+
+	// DefaultNotifyBodyTemplate 默认的通知内容模板
+	DefaultNotifyBodyTemplate = `事件: {{.Type}}
+{{if .User}}用户: {{.User}}
+{{end}}{{if .Item}}媒体: {{.Item}}
+{{end}}{{if .Client}}客户端: {{.Client}} {{.Device}}
+{{end}}{{if .Backend}}后端: {{.Backend}}
+{{end}}{{range $k, $v := .Extra}}{{$k}}: {{$v}}
+{{end}}`
+
+	// DefaultTelegramApi Telegram 机器人接口地址
+	DefaultTelegramApi = "https://api.telegram.org"
+)
+
+// Notify 事件通知配置
+type Notify struct {
+	// Enable 是否启用
+	Enable bool `yaml:"enable"`
+	// WebhookToken 接收 emby webhook 时校验的密钥, 通过 query 参数 token 传递, 为空时不接收 webhook
+	WebhookToken string `yaml:"webhook-token"`
+	// Sinks 通知渠道, 每个事件会推送到所有订阅了该事件的渠道
+	Sinks []*NotifySink `yaml:"sinks"`
+}
+
+// Init 配置初始化
+func (n *Notify) Init() error {
+	n.WebhookToken = strings.TrimSpace(n.WebhookToken)
+	if !n.Enable {
+		return nil
+	}
+	if n.WebhookToken == "" {
+		return errors.New("notify.webhook-token 配置错误: 启用通知时不能为空")
+	}
+	for i, sink := range n.Sinks {
+		if sink == nil {
+			return fmt.Errorf("notify.sinks[%d] 配置不能为空", i)
+		}
+		if err := sink.Init(); err != nil {
+			return fmt.Errorf("notify.sinks[%d] 配置错误: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// NotifySink 通知渠道配置
+type NotifySink struct {
+	// Name 渠道名称, 用于日志输出
+	Name string `yaml:"name"`
+	// Type 渠道类型
+	Type NotifySinkType `yaml:"type"`
+	// Url 推送地址
+	//
+	// webhook: 完整的推送地址; telegram: 接口地址, 默认为 https://api.telegram.org;
+	// bark: https://api.day.app/<device_key>; ntfy: https://ntfy.sh/<topic>; gotify: 服务地址
+	Url string `yaml:"url"`
+	// Token 渠道密钥, telegram 为机器人 token, gotify 为应用 token, ntfy 为访问 token (可选)
+	Token string `yaml:"token"`
+	// ChatId telegram 推送的会话 id
+	ChatId string `yaml:"chat-id"`
+	// Events 订阅的事件类型, 为空时订阅所有事件
+	Events []string `yaml:"events"`
+	// Title 标题模板, 使用 go template 语法
+	Title string `yaml:"title"`
+	// Template 内容模板, 使用 go template 语法
+	Template string `yaml:"template"`
+
+	titleTpl *template.Template // 编译后的标题模板
+	bodyTpl  *template.Template // 编译后的内容模板
+}
+
+// Init 配置初始化
+func (ns *NotifySink) Init() error {
+	ns.Type = NotifySinkType(strings.ToLower(strings.TrimSpace(string(ns.Type))))
+	if _, ok := validNotifySinkType[ns.Type]; !ok {
+		return fmt.Errorf("type 配置错误: [%s], 有效值: %v", ns.Type, maps.Keys(validNotifySinkType))
+	}
+	if ns.Name = strings.TrimSpace(ns.Name); ns.Name == "" {
+		ns.Name = string(ns.Type)
+	}
+
+	ns.Url = strings.TrimSuffix(strings.TrimSpace(ns.Url), "/")
+	if ns.Url == "" && ns.Type == NotifySinkTelegram {
+		ns.Url = DefaultTelegramApi
+	}
+	if ns.Url == "" {
+		return errors.New("url 不能为空")
+	}
+	if ns.Type == NotifySinkTelegram && (ns.Token == "" || ns.ChatId == "") {
+		return errors.New("telegram 渠道需要配置 token 和 chat-id")
+	}
+	if ns.Type == NotifySinkGotify && ns.Token == "" {
+		return errors.New("gotify 渠道需要配置 token")
+	}
+
+	for i, event := range ns.Events {
+		ns.Events[i] = strings.ToLower(strings.TrimSpace(event))
+	}
+
+	if ns.Title == "" {
+		ns.Title = DefaultNotifyTitleTemplate
+	}
+	if ns.Template == "" {
+		ns.Template = DefaultNotifyBodyTemplate
+	}
+	var err error
+	if ns.titleTpl, err = template.New(ns.Name + "-title").Parse(ns.Title); err != nil {
+		return fmt.Errorf("title 模板解析失败: %v", err)
+	}
+	if ns.bodyTpl, err = template.New(ns.Name + "-body").Parse(ns.Template); err != nil {
+		return fmt.Errorf("template 模板解析失败: %v", err)
+	}
+	return nil
+}
+
+// Subscribed 判断渠道是否订阅了指定的事件类型
+func (ns *NotifySink) Subscribed(eventType string) bool {
+	return len(ns.Events) == 0 || slices.Contains(ns.Events, strings.ToLower(eventType))
+}
+
+// Render 使用渠道的模板渲染通知标题和内容
+func (ns *NotifySink) Render(data any) (title, body string, err error) {
+	if ns.titleTpl == nil || ns.bodyTpl == nil {
+		return "", "", errors.New("渠道未初始化")
+	}
+	var sb strings.Builder
+	if err = ns.titleTpl.Execute(&sb, data); err != nil {
+		return "", "", fmt.Errorf("渲染标题失败: %v", err)
+	}
+	title = strings.TrimSpace(sb.String())
+
+	sb.Reset()
+	if err = ns.bodyTpl.Execute(&sb, data); err != nil {
+		return "", "", fmt.Errorf("渲染内容失败: %v", err)
+	}
+	return title, strings.TrimSpace(sb.String()), nil
+}
@@ -3,21 +3,108 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/lib/ffmpeg"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/maps"
 )
 
+// LinkMode 直链获取方式
+type LinkMode string
+
+const (
+	LinkModeRaw    LinkMode = "raw"  // 使用 /api/fs/get 接口返回的 raw_url
+	LinkModeD      LinkMode = "d"    // 使用 openlist 的 /d/ 签名下载地址
+	LinkModeP      LinkMode = "p"    // 使用 openlist 的 /p/ 签名代理地址
+	LinkModeFsLink LinkMode = "link" // 使用 /api/fs/link 接口返回的地址
+)
+
+// validLinkModes 用于校验用户配置的直链获取方式是否合法
+var validLinkModes = map[LinkMode]struct{}{
+	LinkModeRaw: {}, LinkModeD: {}, LinkModeP: {}, LinkModeFsLink: {},
+}
+
 type Openlist struct {
 	// Token 访问 openlist 接口的密钥, 在 openlist 管理后台获取
 	Token string `yaml:"token"`
 	// Host openlist 访问地址（如果 openlist 使用本地代理模式, 则这个地址必须配置公网可访问地址）
 	Host string `yaml:"host"`
+	// Username 登录 openlist 的用户名, 配置后使用账号密码登录获取 token, 忽略 Token 配置
+	Username string `yaml:"username"`
+	// Password 登录 openlist 的密码
+	Password string `yaml:"password"`
+	// OtpSecret 账号开启两步验证时的 otp 密钥 (base32 编码)
+	OtpSecret string `yaml:"otp-secret"`
+
+	// Passwords openlist 加密目录的访问密码, key 为目录路径前缀, 按最长前缀匹配
+	Passwords map[string]string `yaml:"passwords"`
+
+	// LinkModes 直链获取方式, key 为目录路径前缀, 按最长前缀匹配, 未匹配的路径使用 raw
+	LinkModes map[string]string `yaml:"link-modes"`
+
+	// CircuitBreaker openlist 请求熔断配置
+	CircuitBreaker *CircuitBreaker `yaml:"circuit-breaker"`
+
+	// LinkProbe 重定向前的直链可用性探测配置
+	LinkProbe *LinkProbe `yaml:"link-probe"`
+
+	// Instances 多个 openlist 实例, 未配置时使用上方的 host 等配置作为唯一实例
+	Instances []*OpenlistInstance `yaml:"instances"`
 
 	// LocalTreeGen 本地目录树生成相关
 	LocalTreeGen *LocalTreeGen `yaml:"local-tree-gen"`
 }
 
 func (a *Openlist) Init() error {
+	if len(a.Instances) == 0 && a.Host != "" {
+		a.Instances = []*OpenlistInstance{{
+			Name:      "default",
+			Host:      a.Host,
+			Token:     a.Token,
+			Username:  a.Username,
+			Password:  a.Password,
+			OtpSecret: a.OtpSecret,
+		}}
+	}
+	for i, ins := range a.Instances {
+		if err := ins.Init(); err != nil {
+			return fmt.Errorf("openlist.instances[%d] 配置错误: %w", i, err)
+		}
+	}
+	if a.Host == "" && len(a.Instances) > 0 {
+		// 兼容只使用 host 的场景, 如 strm 文件生成
+		a.Host = a.Instances[0].Host
+	}
+
+	for prefix := range a.Passwords {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("openlist.passwords 配置错误: 无效路径前缀 [%s], 必须以 / 开头", prefix)
+		}
+	}
+
+	for prefix, mode := range a.LinkModes {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("openlist.link-modes 配置错误: 无效路径前缀 [%s], 必须以 / 开头", prefix)
+		}
+		if _, ok := validLinkModes[LinkMode(mode)]; !ok {
+			return fmt.Errorf("openlist.link-modes 配置错误: 无效的直链获取方式 [%s], 有效值: %v", mode, maps.Keys(validLinkModes))
+		}
+	}
+
+	if a.CircuitBreaker == nil {
+		a.CircuitBreaker = new(CircuitBreaker)
+	}
+	if err := a.CircuitBreaker.Init(); err != nil {
+		return fmt.Errorf("openlist.circuit-breaker 配置错误: %w", err)
+	}
+
+	if a.LinkProbe == nil {
+		a.LinkProbe = new(LinkProbe)
+	}
+	if err := a.LinkProbe.Init(); err != nil {
+		return fmt.Errorf("openlist.link-probe 配置错误: %w", err)
+	}
+
 	if a.LocalTreeGen == nil {
 		a.LocalTreeGen = new(LocalTreeGen)
 	}
@@ -28,6 +115,203 @@ func (a *Openlist) Init() error {
 	return nil
 }
 
+// PasswordOf 获取 openlist 路径 path 的访问密码, 按最长目录前缀匹配, 匹配不到返回空串
+func (a *Openlist) PasswordOf(path string) string {
+	password, _ := matchLongestPrefix(a.Passwords, path)
+	return password
+}
+
+// LinkModeOf 获取 openlist 路径 path 的直链获取方式, 按最长目录前缀匹配, 匹配不到返回 raw
+func (a *Openlist) LinkModeOf(path string) LinkMode {
+	if mode, ok := matchLongestPrefix(a.LinkModes, path); ok {
+		return LinkMode(mode)
+	}
+	return LinkModeRaw
+}
+
+// matchLongestPrefix 在以目录前缀为 key 的 map 中查找 path 最长匹配的 value
+func matchLongestPrefix(m map[string]string, path string) (string, bool) {
+	var hit, value string
+	found := false
+	for prefix, v := range m {
+		p := strings.TrimSuffix(prefix, "/")
+		if path != p && !strings.HasPrefix(path, p+"/") {
+			continue
+		}
+		if !found || len(p) > len(hit) {
+			hit, value, found = p, v, true
+		}
+	}
+	return value, found
+}
+
+// Route 获取能够处理 openlist 路径 path 的所有实例
+//
+// 配置了匹配前缀的实例优先, 其次是负责所有路径的实例, 同类实例按配置顺序排列;
+// 没有任何实例负责该路径时, 返回所有实例
+func (a *Openlist) Route(path string) []*OpenlistInstance {
+	owners := make([]*OpenlistInstance, 0, len(a.Instances))
+	fallbacks := make([]*OpenlistInstance, 0, len(a.Instances))
+	for _, ins := range a.Instances {
+		if len(ins.Prefixes) == 0 {
+			fallbacks = append(fallbacks, ins)
+		} else if ins.Owns(path) {
+			owners = append(owners, ins)
+		}
+	}
+	res := append(owners, fallbacks...)
+	if len(res) == 0 {
+		return a.Instances
+	}
+	return res
+}
+
+// OpenlistInstance 单个 openlist 实例配置
+type OpenlistInstance struct {
+	// Name 实例名称, 用于日志输出, 默认使用 host
+	Name string `yaml:"name"`
+	// Host openlist 访问地址
+	Host string `yaml:"host"`
+	// Token 访问 openlist 接口的密钥
+	Token string `yaml:"token"`
+	// Username 登录用户名, 配置后使用账号密码登录获取 token, 忽略 Token 配置
+	Username string `yaml:"username"`
+	// Password 登录密码
+	Password string `yaml:"password"`
+	// OtpSecret 账号开启两步验证时的 otp 密钥 (base32 编码)
+	OtpSecret string `yaml:"otp-secret"`
+	// Prefixes 实例负责的 openlist 路径前缀, 不配置则负责所有路径
+	Prefixes []string `yaml:"prefixes"`
+	// Timeout 请求超时时间, 单位: 秒, 超时后转移到下一个实例
+	Timeout int `yaml:"timeout"`
+}
+
+// Init 配置初始化
+func (oi *OpenlistInstance) Init() error {
+	if oi.Host = strings.TrimRight(strings.TrimSpace(oi.Host), "/"); oi.Host == "" {
+		return fmt.Errorf("host 不能为空")
+	}
+	if oi.Name == "" {
+		oi.Name = oi.Host
+	}
+	if oi.Username != "" && oi.Password == "" {
+		return fmt.Errorf("password 不能为空")
+	}
+	for i, prefix := range oi.Prefixes {
+		prefix = strings.TrimSpace(prefix)
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("无效路径前缀: [%s], 必须以 / 开头", prefix)
+		}
+		oi.Prefixes[i] = prefix
+	}
+	if oi.Timeout < 0 {
+		return fmt.Errorf("无效超时时间: [%d]", oi.Timeout)
+	}
+	return nil
+}
+
+// LinkProbe 直链可用性探测配置
+//
+// 重定向到直链之前, 使用 HEAD (或 Range: bytes=0-0) 请求探测直链是否可用,
+// 不可用时尝试其他候选路径
+type LinkProbe struct {
+	// Enable 是否启用探测
+	Enable bool `yaml:"enable"`
+	// Timeout 探测超时时间, 单位: 毫秒
+	Timeout int `yaml:"timeout"`
+	// TranscodeFallback 所有直链都不可用时, 是否回退到网盘转码播放
+	TranscodeFallback bool `yaml:"transcode-fallback"`
+}
+
+// Init 配置初始化
+func (lp *LinkProbe) Init() error {
+	if lp.Timeout == 0 {
+		lp.Timeout = 3000
+	}
+	if lp.Timeout < 0 {
+		return fmt.Errorf("无效超时时间: [%d]", lp.Timeout)
+	}
+	return nil
+}
+
+// TimeoutDuration 探测超时时间
+func (lp *LinkProbe) TimeoutDuration() time.Duration {
+	return time.Duration(lp.Timeout) * time.Millisecond
+}
+
+// CircuitBreaker openlist 请求熔断配置
+//
+// 熔断以 openlist 实例 + 存储 (路径的第一级目录) 为单位
+type CircuitBreaker struct {
+	// Enable 是否启用熔断
+	Enable bool `yaml:"enable"`
+	// FailureThreshold 连续失败多少次后熔断
+	FailureThreshold int `yaml:"failure-threshold"`
+	// Cooldown 熔断后的初始冷却时间, 单位: 秒
+	Cooldown int `yaml:"cooldown"`
+	// MaxCooldown 冷却时间的上限, 半开状态下试探失败会使冷却时间翻倍, 单位: 秒
+	MaxCooldown int `yaml:"max-cooldown"`
+	// TripMessages 错误信息中包含这些关键字时立即熔断, 不区分大小写
+	TripMessages []string `yaml:"trip-messages"`
+}
+
+// Init 配置初始化
+func (cb *CircuitBreaker) Init() error {
+	if cb.FailureThreshold == 0 {
+		cb.FailureThreshold = 5
+	}
+	if cb.Cooldown == 0 {
+		cb.Cooldown = 30
+	}
+	if cb.MaxCooldown == 0 {
+		cb.MaxCooldown = 600
+	}
+	if cb.FailureThreshold < 0 || cb.Cooldown < 0 || cb.MaxCooldown < cb.Cooldown {
+		return fmt.Errorf("无效配置: failure-threshold [%d], cooldown [%d], max-cooldown [%d]", cb.FailureThreshold, cb.Cooldown, cb.MaxCooldown)
+	}
+	if len(cb.TripMessages) == 0 {
+		cb.TripMessages = []string{"too many requests", "rate limit", "429"}
+	}
+	for i, msg := range cb.TripMessages {
+		cb.TripMessages[i] = strings.ToLower(msg)
+	}
+	return nil
+}
+
+// ShouldTrip 判断错误信息是否需要立即熔断
+func (cb *CircuitBreaker) ShouldTrip(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, tm := range cb.TripMessages {
+		if strings.Contains(msg, tm) {
+			return true
+		}
+	}
+	return false
+}
+
+// UseLogin 是否使用账号密码登录 openlist
+func (oi *OpenlistInstance) UseLogin() bool {
+	return oi.Username != ""
+}
+
+// TimeoutDuration 请求超时时间, 为 0 表示不限制
+func (oi *OpenlistInstance) TimeoutDuration() time.Duration {
+	return time.Duration(oi.Timeout) * time.Second
+}
+
+// Owns 判断实例是否负责 openlist 路径 path
+func (oi *OpenlistInstance) Owns(path string) bool {
+	if len(oi.Prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range oi.Prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 type LocalTreeGen struct {
 
 	// Enable 是否启用
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/mappings"
 )
 
 // pathCacheItem 路径缓存项（带过期时间）
@@ -24,7 +25,7 @@ type Oss struct {
 	// Bucket 存储桶名称
 	Bucket string `yaml:"bucket"`
 	// PathMapping Emby路径到OSS路径的映射关系
-	PathMapping []string `yaml:"path-mapping"`
+	PathMapping []mappings.Rule `yaml:"path-mapping"`
 	// PathMappingCacheTTL 路径映射缓存过期时间（秒），默认 3600 秒（1小时）
 	PathMappingCacheTTL int64 `yaml:"path-mapping-cache-ttl"`
 	// CdnAuth CDN Type-A 鉴权配置
@@ -32,8 +33,8 @@ type Oss struct {
 	// ApiKey 源站验证 API Key 配置
 	ApiKey *ApiKeyConfig `yaml:"api-key"`
 
-	// 内部使用的映射表
-	pathMappingTable map[string]string
+	// 内部使用的映射器
+	pathMapper *mappings.Mapper
 	// 路径映射缓存（提升性能，避免重复计算）
 	pathMappingCache sync.Map
 }
@@ -92,25 +93,9 @@ func (o *Oss) Init() error {
 		o.PathMappingCacheTTL = 3600
 	}
 
-	// 初始化路径映射表
-	o.pathMappingTable = make(map[string]string)
-	for _, mapping := range o.PathMapping {
-		parts := strings.Split(mapping, ":")
-		if len(parts) != 2 {
-			logs.Warn("无效的路径映射配置: %s, 跳过", mapping)
-			continue
-		}
-		embyPath := strings.TrimSpace(parts[0])
-		ossPath := strings.TrimSpace(parts[1])
-		if embyPath == "" || ossPath == "" {
-			logs.Warn("无效的路径映射配置: %s, 跳过", mapping)
-			continue
-		}
-		o.pathMappingTable[embyPath] = ossPath
-		logs.Info("OSS 路径映射: %s -> %s", embyPath, ossPath)
-	}
-
-	if len(o.pathMappingTable) == 0 {
+	// 初始化路径映射器
+	o.pathMapper = newLenientMapper("OSS", o.PathMapping)
+	if o.pathMapper.Len() == 0 {
 		logs.Warn("未配置有效的 OSS 路径映射")
 	}
 
@@ -149,7 +134,7 @@ func (o *Oss) Init() error {
 
 // MapPath 将 Emby 路径映射为 OSS 路径
 func (o *Oss) MapPath(embyPath string) (string, error) {
-	if o.pathMappingTable == nil {
+	if o.pathMapper == nil {
 		return "", fmt.Errorf("路径映射表未初始化")
 	}
 
@@ -164,19 +149,15 @@ func (o *Oss) MapPath(embyPath string) (string, error) {
 		o.pathMappingCache.Delete(embyPath)
 	}
 
-	// 遍历映射表,找到匹配的前缀
-	for embyPrefix, ossPrefix := range o.pathMappingTable {
-		if strings.HasPrefix(embyPath, embyPrefix) {
-			// 替换前缀
-			ossPath := strings.Replace(embyPath, embyPrefix, ossPrefix, 1)
-			// 存入缓存（带过期时间）
-			item := &pathCacheItem{
-				value:    ossPath,
-				expireAt: time.Now().Add(time.Duration(o.PathMappingCacheTTL) * time.Second),
-			}
-			o.pathMappingCache.Store(embyPath, item)
-			return ossPath, nil
+	// 按配置顺序匹配映射规则
+	if ossPath, _, ok := o.pathMapper.Map(embyPath); ok {
+		// 存入缓存（带过期时间）
+		item := &pathCacheItem{
+			value:    ossPath,
+			expireAt: time.Now().Add(time.Duration(o.PathMappingCacheTTL) * time.Second),
 		}
+		o.pathMappingCache.Store(embyPath, item)
+		return ossPath, nil
 	}
 
 	return "", fmt.Errorf("无法映射 Emby 路径到 OSS 路径: %s", embyPath)
@@ -5,36 +5,130 @@ import (
 	"strings"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/mappings"
 )
 
 type Path struct {
-	// Emby2Openlist Emby 的路径前缀映射到 Openlist 的路径前缀, 两个路径使用 : 符号隔开
-	Emby2Openlist []string `yaml:"emby2openlist"`
+	// Emby2Openlist Emby 的路径前缀映射到 Openlist 的路径前缀
+	//
+	// 支持字符串形式 (使用 : 或 => 隔开) 以及结构化形式 (from, to, type, ignore-case)
+	Emby2Openlist []mappings.Rule `yaml:"emby2openlist"`
 
-	// emby2OpenlistArr 根据 Emby2Openlist 转换成路径键值对数组
-	emby2OpenlistArr [][2]string
+	// Discovery emby2openlist 映射自动探测配置
+	Discovery *Discovery `yaml:"discovery"`
+
+	// Search 映射失败时使用 openlist 搜索接口解析路径的配置
+	Search *Search `yaml:"search"`
+
+	// emby2OpenlistMapper 根据 Emby2Openlist 编译生成的映射器
+	emby2OpenlistMapper *mappings.Mapper
 }
 
 func (p *Path) Init() error {
-	p.emby2OpenlistArr = make([][2]string, 0, len(p.Emby2Openlist))
-	for _, e2a := range p.Emby2Openlist {
-		arr := strings.Split(e2a, ":")
-		if len(arr) != 2 {
-			return fmt.Errorf("path.emby2openlist 配置错误, %s 无法根据 ':' 进行分割", e2a)
-		}
-		p.emby2OpenlistArr = append(p.emby2OpenlistArr, [2]string{arr[0], arr[1]})
+	m, err := mappings.New(p.Emby2Openlist, mappings.TypePrefix)
+	if err != nil {
+		return fmt.Errorf("path.emby2openlist 配置错误: %v", err)
+	}
+	p.emby2OpenlistMapper = m
+
+	if p.Discovery == nil {
+		p.Discovery = new(Discovery)
+	}
+	if err := p.Discovery.Init(); err != nil {
+		return fmt.Errorf("path.discovery 配置错误: %v", err)
+	}
+
+	if p.Search == nil {
+		p.Search = new(Search)
+	}
+	if err := p.Search.Init(); err != nil {
+		return fmt.Errorf("path.search 配置错误: %v", err)
+	}
+	return nil
+}
+
+// Discovery emby2openlist 映射自动探测配置
+type Discovery struct {
+	// Enable 是否在启动时根据 openlist 存储列表和 emby 媒体库自动探测映射
+	Enable bool `yaml:"enable"`
+	// Apply 探测到的映射是否直接生效, 不生效时仅输出建议的映射配置
+	Apply bool `yaml:"apply"`
+	// AutoLearn 播放时是否从根目录遍历的结果中自动学习映射
+	AutoLearn bool `yaml:"auto-learn"`
+	// SampleSize 每个媒体库采样的媒体数量
+	SampleSize int `yaml:"sample-size"`
+}
+
+// Init 配置初始化
+func (d *Discovery) Init() error {
+	if d.SampleSize == 0 {
+		d.SampleSize = 3
+	}
+	if d.SampleSize < 0 {
+		return fmt.Errorf("无效采样数量: [%d], 必须配置为大于 0 的值", d.SampleSize)
+	}
+	return nil
+}
+
+// Persistent 学习到的映射是否需要生效并持久化
+func (d *Discovery) Persistent() bool {
+	return d.AutoLearn || (d.Enable && d.Apply)
+}
+
+// Search openlist 搜索接口解析配置
+type Search struct {
+	// Enable 映射失败时是否优先使用 openlist 搜索接口解析路径, 需要 openlist 开启索引
+	//
+	// 启用后, 解析成功的路径会被持久化缓存
+	Enable bool `yaml:"enable"`
+	// Parent 搜索的根目录
+	Parent string `yaml:"parent"`
+	// MaxResults 单次搜索最多返回的结果数量
+	MaxResults int `yaml:"max-results"`
+}
+
+// Init 配置初始化
+func (s *Search) Init() error {
+	if s.Parent == "" {
+		s.Parent = "/"
+	}
+	if !strings.HasPrefix(s.Parent, "/") {
+		return fmt.Errorf("无效搜索根目录: [%s], 必须以 / 开头", s.Parent)
+	}
+	if s.MaxResults == 0 {
+		s.MaxResults = 100
+	}
+	if s.MaxResults < 0 {
+		return fmt.Errorf("无效结果数量: [%d], 必须配置为大于 0 的值", s.MaxResults)
 	}
 	return nil
 }
 
 // MapEmby2Openlist 将 emby 路径映射成 openlist 路径
 func (p *Path) MapEmby2Openlist(embyPath string) (string, bool) {
-	for _, cfg := range p.emby2OpenlistArr {
-		ep, ap := cfg[0], cfg[1]
-		if strings.HasPrefix(embyPath, ep) {
-			logs.Tip("命中 emby2openlist 路径映射: %s => %s (如命中错误, 请将正确的映射配置前移)", ep, ap)
-			return strings.Replace(embyPath, ep, ap, 1), true
+	res, rule, ok := p.emby2OpenlistMapper.Map(embyPath)
+	if !ok {
+		return "", false
+	}
+	logs.Tip("命中 emby2openlist 路径映射: %s (如命中错误, 请将正确的映射配置前移)", rule)
+	return res, true
+}
+
+// newLenientMapper 编译前缀映射规则, 无效的规则会被跳过并输出警告
+//
+// name 为日志输出时使用的映射名称
+func newLenientMapper(name string, rules []mappings.Rule) *mappings.Mapper {
+	valid := make([]mappings.Rule, 0, len(rules))
+	for _, rule := range rules {
+		if err := rule.Compile(mappings.TypePrefix); err != nil {
+			logs.Warn("无效的路径映射配置: %v, 跳过", err)
+			continue
 		}
+		valid = append(valid, rule)
+		logs.Info("%s 路径映射: %s", name, &rule)
 	}
-	return "", false
+
+	// 规则已校验过, 这里不会出现编译错误
+	m, _ := mappings.New(valid, mappings.TypePrefix)
+	return m
 }
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Session 播放会话追踪配置
+//
+// 程序根据 PlaybackInfo, 资源重定向, 播放进度以及停止播放请求记录每个设备正在播放的媒体
+type Session struct {
+	// Persist 是否将会话持久化到磁盘, 程序重启后恢复
+	Persist bool `yaml:"persist"`
+	// IdleTimeout 会话空闲超时时间, 超过该时间没有任何请求的会话会被移除, 默认 5m
+	IdleTimeout string `yaml:"idle-timeout"`
+	// MaxStreamsPerUser 每个用户在同一个 emby 后端同时播放的最大设备数, 0 表示不限制
+	MaxStreamsPerUser int `yaml:"max-streams-per-user"`
+
+	// idleTimeout 配置初始化转换之后的标准时间对象
+	idleTimeout time.Duration
+}
+
+// Init 配置初始化
+func (s *Session) Init() error {
+	if s.MaxStreamsPerUser < 0 {
+		return errors.New("session.max-streams-per-user 配置错误: 不能小于 0")
+	}
+	if s.IdleTimeout == "" {
+		s.idleTimeout = time.Minute * 5
+		return nil
+	}
+	idleTimeout, err := parseDuration(s.IdleTimeout)
+	if err != nil {
+		return fmt.Errorf("session.idle-timeout 配置错误: %v", err)
+	}
+	s.idleTimeout = idleTimeout
+	return nil
+}
+
+// IdleTimeoutDuration 会话空闲超时时间
+func (s *Session) IdleTimeoutDuration() time.Duration {
+	return s.idleTimeout
+}
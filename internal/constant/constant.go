@@ -5,6 +5,9 @@ const (
 	RepoAddr       = "https://github.com/AmbitiousJun/go-emby2openlist"
 )
 
+// reg_ItemId 匹配 item id, emby 为纯数字, jellyfin 为 32 位十六进制
+const reg_ItemId = `(?:\d+|[0-9a-fA-F]{32})`
+
 const (
 	Reg_Socket       = `(?i)^/.*(socket|embywebsocket)`
 	Reg_PlaybackInfo = `(?i)^/.*items/.*/playbackinfo\??`
@@ -12,11 +15,12 @@ const (
 	Reg_PlayingStopped  = `(?i)^/.*sessions/playing/stopped`
 	Reg_PlayingProgress = `(?i)^/.*sessions/playing/progress`
 
-	Reg_UserItems                = `(?i)^/.*users/.*/items/\d+($|\?)`
+	Reg_UserItems                = `(?i)^/.*users/.*/items/` + reg_ItemId + `($|\?)`
+	Reg_JellyfinItems            = `(?i)^/items/[0-9a-f]{32}($|\?)`
 	Reg_UserEpisodeItems         = `(?i)^/.*users/.*/items\?.*includeitemtypes=(episode|movie)`
 	Reg_UserItemsRandomResort    = `(?i)^/.*users/.*/items\?.*SortBy=Random`
 	Reg_UserItemsRandomWithLimit = `(?i)^/.*users/.*/items/with_limit\?.*SortBy=Random`
-	Reg_UserPlayedItems          = `(?i)^/.*users/.*/playeditems/(\d+)($|\?|/.*)?`
+	Reg_UserPlayedItems          = `(?i)^/.*users/.*/playeditems/(` + reg_ItemId + `)($|\?|/.*)?`
 	Reg_UserLatestItems          = `(?i)^/.*users/.*/items/latest($|\?)`
 	Reg_ItemsCounts              = `(?i)^/.*items/counts($|\?)`
 
@@ -32,7 +36,7 @@ const (
 	Reg_ProxyTs       = `(?i)^/.*videos/proxy_ts\??`
 	Reg_ProxySubtitle = `(?i)^/.*videos/proxy_subtitle\??`
 
-	Reg_ItemDownload     = `(?i)^/.*items/\d+/download($|\?)`
+	Reg_ItemDownload     = `(?i)^/.*items/` + reg_ItemId + `/download($|\?)`
 	Reg_ItemSyncDownload = `(?i)^/.*sync/jobitems/\d+/file($|\?)`
 
 	Reg_Images             = `(?i)^/.*images`
@@ -44,14 +48,36 @@ const (
 	Route_CustomJs  = `/ge2o/custom.js`
 	Route_CustomCss = `/ge2o/custom.css`
 
+	Reg_AdminBreakers      = `^/ge2o/admin/breakers($|\?)`
+	Reg_AdminBreakersReset = `^/ge2o/admin/breakers/reset($|\?)`
+	Reg_AdminSessions      = `^/ge2o/admin/sessions($|\?)`
+	Reg_AdminAudit         = `^/ge2o/admin/audit($|\?)`
+
+	Reg_Webhook = `^/ge2o/webhook($|\?)`
+	Reg_Metrics = `^/metrics($|\?)`
+	Reg_Healthz = `^/ge2o/healthz($|\?)`
+	Reg_Readyz  = `^/ge2o/readyz($|\?)`
+
 	Reg_All = `.*`
 )
 
 const (
 	RouteSubMatchGinKey = "routeSubMatches" // 路由匹配成功时, 会将匹配的正则结果存放到 Gin 上下文
+	EmbyBackendGinKey   = "embyBackend"     // 当前请求选中的 emby 后端配置存放在 Gin 上下文的 key
+	CacheMetaGinKey     = "cacheMeta"       // 处理器存放在 Gin 上下文中, 随响应一起缓存的附加信息
 
 	CustomJsDirName  = "custom-js"  // 自定义脚本存放目录
 	CustomCssDirName = "custom-css" // 自定义样式存放目录
 
 	CommonDlUserAgent = "libmpv" // 通用的下载 UA
+
+	AdminTokenHeader = "X-Ge2o-Admin-Token" // 管理接口密钥请求头
+	AdminTokenQuery  = "admin_token"        // 管理接口密钥 query 参数
+
+	EmbyBackendHeader    = "X-Ge2o-Emby-Backend"    // 服务内部自请求时, 通过该请求头指定 emby 后端
+	InternalSecretHeader = "X-Ge2o-Internal-Secret" // 服务内部自请求时, 携带进程级随机密钥证明请求来源
+
+	RequestIdHeader = "X-Request-Id" // 请求 id 请求头, 客户端未传递时自动生成, 并在响应中返回
+	RequestIdGinKey = "requestId"    // 请求 id 存放在 Gin 上下文的 key
+	TraceHeader     = "X-Ge2o-Trace" // 管理员携带该请求头时, 在响应头中以同名字段返回请求的处理过程
 )
@@ -0,0 +1,56 @@
+package constant
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRegItemId(t *testing.T) {
+	reg := regexp.MustCompile(`^` + reg_ItemId + `$`)
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"2008", true},
+		{"0123456789abcdef0123456789abcdef", true},
+		{"0123456789ABCDEF0123456789ABCDEF", true},
+		{"0123456789abcdef0123456789abcde", false},
+		{"0123456789abcdef0123456789abcdef0", false},
+		{"0123456789abcdeg0123456789abcdef", false},
+		{"12ab", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := reg.MatchString(tt.id); got != tt.want {
+			t.Errorf("reg_ItemId 匹配 [%s] = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestItemRoutes(t *testing.T) {
+	tests := []struct {
+		name string
+		reg  string
+		uri  string
+		want bool
+	}{
+		{"jellyfin item", Reg_JellyfinItems, "/Items/0123456789abcdef0123456789abcdef", true},
+		{"jellyfin item 带参数", Reg_JellyfinItems, "/items/0123456789abcdef0123456789abcdef?UserId=1", true},
+		{"jellyfin item 子路径", Reg_JellyfinItems, "/Items/0123456789abcdef0123456789abcdef/PlaybackInfo", false},
+		{"jellyfin item 数字 id", Reg_JellyfinItems, "/Items/2008", false},
+		{"jellyfin item 带前缀", Reg_JellyfinItems, "/emby/Items/0123456789abcdef0123456789abcdef", false},
+		{"emby 用户 item", Reg_UserItems, "/emby/Users/1/Items/2008?X-Emby-Token=1", true},
+		{"jellyfin 用户 item", Reg_UserItems, "/Users/1/Items/0123456789abcdef0123456789abcdef", true},
+		{"用户 item 列表", Reg_UserItems, "/emby/Users/1/Items?ParentId=1", false},
+		{"jellyfin 下载", Reg_ItemDownload, "/Items/0123456789abcdef0123456789abcdef/Download?api_key=1", true},
+		{"emby 同步下载", Reg_ItemSyncDownload, "/emby/Sync/JobItems/12/File?api_key=1", true},
+		{"同步下载不接受 guid", Reg_ItemSyncDownload, "/Sync/JobItems/0123456789abcdef0123456789abcdef/File", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := regexp.MustCompile(tt.reg).MatchString(tt.uri); got != tt.want {
+				t.Errorf("匹配 [%s] = %v, want %v", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// 资源的提供方
+const (
+	BackendOpenlist  = "openlist"  // openlist 直链
+	BackendTranscode = "transcode" // openlist 网盘转码
+	BackendOss       = "oss"       // 对象存储
+	BackendGoEdge    = "goedge"    // GoEdge CDN
+	BackendStrm      = "strm"      // strm 远程地址
+	BackendLocal     = "local"     // 本地媒体
+	BackendOrigin    = "origin"    // 回源到 emby
+)
+
+// 决策结果
+const (
+	OutcomeRedirect = "redirect" // 重定向到目标地址
+	OutcomeServed   = "served"   // 由程序或源服务器直接响应
+	OutcomeRejected = "rejected" // 拒绝请求
+	OutcomeError    = "error"    // 处理失败
+)
+
+// DateLayout 审计日志文件名以及查询参数使用的日期格式
+const DateLayout = "2006-01-02"
+
+// Record 一次资源重定向决策的审计记录
+type Record struct {
+	Time         time.Time `json:"time"`
+	User         string    `json:"user,omitempty"`          // emby 用户 id
+	ClientIp     string    `json:"client_ip,omitempty"`     // 客户端 ip
+	Emby         string    `json:"emby"`                    // emby 后端名称
+	Route        string    `json:"route"`                   // 请求的路由类型
+	ItemId       string    `json:"item_id,omitempty"`       // item id
+	EmbyPath     string    `json:"emby_path,omitempty"`     // 媒体在 emby 中的路径
+	OpenlistPath string    `json:"openlist_path,omitempty"` // 媒体在 openlist 中的路径
+	Backend      string    `json:"backend,omitempty"`       // 资源的提供方
+	TargetHost   string    `json:"target_host,omitempty"`   // 重定向的目标主机
+	Status       int       `json:"status"`                  // 响应码
+	Outcome      string    `json:"outcome"`                 // 决策结果
+}
+
+// OutcomeOf 根据响应码推断决策结果
+func OutcomeOf(status int) string {
+	switch {
+	case status >= 300 && status < 400:
+		return OutcomeRedirect
+	case status == 401 || status == 403 || status == 429:
+		return OutcomeRejected
+	case status >= 400:
+		return OutcomeError
+	default:
+		return OutcomeServed
+	}
+}
+
+// FileName 指定日期的审计日志文件名
+func FileName(date time.Time) string {
+	return "audit-" + date.Format(DateLayout) + ".jsonl"
+}
+
+// writer 当前正在写入的审计日志文件
+var writer struct {
+	mu   sync.Mutex
+	date string
+	file *os.File
+}
+
+// Log 追加一条审计记录, 未启用审计日志时不做任何处理
+func Log(r Record) {
+	cfg := config.C.Audit
+	if !cfg.Enable {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	if r.Outcome == "" {
+		r.Outcome = OutcomeOf(r.Status)
+	}
+	bytes, err := json.Marshal(r)
+	if err != nil {
+		logs.Warn("序列化审计记录失败: %v", err)
+		return
+	}
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	if err := rotate(cfg, r.Time); err != nil {
+		logs.Warn("打开审计日志失败: %v", err)
+		return
+	}
+	if _, err := writer.file.Write(append(bytes, '\n')); err != nil {
+		logs.Warn("写入审计日志失败: %v", err)
+	}
+}
+
+// Close 关闭正在写入的审计日志文件, 在程序退出前调用
+func Close() error {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	if writer.file == nil {
+		return nil
+	}
+	err := writer.file.Close()
+	writer.file, writer.date = nil, ""
+	return err
+}
+
+// rotate 日期变化时切换到新的日志文件, 并清理过期的日志, 调用方需持有锁
+func rotate(cfg *config.Audit, now time.Time) error {
+	date := now.Format(DateLayout)
+	if writer.file != nil && writer.date == date {
+		return nil
+	}
+	if err := os.MkdirAll(cfg.Dir, os.ModePerm); err != nil {
+		return fmt.Errorf("创建审计日志目录失败: %v", err)
+	}
+	file, err := os.OpenFile(filepath.Join(cfg.Dir, FileName(now)), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if writer.file != nil {
+		writer.file.Close()
+	}
+	writer.file, writer.date = file, date
+	go cleanExpired(cfg.Dir, *cfg.RetentionDays)
+	return nil
+}
+
+// cleanExpired 移除超过保留天数的日志文件
+func cleanExpired(dir string, retentionDays int) {
+	if retentionDays <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	deadline := time.Now().AddDate(0, 0, -retentionDays).Format(DateLayout)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "audit-") || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		if date := strings.TrimSuffix(strings.TrimPrefix(name, "audit-"), ".jsonl"); date < deadline {
+			if err := os.Remove(filepath.Join(dir, name)); err == nil {
+				logs.Info("已移除过期的审计日志: %s", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package audit_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/audit"
+)
+
+func TestLogAndExport(t *testing.T) {
+	dir := t.TempDir()
+	retention := 0
+	config.C = &config.Config{Audit: &config.Audit{Enable: true, Dir: dir, RetentionDays: &retention}}
+
+	now := time.Now()
+	audit.Log(audit.Record{Time: now, User: "u1", ItemId: "1", Backend: audit.BackendOpenlist, Status: 302})
+	audit.Log(audit.Record{Time: now, User: "u2", ItemId: "2", Backend: audit.BackendOss, Status: 429})
+
+	all, err := audit.Query(dir, now, now, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("期望 2 条记录, 实际: %d", len(all))
+	}
+	if all[0].Outcome != audit.OutcomeRedirect || all[1].Outcome != audit.OutcomeRejected {
+		t.Fatalf("决策结果错误: %s, %s", all[0].Outcome, all[1].Outcome)
+	}
+
+	records, err := audit.Query(dir, now.AddDate(0, 0, -1), now, "u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ItemId != "2" {
+		t.Fatalf("按用户过滤失败: %+v", records)
+	}
+
+	var buf bytes.Buffer
+	if err := audit.WriteCSV(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][1] != "u2" || rows[1][8] != audit.BackendOss {
+		t.Fatalf("csv 内容错误: %v", rows)
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	from, to, err := audit.ParseDateRange("2025-01-01", "2025-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.Format(audit.DateLayout) != "2025-01-01" || to.Format(audit.DateLayout) != "2025-01-31" {
+		t.Fatalf("解析结果错误: %v ~ %v", from, to)
+	}
+	if _, _, err := audit.ParseDateRange("2025/01/01", ""); err == nil {
+		t.Fatal("期望日期格式错误")
+	}
+	if _, err := audit.Query(t.TempDir(), to, from, ""); err == nil {
+		t.Fatal("期望日期范围错误")
+	}
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// csvHeader 导出 csv 的表头
+var csvHeader = []string{
+	"time", "user", "client_ip", "emby", "route", "item_id", "emby_path",
+	"openlist_path", "backend", "target_host", "status", "outcome",
+}
+
+// Query 查询指定目录下日期范围内的审计记录
+//
+// from, to 均为包含的日期, user 不为空时只返回该用户的记录
+func Query(dir string, from, to time.Time, user string) ([]Record, error) {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, to.Location())
+	if to.Before(from) {
+		return nil, fmt.Errorf("日期范围错误: %s ~ %s", from.Format(DateLayout), to.Format(DateLayout))
+	}
+
+	res := make([]Record, 0)
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		file, err := os.Open(filepath.Join(dir, FileName(date)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("读取审计日志失败: %v", err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var r Record
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				// 跳过写入不完整的行
+				continue
+			}
+			if user != "" && r.User != user {
+				continue
+			}
+			res = append(res, r)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取审计日志失败: %v", err)
+		}
+	}
+	return res, nil
+}
+
+// WriteCSV 将审计记录以 csv 格式写入 w
+func WriteCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := []string{
+			r.Time.Format(time.RFC3339), r.User, r.ClientIp, r.Emby, r.Route, r.ItemId, r.EmbyPath,
+			r.OpenlistPath, r.Backend, r.TargetHost, strconv.Itoa(r.Status), r.Outcome,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ParseDateRange 解析日期范围参数, 格式为 2006-01-02
+//
+// 未传递 to 时默认为当天, 未传递 from 时默认与 to 相同
+func ParseDateRange(fromStr, toStr string) (from, to time.Time, err error) {
+	to = time.Now()
+	if toStr != "" {
+		if to, err = time.ParseInLocation(DateLayout, toStr, time.Local); err != nil {
+			return from, to, fmt.Errorf("to 参数格式错误, 正确格式: %s", DateLayout)
+		}
+	}
+	from = to
+	if fromStr != "" {
+		if from, err = time.ParseInLocation(DateLayout, fromStr, time.Local); err != nil {
+			return from, to, fmt.Errorf("from 参数格式错误, 正确格式: %s", DateLayout)
+		}
+	}
+	return from, to, nil
+}
@@ -19,7 +19,7 @@ import (
 // 如果请求是失败的响应, 会直接返回客户端, 并在第二个参数中返回 false
 func proxyAndSetRespHeader(c *gin.Context) (model.HttpRes[*jsons.Item], bool) {
 	c.Request.Header.Del("Accept-Encoding")
-	res, respHeader := RawFetch(c.Request.URL.String(), c.Request.Method, c.Request.Header, c.Request.Body)
+	res, respHeader := RawFetchOf(embyOf(c), c.Request.URL.String(), c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		checkErr(c, errors.New(res.Msg))
 		return res, false
@@ -30,12 +30,22 @@ func proxyAndSetRespHeader(c *gin.Context) (model.HttpRes[*jsons.Item], bool) {
 
 // Fetch 请求 emby api 接口, 使用 map 请求体
 func Fetch(uri, method string, header http.Header, body map[string]any) (model.HttpRes[*jsons.Item], http.Header) {
-	return RawFetch(uri, method, header, https.MapBody(body))
+	return FetchOf(config.C.Emby, uri, method, header, body)
+}
+
+// FetchOf 请求指定 emby 后端的 api 接口, 使用 map 请求体
+func FetchOf(e *config.Emby, uri, method string, header http.Header, body map[string]any) (model.HttpRes[*jsons.Item], http.Header) {
+	return RawFetchOf(e, uri, method, header, https.MapBody(body))
 }
 
 // RawFetch 请求 emby api 接口, 使用流式请求体
 func RawFetch(uri, method string, header http.Header, body io.ReadCloser) (model.HttpRes[*jsons.Item], http.Header) {
-	u := config.C.Emby.Host + uri
+	return RawFetchOf(config.C.Emby, uri, method, header, body)
+}
+
+// RawFetchOf 请求指定 emby 后端的 api 接口, 使用流式请求体
+func RawFetchOf(e *config.Emby, uri, method string, header http.Header, body io.ReadCloser) (model.HttpRes[*jsons.Item], http.Header) {
+	u := e.Host + uri
 
 	// 构造请求头, 发出请求
 	if header == nil {
@@ -0,0 +1,100 @@
+package emby
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/audit"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAuditRecord 初始化一条资源重定向的审计记录
+func newAuditRecord(c *gin.Context, route RouteType) audit.Record {
+	return audit.Record{
+		User:     sessionUserId(c),
+		ClientIp: c.ClientIP(),
+		Emby:     embyOf(c).Name,
+		Route:    string(route),
+	}
+}
+
+// logAudit 根据响应结果补全审计记录并写入审计日志, 同时记录重定向指标
+//
+// 审计记录会随响应一起缓存, 缓存命中时由 replayRedirect 重新记录
+func logAudit(c *gin.Context, r *audit.Record) {
+	c.Set(constant.CacheMetaGinKey, *r)
+	finishAudit(r, c.Writer.Status(), c.Writer.Header())
+}
+
+// finishAudit 根据响应码以及响应头补全审计记录并写入审计日志
+func finishAudit(r *audit.Record, status int, header http.Header) {
+	r.Status = status
+	if loc := header.Get("Location"); loc != "" {
+		if u, err := url.Parse(loc); err == nil {
+			r.TargetHost = u.Host
+		}
+	}
+	if r.Backend == "" && r.Status < 400 {
+		r.Backend = audit.BackendOrigin
+	}
+	redirectDecisions.Inc(r.Backend, audit.OutcomeOf(r.Status))
+	audit.Log(*r)
+}
+
+// CacheHitHooks 资源重定向接口的缓存命中钩子
+//
+// 缓存回放时同样需要校验并记录播放会话, 以及写入审计日志
+func CacheHitHooks() []cache.HitHook {
+	return []cache.HitHook{
+		{Pattern: constant.Reg_ResourceStream, Handle: replayRedirect},
+		{Pattern: constant.Reg_ItemDownload, Handle: replayRedirect},
+	}
+}
+
+// replayRedirect 回放缓存的重定向响应之前, 校验播放会话并记录审计日志
+//
+// meta 为处理器缓存的审计记录, 没有审计记录的响应 (如字幕) 不做处理
+func replayRedirect(c *gin.Context, code int, header http.Header, meta any) {
+	cached, ok := meta.(audit.Record)
+	if !ok {
+		return
+	}
+	itemInfo, err := resolveItemInfo(c, RouteStream)
+	if err != nil {
+		return
+	}
+
+	rec := newAuditRecord(c, RouteType(cached.Route))
+	rec.ItemId = itemInfo.Id
+	if !allowSession(c, itemInfo) {
+		logs.Ctx(c).Warn("同时播放的设备数已达上限, 拒绝播放: %s", itemInfo.Id)
+		c.String(http.StatusTooManyRequests, "同时播放的设备数已达上限")
+		c.Abort()
+		finishAudit(&rec, http.StatusTooManyRequests, c.Writer.Header())
+		return
+	}
+	trackSession(c, itemInfo, SessionPlaying)
+	if cached.OpenlistPath != "" {
+		setSessionOpenlistPath(c, cached.OpenlistPath)
+	}
+
+	rec.EmbyPath, rec.OpenlistPath, rec.Backend = cached.EmbyPath, cached.OpenlistPath, cached.Backend
+	finishAudit(&rec, code, header)
+}
+
+// sessionUserId 获取当前设备的播放会话中记录的用户 id
+func sessionUserId(c *gin.Context) string {
+	if userId := c.Query("UserId"); userId != "" {
+		return userId
+	}
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if s, ok := sessions[embyOf(c).Name+"|"+getDeviceId(c)]; ok {
+		return s.UserId
+	}
+	return ""
+}
@@ -7,7 +7,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
@@ -22,7 +21,7 @@ import (
 // 通过此 uri, 可以判断出客户端传递的 api_key 是否是被 emby 服务器认可的
 const AuthUri = "/emby/Auth/Keys"
 
-// validApiKeys 已经校验通过的 api_key, 下次就不再校验
+// validApiKeys 已经校验通过的 api_key, 下次就不再校验, key 为 emby 后端名称 + api_key
 //
 // 这个 map 不会进行大小限制, 考虑到 emby 原服务器中合法的 api_key 个数不是无限个
 // 所以这里也不用限制太多
@@ -46,6 +45,8 @@ const (
 const UnauthorizedResp = "Access token is invalid or expired."
 
 // AuthorizationTokenExtractReg 匹配 Authorization 头中 Token 字段
+//
+// 兼容 Emby 的 Emby Token="xxx" 以及 Jellyfin 的 MediaBrowser Token="xxx" 两种格式
 var AuthorizationTokenExtractReg = regexp.MustCompile(`(?i)token="([^"]+)"`)
 
 // ApiKeyChecker 对指定的 api 进行鉴权
@@ -64,14 +65,16 @@ func ApiKeyChecker() gin.HandlerFunc {
 		regexp.MustCompile(constant.Reg_ProxySubtitle),
 		regexp.MustCompile(constant.Reg_ShowEpisodes),
 		regexp.MustCompile(constant.Reg_UserItems),
+		regexp.MustCompile(constant.Reg_JellyfinItems),
 	}
 
 	return func(c *gin.Context) {
 		// 1 取出 api_key
 		kType, kName, apiKey := getApiKey(c)
+		e := embyOf(c)
 
 		// 2 如果该 key 已经是被信任的, 跳过校验
-		if _, ok := validApiKeys.Load(apiKey); ok {
+		if _, ok := validApiKeys.Load(e.Name + "|" + apiKey); ok {
 			return
 		}
 
@@ -88,7 +91,7 @@ func ApiKeyChecker() gin.HandlerFunc {
 		}
 
 		// 4 发出请求, 验证 api_key
-		u := config.C.Emby.Host + AuthUri
+		u := e.Host + authUri(e)
 		var header http.Header
 		if kType == Query {
 			u = urls.AppendArgs(u, kName, apiKey)
@@ -99,6 +102,7 @@ func ApiKeyChecker() gin.HandlerFunc {
 		resp, err := https.Get(u).Header(header).Do()
 		if err != nil {
 			logs.Error("鉴权失败: %v", err)
+			apiKeyChecks.Inc(ApiKeyCheckError)
 			c.Abort()
 			return
 		}
@@ -111,23 +115,33 @@ func ApiKeyChecker() gin.HandlerFunc {
 		respBody := strings.TrimSpace(string(bodyBytes))
 
 		// 5 判断是否被源服务器拒绝
-		if resp.StatusCode == http.StatusUnauthorized && respBody == UnauthorizedResp {
+		if isUnauthorized(e, resp.StatusCode, respBody) {
+			apiKeyChecks.Inc(ApiKeyCheckRejected)
 			c.String(http.StatusUnauthorized, "鉴权失败")
 			c.Abort()
 			return
 		}
 
 		// 6 校验通过, 加入信任集合
-		validApiKeys.Store(apiKey, struct{}{})
+		apiKeyChecks.Inc(ApiKeyCheckPassed)
+		validApiKeys.Store(e.Name+"|"+apiKey, struct{}{})
 	}
 }
 
 // getApiKey 获取请求中的 api_key 信息
+//
+// 源服务器为 Jellyfin 时, query 形式的 api_key 统一使用 ApiKey 参数名传递
 func getApiKey(c *gin.Context) (keyType ApiKeyType, keyName string, apiKey string) {
 	if c == nil {
 		return Query, "", ""
 	}
 
+	defer func() {
+		if keyType == Query && apiKey != "" && embyOf(c).IsJellyfin() {
+			keyName = JellyfinQueryApiKeyName
+		}
+	}()
+
 	keyName = QueryApiKeyName
 	keyType = Query
 	apiKey = c.Query(keyName)
@@ -135,6 +149,12 @@ func getApiKey(c *gin.Context) (keyType ApiKeyType, keyName string, apiKey strin
 		return
 	}
 
+	keyName = JellyfinQueryApiKeyName
+	apiKey = c.Query(keyName)
+	if strs.AllNotEmpty(apiKey) {
+		return
+	}
+
 	keyName = QueryTokenName
 	apiKey = c.Query(keyName)
 	if strs.AllNotEmpty(apiKey) {
@@ -152,6 +172,12 @@ func getApiKey(c *gin.Context) (keyType ApiKeyType, keyName string, apiKey strin
 		return
 	}
 
+	keyName = HeaderMediaBrowserTokenName
+	apiKey = c.GetHeader(keyName)
+	if strs.AllNotEmpty(apiKey) {
+		return
+	}
+
 	keyName = HeaderAuthName
 	apiKey = c.GetHeader(keyName)
 	if strs.AllNotEmpty(apiKey) {
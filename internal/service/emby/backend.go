@@ -0,0 +1,81 @@
+package emby
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
+
+	"github.com/gin-gonic/gin"
+)
+
+// internalSecret 进程级随机密钥, 用于识别服务内部自请求
+var internalSecret = func() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+// setInternalBackend 为服务内部自请求指定 emby 后端
+func setInternalBackend(header http.Header, e *config.Emby) {
+	header.Set(constant.EmbyBackendHeader, e.Name)
+	header.Set(constant.InternalSecretHeader, internalSecret)
+}
+
+// internalBackend 解析服务内部自请求指定的 emby 后端, 并移除相关请求头
+//
+// 仅当请求来自本机回环地址且携带正确的进程密钥时才信任指定的后端
+func internalBackend(c *gin.Context) (*config.Emby, bool) {
+	name := c.GetHeader(constant.EmbyBackendHeader)
+	secret := c.GetHeader(constant.InternalSecretHeader)
+	c.Request.Header.Del(constant.EmbyBackendHeader)
+	c.Request.Header.Del(constant.InternalSecretHeader)
+	if name == "" || secret != internalSecret {
+		return nil, false
+	}
+	if ip := net.ParseIP(c.RemoteIP()); ip == nil || !ip.IsLoopback() {
+		return nil, false
+	}
+	return config.C.Emby.Backend(name)
+}
+
+// BackendSelector 根据请求的 Host 头, 监听端口或路径前缀选择 emby 后端
+//
+// 选中的后端配置存放在 Gin 上下文中, 通过路径前缀匹配时, 会移除请求路径中的前缀;
+// 服务内部自请求通过 constant.EmbyBackendHeader 请求头直接指定后端
+func BackendSelector() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(config.C.Emby.Backends) == 0 {
+			return
+		}
+		if e, ok := internalBackend(c); ok {
+			c.Set(constant.EmbyBackendGinKey, e)
+			return
+		}
+		e, prefix := config.C.Emby.Select(c.Request.Host, c.GetString(webport.GinKey), c.Request.URL.Path)
+		if prefix != "" {
+			c.Request.URL.Path = strings.TrimPrefix(c.Request.URL.Path, prefix)
+			c.Request.URL.RawPath = ""
+			c.Request.RequestURI = strings.TrimPrefix(c.Request.RequestURI, prefix)
+			if c.Request.URL.Path == "" {
+				c.Request.URL.Path, c.Request.RequestURI = "/", "/"+c.Request.RequestURI
+			}
+		}
+		c.Set(constant.EmbyBackendGinKey, e)
+	}
+}
+
+// embyOf 获取当前请求选中的 emby 后端配置, 未选中时返回顶层配置
+func embyOf(c *gin.Context) *config.Emby {
+	if c != nil {
+		if e, ok := c.Get(constant.EmbyBackendGinKey); ok {
+			return e.(*config.Emby)
+		}
+	}
+	return config.C.Emby
+}
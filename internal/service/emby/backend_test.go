@@ -0,0 +1,54 @@
+package emby
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBackendSelector(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.C = &config.Config{Emby: &config.Emby{Name: "main", Backends: []*config.EmbyBackend{
+		{Emby: config.Emby{Name: "jf"}, PathPrefix: "/jf"},
+		{Emby: config.Emby{Name: "by-host"}, Hosts: []string{"b.com"}},
+	}}}
+
+	tests := []struct {
+		name        string
+		host        string
+		uri         string
+		wantBackend string
+		wantPath    string
+		wantUri     string
+	}{
+		{name: "未匹配", host: "a.com", uri: "/emby/Items/1?api_key=x", wantBackend: "main", wantPath: "/emby/Items/1", wantUri: "/emby/Items/1?api_key=x"},
+		{name: "移除路径前缀", host: "a.com", uri: "/jf/Items/1?api_key=x", wantBackend: "jf", wantPath: "/Items/1", wantUri: "/Items/1?api_key=x"},
+		{name: "只有路径前缀", host: "a.com", uri: "/jf", wantBackend: "jf", wantPath: "/", wantUri: "/"},
+		{name: "只有路径前缀以及参数", host: "a.com", uri: "/jf?a=1", wantBackend: "jf", wantPath: "/", wantUri: "/?a=1"},
+		{name: "转义路径", host: "a.com", uri: "/jf/Videos/1/a%20b.mkv", wantBackend: "jf", wantPath: "/Videos/1/a b.mkv", wantUri: "/Videos/1/a%20b.mkv"},
+		{name: "host 匹配", host: "b.com", uri: "/emby/Items/1", wantBackend: "by-host", wantPath: "/emby/Items/1", wantUri: "/emby/Items/1"},
+		{name: "按配置顺序匹配", host: "b.com", uri: "/jf/Items/1", wantBackend: "jf", wantPath: "/Items/1", wantUri: "/Items/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", tt.uri, nil)
+			c.Request.Host = tt.host
+			c.Request.RemoteAddr = "192.168.1.2:1234"
+
+			BackendSelector()(c)
+			if got := embyOf(c).Name; got != tt.wantBackend {
+				t.Errorf("后端 = %s, want %s", got, tt.wantBackend)
+			}
+			if c.Request.URL.Path != tt.wantPath || c.Request.RequestURI != tt.wantUri {
+				t.Errorf("路径 = (%s, %s), want (%s, %s)", c.Request.URL.Path, c.Request.RequestURI, tt.wantPath, tt.wantUri)
+			}
+			if got := c.Request.URL.String(); tt.wantBackend == "jf" && got != tt.wantUri {
+				t.Errorf("URL.String() = %s, want %s", got, tt.wantUri)
+			}
+		})
+	}
+}
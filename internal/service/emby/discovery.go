@@ -0,0 +1,166 @@
+package emby
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/path"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
+)
+
+// discoveryMaxDrop 探测映射时, emby 路径最多去除的目录层级
+const discoveryMaxDrop = 2
+
+// DiscoverPathMappings 根据 openlist 存储列表和每个 emby 后端的媒体库采样, 自动探测 emby2openlist 映射
+//
+// 配置 path.discovery.apply 为 true 时, 探测结果会直接在对应的后端生效并持久化,
+// 否则只输出建议的映射配置; 未配置 api-key 的后端会被跳过
+func DiscoverPathMappings() error {
+	cfg := config.C.Path.Discovery
+	if !cfg.Enable {
+		return nil
+	}
+	embys := make([]*config.Emby, 0)
+	for _, e := range config.C.Emby.All() {
+		if strs.AnyEmpty(e.ApiKey) {
+			logs.Warn("[映射探测] emby 后端 [%s] 未配置 api-key, 跳过探测", e.Name)
+			continue
+		}
+		embys = append(embys, e)
+	}
+	if len(embys) == 0 {
+		return errors.New("emby.api-key 未配置, 无法读取 emby 媒体库")
+	}
+
+	// 1 读取 openlist 存储挂载路径
+	storageRes := openlist.FetchStorageList()
+	if storageRes.Code != http.StatusOK {
+		return fmt.Errorf("读取 openlist 存储列表失败: %s", storageRes.Msg)
+	}
+	mountPaths := make([]string, 0, len(storageRes.Data.Content))
+	for _, s := range storageRes.Data.Content {
+		if s.Disabled || s.MountPath == "" {
+			continue
+		}
+		mountPaths = append(mountPaths, s.MountPath)
+	}
+	if len(mountPaths) == 0 {
+		return errors.New("openlist 中没有可用的存储")
+	}
+
+	// 2 逐个后端读取 emby 媒体库并采样探测
+	libraries, found := 0, 0
+	for _, e := range embys {
+		folders, err := fetchVirtualFolders(e)
+		if err != nil {
+			logs.Warn("[映射探测] 读取 emby 后端 [%s] 的媒体库失败: %v", e.Name, err)
+			continue
+		}
+		libraries += len(folders)
+		for _, folder := range folders {
+			samples, err := fetchLibrarySamplePaths(e, folder.id, cfg.SampleSize)
+			if err != nil {
+				logs.Warn("[映射探测] emby 后端 [%s] 媒体库 [%s] 采样失败: %v", e.Name, folder.name, err)
+				continue
+			}
+			for _, sample := range samples {
+				if lm, ok := discoverSample(e, sample, mountPaths, cfg.Apply); ok {
+					found++
+					if !cfg.Apply {
+						logs.Warn("[映射探测] emby 后端 [%s] 媒体库 [%s] 建议添加映射配置: - %s:%s", e.Name, folder.name, lm.From, lm.To)
+					}
+					break
+				}
+			}
+		}
+	}
+
+	logs.Success("[映射探测] 完成, 共探测 %d 个 emby 后端的 %d 个媒体库, 发现 %d 条新映射", len(embys), libraries, found)
+	return nil
+}
+
+// libraryFolder emby 媒体库信息
+type libraryFolder struct {
+	id   string
+	name string
+}
+
+// fetchVirtualFolders 请求 emby 后端 e 的 "/Library/VirtualFolders" 接口, 获取所有媒体库
+func fetchVirtualFolders(e *config.Emby) ([]libraryFolder, error) {
+	res, _ := FetchOf(e, "/Library/VirtualFolders?"+QueryApiKeyName+"="+url.QueryEscape(e.ApiKey), http.MethodGet, nil, nil)
+	if res.Code != http.StatusOK {
+		return nil, errors.New(res.Msg)
+	}
+	if res.Data.Type() != jsons.JsonTypeArr {
+		return nil, fmt.Errorf("响应格式异常: %s", res.Data)
+	}
+
+	folders := make([]libraryFolder, 0, res.Data.Len())
+	res.Data.RangeArr(func(_ int, value *jsons.Item) error {
+		id, _ := value.Attr("ItemId").String()
+		name, _ := value.Attr("Name").String()
+		if id != "" {
+			folders = append(folders, libraryFolder{id: id, name: name})
+		}
+		return nil
+	})
+	return folders, nil
+}
+
+// fetchLibrarySamplePaths 请求 emby 后端 e 的 "/Items" 接口, 获取媒体库中若干个媒体的路径
+func fetchLibrarySamplePaths(e *config.Emby, parentId string, limit int) ([]string, error) {
+	q := url.Values{}
+	q.Set("ParentId", parentId)
+	q.Set("Recursive", "true")
+	q.Set("IncludeItemTypes", "Movie,Episode,Audio,MusicVideo")
+	q.Set("Fields", "Path")
+	q.Set("Limit", strconv.Itoa(limit))
+	q.Set(QueryApiKeyName, e.ApiKey)
+
+	res, _ := FetchOf(e, "/Items?"+q.Encode(), http.MethodGet, nil, nil)
+	if res.Code != http.StatusOK {
+		return nil, errors.New(res.Msg)
+	}
+	items, ok := res.Data.Attr("Items").Done()
+	if !ok || items.Type() != jsons.JsonTypeArr {
+		return nil, fmt.Errorf("响应格式异常: %s", res.Data)
+	}
+
+	paths := make([]string, 0, items.Len())
+	items.RangeArr(func(_ int, value *jsons.Item) error {
+		p, _ := value.Attr("Path").String()
+		if p != "" && !urls.IsRemote(p) && !strings.HasPrefix(p, e.LocalMediaRoot) {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	return paths, nil
+}
+
+// discoverSample 对 emby 后端 e 的单个媒体路径进行探测
+//
+// 已命中映射的路径不会进行探测; 探测成功时返回学习到的映射
+func discoverSample(e *config.Emby, embyPath string, mountPaths []string, apply bool) (path.LearnedMapping, bool) {
+	res := path.Emby2OpenlistOf(e, embyPath)
+	if res.Mapped && openlist.FetchFsGet(res.Path, nil).Code == http.StatusOK {
+		return path.LearnedMapping{}, false
+	}
+
+	for _, candidate := range path.StorageCandidates(res.EmbyPath, mountPaths, discoveryMaxDrop) {
+		if openlist.FetchFsGet(candidate, nil).Code != http.StatusOK {
+			continue
+		}
+		logs.Info("[映射探测] [%s] %s => %s", e.Name, embyPath, candidate)
+		return path.Learn(e.Name, res.EmbyPath, candidate, path.LearnSourceDiscovery, apply)
+	}
+	return path.LearnedMapping{}, false
+}
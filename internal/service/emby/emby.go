@@ -10,7 +10,6 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/bytess"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
@@ -21,27 +20,31 @@ import (
 
 func ProxySocket() func(*gin.Context) {
 
-	var proxy *httputil.ReverseProxy
-	var once = sync.Once{}
+	// proxies 每个 emby 后端对应一个代理, key 为后端名称
+	var proxies sync.Map
 
-	initFunc := func() {
-		origin := config.C.Emby.Host
+	newProxy := func(origin string) *httputil.ReverseProxy {
 		u, err := url.Parse(origin)
 		if err != nil {
 			panic("转换 emby host 异常: " + err.Error())
 		}
 
-		proxy = httputil.NewSingleHostReverseProxy(u)
+		proxy := httputil.NewSingleHostReverseProxy(u)
 
 		proxy.Director = func(r *http.Request) {
 			r.URL.Scheme = u.Scheme
 			r.URL.Host = u.Host
 		}
+		return proxy
 	}
 
 	return func(c *gin.Context) {
-		once.Do(initFunc)
-		proxy.ServeHTTP(c.Writer, c.Request)
+		e := embyOf(c)
+		proxy, ok := proxies.Load(e.Name)
+		if !ok {
+			proxy, _ = proxies.LoadOrStore(e.Name, newProxy(e.Host))
+		}
+		proxy.(*httputil.ReverseProxy).ServeHTTP(c.Writer, c.Request)
 	}
 }
 
@@ -52,7 +55,7 @@ func HandleImages(c *gin.Context) {
 	q := c.Request.URL.Query()
 	q.Del("quality")
 	q.Del("Quality")
-	q.Set("Quality", strconv.Itoa(config.C.Emby.ImagesQuality))
+	q.Set("Quality", strconv.Itoa(embyOf(c).ImagesQuality))
 	c.Request.RequestURI = c.Request.URL.Path + "?" + q.Encode()
 	ProxyOrigin(c)
 }
@@ -62,14 +65,14 @@ func ProxyOrigin(c *gin.Context) {
 	if c == nil {
 		return
 	}
-	origin := config.C.Emby.Host
+	origin := embyOf(c).Host
 
 	// 传递客户端 IP 到 emby
 	c.Request.Header.Set("X-Forwarded-For", c.ClientIP())
 	c.Request.Header.Set("X-Real-IP", c.ClientIP())
 
 	if err := https.ProxyPass(c.Request, c.Writer, origin); err != nil {
-		logs.Error("代理异常: %v", err)
+		logs.Ctx(c).Error("代理异常: %v", err)
 	}
 }
 
@@ -112,18 +115,18 @@ func TestProxyUri(c *gin.Context) bool {
 
 	bodyBytes, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		logs.Error("测试 uri 执行异常: %v", err)
+		logs.Ctx(c).Error("测试 uri 执行异常: %v", err)
 		return false
 	}
 	infos.Body = string(bodyBytes)
 
-	origin := config.C.Emby.Host
+	origin := embyOf(c).Host
 	resp, err := https.Request(infos.Method, origin+infos.Uri).
 		Header(c.Request.Header).
 		Body(io.NopCloser(bytes.NewBuffer(bodyBytes))).
 		Do()
 	if err != nil {
-		logs.Error("测试 uri 执行异常: %v", err)
+		logs.Ctx(c).Error("测试 uri 执行异常: %v", err)
 		return false
 	}
 	defer resp.Body.Close()
@@ -137,12 +140,12 @@ func TestProxyUri(c *gin.Context) bool {
 
 	bodyBytes, err = io.ReadAll(resp.Body)
 	if err != nil {
-		logs.Error("测试 uri 执行异常: %v", err)
+		logs.Ctx(c).Error("测试 uri 执行异常: %v", err)
 		return false
 	}
 	infos.RespBody = string(bodyBytes)
 	infos.RespStatus = resp.StatusCode
-	logs.Warn("测试 uri 代理信息: %s", jsons.FromValue(infos))
+	logs.Ctx(c).Warn("测试 uri 代理信息: %s", jsons.FromValue(infos))
 
 	c.Status(infos.RespStatus)
 	c.Writer.Write(bodyBytes)
@@ -152,7 +155,7 @@ func TestProxyUri(c *gin.Context) bool {
 
 // ProxyRoot web 首页代理
 func ProxyRoot(c *gin.Context) {
-	resp, err := https.Request(c.Request.Method, config.C.Emby.Host+c.Request.URL.String()).
+	resp, err := https.Request(c.Request.Method, embyOf(c).Host+c.Request.URL.String()).
 		Header(c.Request.Header).
 		Body(c.Request.Body).
 		DoSingle()
@@ -8,7 +8,6 @@ import (
 	"slices"
 	"strconv"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 
 	"github.com/gin-gonic/gin"
@@ -18,26 +17,31 @@ import (
 //
 // 如果开启了 emby.episodes-unplay-prior 配置,
 // 则会将未播剧集排在前面位置
+//
+// 如果开启了 emby.prefetch 配置, 会记录剧集的顺序, 用于预取下一集
 func ResortEpisodes(c *gin.Context) {
 	// 1 检查配置是否开启
-	if !config.C.Emby.EpisodesUnplayPrior {
-		checkErr(c, https.ProxyPass(c.Request, c.Writer, config.C.Emby.Host))
+	unplayPrior := embyOf(c).EpisodesUnplayPrior
+	if !unplayPrior && !embyOf(c).Prefetch.Enable {
+		checkErr(c, https.ProxyPass(c.Request, c.Writer, embyOf(c).Host))
 		return
 	}
 
 	// 2 去除分页限制
-	q := c.Request.URL.Query()
-	q.Del("Limit")
-	q.Del("StartIndex")
-	var querySuffix string
-	if len(q) > 0 {
-		querySuffix = "?" + q.Encode()
+	if unplayPrior {
+		q := c.Request.URL.Query()
+		q.Del("Limit")
+		q.Del("StartIndex")
+		var querySuffix string
+		if len(q) > 0 {
+			querySuffix = "?" + q.Encode()
+		}
+		c.Request.RequestURI = c.Request.URL.Path + querySuffix
 	}
-	c.Request.RequestURI = c.Request.URL.Path + querySuffix
 
 	// 3 代理请求
 	c.Request.Header.Del("Accept-Encoding")
-	resp, err := https.ProxyRequest(c.Request, config.C.Emby.Host)
+	resp, err := https.ProxyRequest(c.Request, embyOf(c).Host)
 	if checkErr(c, err) {
 		return
 	}
@@ -56,17 +60,29 @@ func ResortEpisodes(c *gin.Context) {
 	if err = json.Unmarshal(bodyBytes, &ih); checkErr(c, err) {
 		return
 	}
+	if embyOf(c).Prefetch.Enable {
+		recordEpisodes(embyOf(c).Name, ih.Items)
+	}
+	if unplayPrior {
+		if bodyBytes, err = resortUnplayedEpisodes(bodyBytes, ih.Items); checkErr(c, err) {
+			return
+		}
+	}
+
+	// 未开启未播优先时, 原样返回 emby 的响应体
 	resp.Header.Del("Content-Length")
 	https.CloneHeader(c.Writer, resp.Header)
-	defer func() {
-		bytes, _ := json.Marshal(ih)
-		c.Header("Content-Length", strconv.Itoa(len(bytes)))
-		c.Writer.WriteHeaderNow()
-		c.Writer.Write(bytes)
-	}()
+	c.Header("Content-Length", strconv.Itoa(len(bodyBytes)))
+	c.Writer.WriteHeaderNow()
+	c.Writer.Write(bodyBytes)
+}
 
-	if len(ih.Items) == 0 {
-		return
+// resortUnplayedEpisodes 将剧集列表中的未播剧集排在前面, 返回替换 Items 字段后的响应体
+//
+// 响应体中 Items 以外的字段保持不变
+func resortUnplayedEpisodes(bodyBytes []byte, items []json.RawMessage) ([]byte, error) {
+	if len(items) == 0 {
+		return bodyBytes, nil
 	}
 
 	type ValueInner struct {
@@ -74,11 +90,11 @@ func ResortEpisodes(c *gin.Context) {
 			Played bool
 		}
 	}
-	playedItems, allItems := make([]json.RawMessage, 0, len(ih.Items)), make([]json.RawMessage, 0, len(ih.Items))
-	for idx, value := range ih.Items {
+	playedItems, allItems := make([]json.RawMessage, 0, len(items)), make([]json.RawMessage, 0, len(items))
+	for idx, value := range items {
 		if len(allItems) > 0 {
 			// 找到第一个未播的剧集之后, 剩余剧集都当作是未播的
-			allItems = slices.Concat(allItems, ih.Items[idx:])
+			allItems = slices.Concat(allItems, items[idx:])
 			break
 		}
 
@@ -97,5 +113,15 @@ func ResortEpisodes(c *gin.Context) {
 
 	// 将已播的数据放在末尾
 	allItems = append(allItems, playedItems...)
-	ih.Items = allItems
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return nil, err
+	}
+	itemsBytes, err := json.Marshal(allItems)
+	if err != nil {
+		return nil, err
+	}
+	body["Items"] = itemsBytes
+	return json.Marshal(body)
 }
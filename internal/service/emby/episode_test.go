@@ -0,0 +1,162 @@
+package emby
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// episodesBody 剧集列表响应, 包含 Items 以外的字段
+const episodesBody = `{"Items":[{"Id":"1","RunTimeTicks":100,"UserData":{"Played":true}},{"Id":"2","RunTimeTicks":100,"UserData":{"Played":false}},{"Id":"3","RunTimeTicks":100}],"TotalRecordCount":3,"StartIndex":0}`
+
+// standInEmby 模拟 emby 服务器, 记录 PlaybackInfo 请求次数
+func standInEmby(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var playbackInfoHits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/Episodes"):
+			fmt.Fprint(w, episodesBody)
+		case strings.HasSuffix(r.URL.Path, "/PlaybackInfo"):
+			playbackInfoHits.Add(1)
+			fmt.Fprint(w, `{"MediaSources":[{"Id":"ms1","Path":"http://remote/a.mkv"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &playbackInfoHits
+}
+
+// setupEpisodeEmby 使用模拟的 emby 服务器初始化配置
+func setupEpisodeEmby(t *testing.T, host string, unplayPrior bool) *config.Emby {
+	gin.SetMode(gin.TestMode)
+	config.BasePath = t.TempDir()
+	e := &config.Emby{
+		Host:                host,
+		EpisodesUnplayPrior: unplayPrior,
+		Prefetch:            &config.Prefetch{Enable: true},
+		ResolutionCache:     &config.ResolutionCache{Enable: true},
+	}
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	config.C = &config.Config{Emby: e}
+	episodeNodesMu.Lock()
+	episodeNodes = map[string]episodeNode{}
+	episodeNodesMu.Unlock()
+	return e
+}
+
+func TestResortEpisodes(t *testing.T) {
+	srv, _ := standInEmby(t)
+	tests := []struct {
+		name        string
+		unplayPrior bool
+		wantIds     []string
+		wantRaw     bool
+	}{
+		{name: "只记录剧集顺序时原样返回", unplayPrior: false, wantIds: []string{"1", "2", "3"}, wantRaw: true},
+		{name: "未播优先", unplayPrior: true, wantIds: []string{"2", "3", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := setupEpisodeEmby(t, srv.URL, tt.unplayPrior)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/Shows/9/Episodes?Limit=1&UserId=u", nil)
+
+			ResortEpisodes(c)
+			body := w.Body.String()
+			if tt.wantRaw && body != episodesBody {
+				t.Errorf("响应体被修改: %s", body)
+			}
+			var res struct {
+				Items []struct {
+					Id string
+				}
+				TotalRecordCount int
+				StartIndex       *int
+			}
+			if err := json.Unmarshal([]byte(body), &res); err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, 0, len(res.Items))
+			for _, item := range res.Items {
+				ids = append(ids, item.Id)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIds, ",") {
+				t.Errorf("剧集顺序 = %v, want %v", ids, tt.wantIds)
+			}
+			if res.TotalRecordCount != 3 || res.StartIndex == nil {
+				t.Errorf("Items 以外的字段丢失: %s", body)
+			}
+			if w.Header().Get("Content-Length") != fmt.Sprint(len(body)) {
+				t.Errorf("Content-Length = %s, 实际长度: %d", w.Header().Get("Content-Length"), len(body))
+			}
+
+			episodeNodesMu.RLock()
+			node := episodeNodes[e.Name+"|1"]
+			episodeNodesMu.RUnlock()
+			if node.nextId != "2" {
+				t.Errorf("下一集 = %q, want 2", node.nextId)
+			}
+		})
+	}
+}
+
+func TestRecordEpisodesEvict(t *testing.T) {
+	episodeNodesMu.Lock()
+	episodeNodes = map[string]episodeNode{}
+	old := time.Now().Add(-time.Hour)
+	for i := range maxEpisodeNodes {
+		episodeNodes[fmt.Sprintf("main|old-%d", i)] = episodeNode{nextId: "x", recordedAt: old.Add(time.Duration(i) * time.Millisecond)}
+	}
+	episodeNodesMu.Unlock()
+
+	items := []json.RawMessage{json.RawMessage(`{"Id":"a"}`), json.RawMessage(`{"Id":"b"}`), json.RawMessage(`{"Id":"c"}`)}
+	recordEpisodes("main", items)
+
+	episodeNodesMu.RLock()
+	defer episodeNodesMu.RUnlock()
+	if len(episodeNodes) != maxEpisodeNodes {
+		t.Errorf("剧集数量 = %d, want %d", len(episodeNodes), maxEpisodeNodes)
+	}
+	for _, key := range []string{"main|a", "main|b", "main|old-2"} {
+		if _, ok := episodeNodes[key]; !ok {
+			t.Errorf("缺少剧集: %s", key)
+		}
+	}
+	for _, key := range []string{"main|old-0", "main|old-1"} {
+		if _, ok := episodeNodes[key]; ok {
+			t.Errorf("最早记录的剧集未被淘汰: %s", key)
+		}
+	}
+}
+
+func TestPrefetchSinglePlaybackInfo(t *testing.T) {
+	srv, hits := standInEmby(t)
+	e := setupEpisodeEmby(t, srv.URL, false)
+	recordEpisodes(e.Name, []json.RawMessage{json.RawMessage(`{"Id":"1","RunTimeTicks":100}`), json.RawMessage(`{"Id":"2"}`)})
+	prefetchedMu.Lock()
+	prefetched = map[string]time.Time{}
+	prefetchedMu.Unlock()
+
+	cur := ItemInfo{Id: "1", Emby: e, ApiKeyType: Query, ApiKeyName: QueryApiKeyName, ApiKey: "k"}
+	prefetchNextEpisode(cur, 90, nil)
+	if got := hits.Load(); got != 1 {
+		t.Errorf("PlaybackInfo 请求次数 = %d, want 1", got)
+	}
+	if r, ok := lookupResolution(e.Name, "2", "ms1"); !ok || r.EmbyPath != "http://remote/a.mkv" {
+		t.Errorf("下一集的解析结果未缓存: %+v", r)
+	}
+}
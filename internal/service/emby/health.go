@@ -0,0 +1,26 @@
+package emby
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+)
+
+// PingTimeout 探测 emby 可用性的超时时间
+const PingTimeout = 5 * time.Second
+
+// Ping 请求源服务器的公开信息接口, 探测 emby 后端是否可用
+func Ping(e *config.Emby) error {
+	resp, err := https.Get(e.Host + apiUri(e, "/System/Info/Public")).Timeout(PingTimeout).Do()
+	if err != nil {
+		return fmt.Errorf("请求源服务器失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("源服务器响应异常: %s", resp.Status)
+	}
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"net/http"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/gin-gonic/gin"
@@ -30,11 +29,11 @@ type ItemCounts struct {
 
 // HandleItemsCounts 处理 /Items/Counts 接口
 func HandleItemsCounts(c *gin.Context) {
-	cfg := config.C.Emby.ItemsCounts
+	cfg := embyOf(c).ItemsCounts
 
 	// 如果未启用自定义或模式为 origin，代理回源
 	if !cfg.Enable || cfg.Mode == "origin" {
-		logs.Info("[ItemsCounts] 代理回源获取真实数据")
+		logs.Ctx(c).Info("[ItemsCounts] 代理回源获取真实数据")
 		ProxyOrigin(c)
 		return
 	}
@@ -52,7 +51,7 @@ func HandleItemsCounts(c *gin.Context) {
 				if libName == "" {
 					libName = parentID
 				}
-				logs.Info("[ItemsCounts] 返回媒体库 [%s] 的自定义统计", libName)
+				logs.Ctx(c).Info("[ItemsCounts] 返回媒体库 [%s] 的自定义统计", libName)
 				counts := ItemCounts{
 					MovieCount:      libCounts.MovieCount,
 					SeriesCount:     libCounts.SeriesCount,
@@ -73,13 +72,13 @@ func HandleItemsCounts(c *gin.Context) {
 				return
 			}
 			// 没有配置该媒体库，记录警告并代理回源
-			logs.Warn("[ItemsCounts] 媒体库 ID [%s] 未在配置中，代理回源", parentID)
+			logs.Ctx(c).Warn("[ItemsCounts] 媒体库 ID [%s] 未在配置中，代理回源", parentID)
 			ProxyOrigin(c)
 			return
 		}
 
 		// 没有 ParentId 参数，返回全局默认值
-		logs.Info("[ItemsCounts] 返回全局默认自定义统计数据")
+		logs.Ctx(c).Info("[ItemsCounts] 返回全局默认自定义统计数据")
 		counts := ItemCounts{
 			MovieCount:      cfg.MovieCount,
 			SeriesCount:     cfg.SeriesCount,
@@ -102,12 +101,12 @@ func HandleItemsCounts(c *gin.Context) {
 
 	// 修改模式：基于真实数据进行修改
 	if cfg.Mode == "modify" {
-		logs.Info("[ItemsCounts] 获取真实数据并修改")
+		logs.Ctx(c).Info("[ItemsCounts] 获取真实数据并修改")
 
 		// 获取真实数据
 		realCounts, err := fetchRealItemsCounts(c)
 		if err != nil {
-			logs.Error("[ItemsCounts] 获取真实数据失败: %v, 回源处理", err)
+			logs.Ctx(c).Error("[ItemsCounts] 获取真实数据失败: %v, 回源处理", err)
 			ProxyOrigin(c)
 			return
 		}
@@ -128,20 +127,20 @@ func HandleItemsCounts(c *gin.Context) {
 		realCounts.BookCount = int(float64(realCounts.BookCount) * cfg.Multiplier)
 		realCounts.ItemCount = int(float64(realCounts.ItemCount) * cfg.Multiplier)
 
-		logs.Success("[ItemsCounts] 返回修改后的统计数据 (系数: %.2f)", cfg.Multiplier)
+		logs.Ctx(c).Success("[ItemsCounts] 返回修改后的统计数据 (系数: %.2f)", cfg.Multiplier)
 		c.JSON(http.StatusOK, realCounts)
 		return
 	}
 
 	// 未知模式，回源处理
-	logs.Warn("[ItemsCounts] 未知模式: %s, 回源处理", cfg.Mode)
+	logs.Ctx(c).Warn("[ItemsCounts] 未知模式: %s, 回源处理", cfg.Mode)
 	ProxyOrigin(c)
 }
 
 // fetchRealItemsCounts 从 Emby 源服务器获取真实的统计数据
 func fetchRealItemsCounts(c *gin.Context) (*ItemCounts, error) {
 	// 构建源服务器 URL
-	originURL := config.C.Emby.Host + c.Request.RequestURI
+	originURL := embyOf(c).Host + c.Request.RequestURI
 
 	// 发起请求
 	resp, err := https.Get(originURL).
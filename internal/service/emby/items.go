@@ -34,7 +34,7 @@ const (
 // ResortRandomItems 对随机的 items 列表进行重排序
 func ResortRandomItems(c *gin.Context) {
 	// 如果没有开启配置, 代理原请求并返回
-	if !config.C.Emby.ResortRandomItems {
+	if !embyOf(c).ResortRandomItems {
 		ProxyOrigin(c)
 		return
 	}
@@ -67,7 +67,7 @@ func ResortRandomItems(c *gin.Context) {
 	defer func() {
 		respBody, _ := json.Marshal(ih)
 		if err != nil {
-			logs.Error("随机排序接口非预期响应, err: %v, 返回原始响应", err)
+			logs.Ctx(c).Error("随机排序接口非预期响应, err: %v, 返回原始响应", err)
 			respBody = bodyBytes
 		}
 
@@ -99,7 +99,7 @@ func RandomItemsWithLimit(c *gin.Context) {
 	q.Set("Limit", "500")
 	q.Del("SortOrder")
 	u.RawQuery = q.Encode()
-	embyHost := config.C.Emby.Host
+	embyHost := embyOf(c).Host
 	c.Request.Header.Del("Accept-Encoding")
 	resp, err := https.Request(c.Request.Method, embyHost+u.String()).
 		Header(c.Request.Header).
@@ -144,7 +144,7 @@ func calcRandomItemsCacheKey(c *gin.Context) string {
 func ProxyAddItemsPreviewInfo(c *gin.Context) {
 	// 代理请求
 	c.Request.Header.Del("Accept-Encoding")
-	resp, err := https.ProxyRequest(c.Request, config.C.Emby.Host)
+	resp, err := https.ProxyRequest(c.Request, embyOf(c).Host)
 	if checkErr(c, err) {
 		return
 	}
@@ -234,7 +234,7 @@ func ProxyAddItemsPreviewInfo(c *gin.Context) {
 func ProxyLatestItems(c *gin.Context) {
 	// 代理请求
 	c.Request.Header.Del("Accept-Encoding")
-	resp, err := https.ProxyRequest(c.Request, config.C.Emby.Host)
+	resp, err := https.ProxyRequest(c.Request, embyOf(c).Host)
 	if checkErr(c, err) {
 		return
 	}
@@ -251,7 +251,7 @@ func ProxyLatestItems(c *gin.Context) {
 	}
 
 	// 调试日志：检查响应类型和内容
-	logs.Info("[ProxyLatestItems] 响应类型: %v, 是否为空: %v, ParentId: %s",
+	logs.Ctx(c).Info("[ProxyLatestItems] 响应类型: %v, 是否为空: %v, ParentId: %s",
 		resJson.Type(), resJson.Empty(), c.Query("ParentId"))
 
 	// 预响应请求
@@ -262,7 +262,7 @@ func ProxyLatestItems(c *gin.Context) {
 
 	// 遍历 MediaSources 解码 path
 	if resJson.Type() != jsons.JsonTypeArr {
-		logs.Warn("[ProxyLatestItems] 响应不是数组类型，ParentId: %s, 类型: %v",
+		logs.Ctx(c).Warn("[ProxyLatestItems] 响应不是数组类型，ParentId: %s, 类型: %v",
 			c.Query("ParentId"), resJson.Type())
 		return
 	}
@@ -0,0 +1,61 @@
+package emby
+
+import (
+	"net/http"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+const (
+	// JellyfinAuthUri Jellyfin 鉴权地址, 合法的 token 可以访问当前用户信息
+	JellyfinAuthUri = "/Users/Me"
+
+	// JellyfinQueryApiKeyName Jellyfin 通过 query 传递 token 的参数名
+	JellyfinQueryApiKeyName = "ApiKey"
+
+	// HeaderMediaBrowserTokenName Jellyfin 支持的 token 请求头
+	HeaderMediaBrowserTokenName = "X-MediaBrowser-Token"
+
+	// JellyfinPlaybackPayload 请求 Jellyfin PlaybackInfo 的通用请求体
+	//
+	// Jellyfin 的 DeviceProfile 字段类型较为严格, 不兼容 Emby 的请求体,
+	// 这里声明所有容器均可直接播放, 避免源服务器创建转码任务
+	JellyfinPlaybackPayload = `{"DeviceProfile":{"MaxStaticBitrate":140000000,"MaxStreamingBitrate":140000000,"DirectPlayProfiles":[{"Type":"Video"},{"Type":"Audio"}],"TranscodingProfiles":[],"SubtitleProfiles":[{"Format":"vtt","Method":"External"},{"Format":"ass","Method":"External"},{"Format":"ssa","Method":"External"},{"Format":"srt","Method":"External"}]}}`
+)
+
+// apiUri 将接口 uri 转换为源服务器可识别的形式
+//
+// Emby 的接口统一携带 /emby 前缀, Jellyfin 则不需要
+func apiUri(e *config.Emby, uri string) string {
+	if e.IsJellyfin() {
+		return uri
+	}
+	return "/emby" + uri
+}
+
+// authUri 校验 api_key 的接口地址
+func authUri(e *config.Emby) string {
+	if e.IsJellyfin() {
+		return JellyfinAuthUri
+	}
+	return AuthUri
+}
+
+// isUnauthorized 判断鉴权接口的响应是否表示 api_key 不合法
+//
+// Emby 的 /Auth/Keys 接口需要管理员权限, 普通用户会响应 401, 需要结合响应体判断;
+// Jellyfin 的 /Users/Me 接口所有用户均可访问, 响应 401 即为不合法
+func isUnauthorized(e *config.Emby, code int, body string) bool {
+	if code != http.StatusUnauthorized {
+		return false
+	}
+	return e.IsJellyfin() || body == UnauthorizedResp
+}
+
+// playbackPayload 请求 PlaybackInfo 使用的请求体
+func playbackPayload(e *config.Emby) string {
+	if e.IsJellyfin() {
+		return JellyfinPlaybackPayload
+	}
+	return PlaybackCommonPayload
+}
@@ -0,0 +1,40 @@
+package emby
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveLocalMedia 由代理程序直接读取本地媒体文件响应客户端
+//
+// 支持 Range, ETag, If-Modified-Since 等条件请求,
+// 返回 false 表示文件无法读取, 调用方需自行回源处理
+func serveLocalMedia(c *gin.Context, embyPath string) bool {
+	localPath := embyOf(c).LocalMediaServe.MapPath(embyPath)
+	file, err := os.Open(localPath)
+	if err != nil {
+		logs.Ctx(c).Warn("读取本地媒体失败: %v, 回源处理", err)
+		return false
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		logs.Ctx(c).Warn("读取本地媒体失败: %s 不是有效的文件, 回源处理", localPath)
+		return false
+	}
+
+	// 媒体文件体积较大, 不经过缓存中间件
+	c.Header(cache.HeaderKeyExpired, "-1")
+	c.Header("ETag", fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()))
+	logs.Ctx(c).Success("本地媒体直接响应: %s", localPath)
+	http.ServeContent(c.Writer, c.Request, filepath.Base(localPath), stat.ModTime(), file)
+	return true
+}
@@ -34,6 +34,14 @@ const MediaSourceIdSegment = "[[_]]"
 // uri 中必须有 query 参数 MediaSourceId,
 // 如果没有携带该参数, 可能会请求到多个媒体, 默认返回第一个媒体的本地路径
 func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
+	path, _, err := getEmbyMediaSource(itemInfo)
+	return path, err
+}
+
+// getEmbyMediaSource 获取 Emby 指定媒体的 Path 参数以及对应的 MediaSourceId
+//
+// 没有指定 MediaSourceId 时, 返回第一个媒体的信息
+func getEmbyMediaSource(itemInfo ItemInfo) (string, string, error) {
 	var header http.Header
 	switch itemInfo.ApiKeyType {
 	case Header:
@@ -45,7 +53,7 @@ func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
 	}
 
 	innerRequest := func(method string) (*http.Response, error) {
-		resp, err := https.Request(method, config.C.Emby.Host+itemInfo.PlaybackInfoUri).Header(header).Do()
+		resp, err := https.Request(method, itemInfo.Emby.Host+itemInfo.PlaybackInfoUri).Header(header).Context(itemInfo.Ctx).Do()
 		if err != nil {
 			return nil, fmt.Errorf("请求 Emby 接口异常, error: %v", err)
 		}
@@ -66,7 +74,7 @@ func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
 	if err != nil {
 		resp, err = innerRequest(http.MethodGet)
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
 	}
 	defer resp.Body.Close()
@@ -80,50 +88,50 @@ func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取 Emby 响应异常, error: %v", err)
+		return "", "", fmt.Errorf("读取 Emby 响应异常, error: %v", err)
 	}
 	var holder MediaSourcesHolder
 	if err = json.Unmarshal(bodyBytes, &holder); err != nil {
-		return "", fmt.Errorf("解析 Emby 响应异常, error: %v, 原始响应: %s", err, string(bodyBytes))
+		return "", "", fmt.Errorf("解析 Emby 响应异常, error: %v, 原始响应: %s", err, string(bodyBytes))
 	}
 
 	if len(holder.MediaSources) == 0 {
-		return "", fmt.Errorf("获取不到 MediaSources, 原始响应: %v", string(bodyBytes))
+		return "", "", fmt.Errorf("获取不到 MediaSources, 原始响应: %v", string(bodyBytes))
 	}
 
-	var path string
-	var defaultPath string
+	var path, id string
+	var defaultPath, defaultId string
 
 	reqId := itemInfo.MsInfo.OriginId
 	// 获取指定 MediaSourceId 的 Path
 	for _, value := range holder.MediaSources {
 		if strs.AnyEmpty(defaultPath) {
 			// 默认选择第一个路径
-			defaultPath = value.Path
+			defaultPath, defaultId = value.Path, value.Id
 		}
 		if itemInfo.MsInfo.Empty {
 			// 如果没有传递 MediaSourceId, 就使用默认的 Path
 			break
 		}
 		if value.Id == reqId {
-			path = value.Path
+			path, id = value.Path, value.Id
 			break
 		}
 	}
 
 	if strs.AllNotEmpty(path) {
-		return path, nil
+		return path, id, nil
 	}
 	if strs.AllNotEmpty(defaultPath) {
-		return defaultPath, nil
+		return defaultPath, defaultId, nil
 	}
-	return "", fmt.Errorf("获取不到 Path 参数, 原始响应: %v", string(bodyBytes))
+	return "", "", fmt.Errorf("获取不到 Path 参数, 原始响应: %v", string(bodyBytes))
 }
 
 // findVideoPreviewInfos 查找 source 的所有转码资源
 //
 // 传递 resChan 进行异步查询, 通过监听 resChan 获取查询结果
-func findVideoPreviewInfos(source *jsons.Item, clientApiKey string, resChan chan []*jsons.Item) {
+func findVideoPreviewInfos(e *config.Emby, source *jsons.Item, clientApiKey string, resChan chan []*jsons.Item) {
 	if resChan == nil {
 		return
 	}
@@ -143,7 +151,10 @@ func findVideoPreviewInfos(source *jsons.Item, clientApiKey string, resChan chan
 	}
 
 	// 转换 openlist 绝对路径
-	openlistPathRes := path.Emby2Openlist(source.Attr("Path").Val().(string))
+	openlistPathRes := path.Emby2OpenlistOf(e, source.Attr("Path").Val().(string))
+	if size, ok := source.Attr("Size").Int64(); ok {
+		openlistPathRes.Size = size
+	}
 	var transcodingList []openlist.TranscodingVideoInfo
 	var subtitleList []openlist.TranscodingSubtitleInfo
 	firstFetchSuccess := false
@@ -162,9 +173,9 @@ func findVideoPreviewInfos(source *jsons.Item, clientApiKey string, resChan chan
 		}
 	}
 
-	// 首次请求失败, 遍历 openlist 所有根目录, 重新请求
+	// 首次请求失败, 获取候选路径, 重新请求
 	if !firstFetchSuccess {
-		paths, err := openlistPathRes.Range()
+		paths, err := openlistPathRes.Candidates()
 		if err != nil {
 			logs.Error("转换 openlist 路径异常: %v", err)
 			resChan <- nil
@@ -176,6 +187,7 @@ func findVideoPreviewInfos(source *jsons.Item, clientApiKey string, resChan chan
 			if res.Code == http.StatusOK {
 				transcodingList = res.Data.VideoPreviewPlayInfo.LiveTranscodingTaskList
 				subtitleList = res.Data.VideoPreviewPlayInfo.LiveTranscodingSubtitleTaskList
+				openlistPathRes.Resolved(path)
 				break
 			}
 		}
@@ -344,7 +356,7 @@ func detectVirtualVideoDisplayTitle(source *jsons.Item) {
 }
 
 // detectSubtitleStreamsDeliveryUrl 强制将外部挂载字幕的访问方式调整为直链访问
-func detectSubtitleStreamsDeliveryUrl(source *jsons.Item, apiKey string) {
+func detectSubtitleStreamsDeliveryUrl(source *jsons.Item, apiKeyName, apiKey string) {
 	if source == nil || source.Type() != jsons.JsonTypeObj {
 		return
 	}
@@ -384,7 +396,10 @@ func detectSubtitleStreamsDeliveryUrl(source *jsons.Item, apiKey string) {
 		value.Put("DeliveryMethod", jsons.FromValue("External"))
 
 		subIndex, _ := value.Attr("Index").Int()
-		u, _ := url.Parse(fmt.Sprintf("/Videos/%s/%s/Subtitles/%d/0/Stream.vtt?api_key=%s", itemId, id, subIndex, apiKey))
+		if apiKeyName == "" {
+			apiKeyName = QueryApiKeyName
+		}
+		u, _ := url.Parse(fmt.Sprintf("/Videos/%s/%s/Subtitles/%d/0/Stream.vtt?%s=%s", itemId, id, subIndex, apiKeyName, apiKey))
 		value.Put("DeliveryUrl", jsons.FromValue(u.String()))
 		return nil
 	})
@@ -427,7 +442,7 @@ func resolveItemInfo(c *gin.Context, routeType RouteType) (ItemInfo, error) {
 
 	// 匹配 item id
 	uri := c.Request.URL.Path
-	itemInfo := ItemInfo{RouteType: routeType}
+	itemInfo := ItemInfo{RouteType: routeType, Emby: embyOf(c), Ctx: c.Request.Context()}
 	switch routeType {
 	case RouteItems:
 		itemInfo.Id = filepath.Base(uri)
@@ -447,9 +462,17 @@ func resolveItemInfo(c *gin.Context, routeType RouteType) (ItemInfo, error) {
 	}
 	itemInfo.MsInfo = msInfo
 
+	if itemInfo.PlaybackInfoUri, err = buildPlaybackInfoUri(itemInfo); err != nil {
+		return ItemInfo{}, err
+	}
+	return itemInfo, nil
+}
+
+// buildPlaybackInfoUri 构建 item 信息查询接口 uri
+func buildPlaybackInfoUri(itemInfo ItemInfo) (string, error) {
 	u, err := url.Parse(fmt.Sprintf("/Items/%s/PlaybackInfo", itemInfo.Id))
 	if err != nil {
-		return ItemInfo{}, fmt.Errorf("构建 PlaybackInfo uri 失败, err: %v", err)
+		return "", fmt.Errorf("构建 PlaybackInfo uri 失败, err: %v", err)
 	}
 	q := u.Query()
 	// 默认只携带 query 形式的 api key
@@ -459,13 +482,11 @@ func resolveItemInfo(c *gin.Context, routeType RouteType) (ItemInfo, error) {
 	q.Set("reqformat", "json")
 	q.Set("IsPlayback", "false")
 	q.Set("AutoOpenLiveStream", "false")
-	if !msInfo.Empty {
-		q.Set("MediaSourceId", msInfo.OriginId)
+	if !itemInfo.MsInfo.Empty {
+		q.Set("MediaSourceId", itemInfo.MsInfo.OriginId)
 	}
 	u.RawQuery = q.Encode()
-	itemInfo.PlaybackInfoUri = u.String()
-
-	return itemInfo, nil
+	return u.String(), nil
 }
 
 // getRequestMediaSourceId 尝试从请求参数或请求体中获取 MediaSourceId 信息
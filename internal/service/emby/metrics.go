@@ -0,0 +1,18 @@
+package emby
+
+import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+
+// api_key 校验结果
+const (
+	ApiKeyCheckPassed   = "passed"   // 校验通过
+	ApiKeyCheckRejected = "rejected" // 被源服务器拒绝
+	ApiKeyCheckError    = "error"    // 校验请求失败
+)
+
+var (
+	// redirectDecisions 按资源提供方统计的资源重定向决策数
+	redirectDecisions = metrics.NewCounter("ge2o_redirects_total", "按资源提供方统计的资源重定向决策数", "backend", "outcome")
+
+	// apiKeyChecks 按校验结果统计的 api_key 校验次数, 不包含已被信任的 api_key
+	apiKeyChecks = metrics.NewCounter("ge2o_apikey_checks_total", "按校验结果统计的 api_key 校验次数", "result")
+)
@@ -47,10 +47,11 @@ var (
 func TransferPlaybackInfo(c *gin.Context) {
 	// 1 解析资源信息
 	itemInfo, err := resolveItemInfo(c, RoutePlaybackInfo)
-	logs.Info("ItemInfo 解析结果: %s", itemInfo)
+	logs.Ctx(c).Info("ItemInfo 解析结果: %s", itemInfo)
 	if checkErr(c, err) {
 		return
 	}
+	trackSession(c, itemInfo, SessionPreparing)
 
 	// 如果是远程资源, 直接代理到源服务器
 	if handleSpecialPlayback(c, itemInfo) {
@@ -68,8 +69,8 @@ func TransferPlaybackInfo(c *gin.Context) {
 	// 2 请求 emby 源服务器的 PlaybackInfo 信息
 	c.Request.Header.Del("Accept-Encoding")
 	originRequestBody := c.Request.Body
-	c.Request.Body = io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
-	res, respHeader := RawFetch(itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewBufferString(playbackPayload(itemInfo.Emby)))
+	res, respHeader := RawFetchOf(itemInfo.Emby, itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		checkErr(c, errors.New(res.Msg))
 		return
@@ -95,7 +96,7 @@ func TransferPlaybackInfo(c *gin.Context) {
 
 		detectVirtualVideoDisplayTitle(source)
 
-		detectSubtitleStreamsDeliveryUrl(source, itemInfo.ApiKey)
+		detectSubtitleStreamsDeliveryUrl(source, itemInfo.ApiKeyName, itemInfo.ApiKey)
 
 		// 如果客户端请求携带了 MediaSourceId 参数
 		// 在返回数据时, 需要重新设置回原始的 Id
@@ -113,7 +114,7 @@ func TransferPlaybackInfo(c *gin.Context) {
 
 		// 如果是本地媒体, 不处理
 		embyPath, _ := source.Attr("Path").String()
-		if strings.HasPrefix(embyPath, config.C.Emby.LocalMediaRoot) {
+		if strings.HasPrefix(embyPath, embyOf(c).LocalMediaRoot) {
 			return nil
 		}
 
@@ -148,7 +149,7 @@ func TransferPlaybackInfo(c *gin.Context) {
 			return nil
 		}
 		resChan := make(chan []*jsons.Item, 1)
-		go findVideoPreviewInfos(source, itemInfo.ApiKey, resChan)
+		go findVideoPreviewInfos(itemInfo.Emby, source, itemInfo.ApiKey, resChan)
 		resChans = append(resChans, resChan)
 		return nil
 	})
@@ -186,8 +187,8 @@ func handleSpecialPlayback(c *gin.Context, itemInfo ItemInfo) bool {
 
 	c.Request.Header.Del("Accept-Encoding")
 	originRequestBody := c.Request.Body
-	c.Request.Body = io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
-	res, _ := RawFetch(itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewBufferString(playbackPayload(itemInfo.Emby)))
+	res, _ := RawFetchOf(itemInfo.Emby, itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		return false
 	}
@@ -205,8 +206,8 @@ func handleSpecialPlayback(c *gin.Context, itemInfo ItemInfo) bool {
 
 		// 本地媒体
 		path, _ := value.Attr("Path").String()
-		if strings.HasPrefix(path, config.C.Emby.LocalMediaRoot) {
-			logs.Info("本地媒体: %s, 回源处理", path)
+		if strings.HasPrefix(path, embyOf(c).LocalMediaRoot) {
+			logs.Ctx(c).Info("本地媒体: %s, 回源处理", path)
 			flag = true
 		}
 
@@ -309,7 +310,7 @@ func useCacheSpacePlaybackInfo(c *gin.Context, itemInfo ItemInfo) bool {
 	findMediaSourceAndReturn := func(spaceCache cache.RespCache) bool {
 		jsonBody, err := spaceCache.JsonBody()
 		if err != nil {
-			logs.Error("解析缓存响应体失败: %v", err)
+			logs.Ctx(c).Error("解析缓存响应体失败: %v", err)
 			return false
 		}
 
@@ -364,7 +365,7 @@ func useCacheSpacePlaybackInfo(c *gin.Context, itemInfo ItemInfo) bool {
 
 	// 如果是单个查询, 则手动请求一次全量
 	if _, err := fetchFullPlaybackInfo(itemInfo); err != nil {
-		logs.Error("更新缓存空间 PlaybackInfo 信息异常: %v", err)
+		logs.Ctx(c).Error("更新缓存空间 PlaybackInfo 信息异常: %v", err)
 		c.String(http.StatusInternalServerError, "查无缓存, 请稍后尝试重新播放")
 		return true
 	}
@@ -416,7 +417,7 @@ func LoadCacheItems(c *gin.Context) {
 	if err != nil {
 		return
 	}
-	logs.Info("itemInfo 解析结果: %s", itemInfo)
+	logs.Ctx(c).Info("itemInfo 解析结果: %s", itemInfo)
 
 	// coverMediaSources 解析 PlaybackInfo 中的 MediaSources 属性
 	// 并覆盖到当前请求的响应中
@@ -443,7 +444,7 @@ func LoadCacheItems(c *gin.Context) {
 	// 缓存空间中没有当前 Item 的 PlaybackInfo 数据, 手动请求
 	bodyJson, err := fetchFullPlaybackInfo(itemInfo)
 	if err != nil {
-		logs.Warn("更新 Items 缓存异常: %v", err)
+		logs.Ctx(c).Warn("更新 Items 缓存异常: %v", err)
 		return
 	}
 	coverMediaSources(bodyJson)
@@ -459,13 +460,18 @@ func fetchFullPlaybackInfo(itemInfo ItemInfo) (*jsons.Item, error) {
 	q.Del("MediaSourceId")
 	u.RawQuery = q.Encode()
 
-	reqBody := io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
+	reqBody := io.NopCloser(bytes.NewBufferString(playbackPayload(itemInfo.Emby)))
 	header := make(http.Header)
 	header.Set("Content-Type", "text/plain")
+	if itemInfo.Emby.IsJellyfin() {
+		// Jellyfin 只接收 json 格式的请求体
+		header.Set("Content-Type", "application/json")
+	}
+	setInternalBackend(header, itemInfo.Emby)
 	if itemInfo.ApiKeyType == Header {
 		header.Set(itemInfo.ApiKeyName, itemInfo.ApiKey)
 	}
-	resp, err := https.Post(u.String()).Header(header).Body(reqBody).Do()
+	resp, err := https.Post(u.String()).Header(header).Body(reqBody).Context(itemInfo.Ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("获取全量 PlaybackInfo 失败: %v", err)
 	}
@@ -483,7 +489,7 @@ func fetchFullPlaybackInfo(itemInfo ItemInfo) (*jsons.Item, error) {
 
 // calcPlaybackInfoSpaceCacheKey 根据请求的 item 信息计算 PlaybackInfo 在缓存空间中的 key
 func calcPlaybackInfoSpaceCacheKey(itemInfo ItemInfo) string {
-	return itemInfo.Id + "_" + itemInfo.ApiKey
+	return itemInfo.Emby.Name + "_" + itemInfo.Id + "_" + itemInfo.ApiKey
 }
 
 // getPlaybackInfoByCacheSpace 从缓存空间中获取 PlaybackInfo 信息
@@ -31,22 +31,28 @@ func PlayingStoppedHelper(c *gin.Context) {
 
 	// 代理原始 Stopped 接口
 	ProxyOrigin(c)
+	endSession(c)
 
 	// 提取 api apiKey
 	kType, kName, apiKey := getApiKey(c)
 
-	// 至少播放 5 分钟才记录进度
+	itemId, _ := bodyJson.Attr("ItemId").String()
+	if itemIdNum, ok := bodyJson.Attr("ItemId").Int(); ok {
+		itemId = strconv.Itoa(itemIdNum)
+	}
 	positionTicks, ok := bodyJson.Attr("PositionTicks").Int64()
+	if itemId != "" {
+		msId, _ := bodyJson.Attr("MediaSourceId").String()
+		publishProxyPlaybackStop(c, itemId, msId, positionTicks)
+	}
+
+	// 至少播放 5 分钟才记录进度
 	var minPos int64 = 5 * 60 * 10_000_000
 	if !ok || positionTicks < minPos {
 		return
 	}
 
 	// 发送辅助请求记录播放进度
-	itemId, _ := bodyJson.Attr("ItemId").String()
-	if itemIdNum, ok := bodyJson.Attr("ItemId").Int(); ok {
-		itemId = strconv.Itoa(itemIdNum)
-	}
 	if strs.AnyEmpty(itemId) {
 		return
 	}
@@ -54,7 +60,7 @@ func PlayingStoppedHelper(c *gin.Context) {
 	body.Put("ItemId", jsons.FromValue(itemId))
 	body.Put("PlaySessionId", jsons.FromValue(randoms.RandomHex(32)))
 	body.Put("PositionTicks", jsons.FromValue(bodyJson.Attr("PositionTicks").Val()))
-	go sendPlayingProgress(kType, kName, apiKey, body)
+	go sendPlayingProgress(embyOf(c), kType, kName, apiKey, body)
 }
 
 // PlayingProgressHelper 拦截 Progress 请求, 如果进度报告为 0, 认为是无效请求
@@ -70,15 +76,34 @@ func PlayingProgressHelper(c *gin.Context) {
 		return
 	}
 
-	if pt, ok := bodyJson.Attr("PositionTicks").Int64(); ok && pt <= 10_000_000 {
+	pt, ok := bodyJson.Attr("PositionTicks").Int64()
+	if ok && pt <= 10_000_000 {
 		c.Status(http.StatusNoContent)
 		return
 	}
 	ProxyOrigin(c)
+
+	itemInfo := ItemInfo{RouteType: RouteStream, Emby: embyOf(c), Ctx: c.Request.Context()}
+	itemInfo.Id, _ = bodyJson.Attr("ItemId").String()
+	if itemIdNum, ok := bodyJson.Attr("ItemId").Int(); ok {
+		itemInfo.Id = strconv.Itoa(itemIdNum)
+	}
+	msId, _ := bodyJson.Attr("MediaSourceId").String()
+	if itemInfo.MsInfo, err = resolveMediaSourceId(msId); err != nil || itemInfo.Id == "" {
+		return
+	}
+	itemInfo.ApiKeyType, itemInfo.ApiKeyName, itemInfo.ApiKey = getApiKey(c)
+	touchSession(c, itemInfo, pt)
+
+	// 播放进度超过配置的百分比时, 预取下一集
+	if !embyOf(c).Prefetch.Enable {
+		return
+	}
+	go prefetchNextEpisode(itemInfo, pt, c.Request.Header.Clone())
 }
 
 // sendPlayingProgress 发送辅助播放进度请求
-func sendPlayingProgress(kType ApiKeyType, kName, apiKey string, body *jsons.Item) {
+func sendPlayingProgress(e *config.Emby, kType ApiKeyType, kName, apiKey string, body *jsons.Item) {
 	if body == nil {
 		return
 	}
@@ -103,11 +128,11 @@ func sendPlayingProgress(kType ApiKeyType, kName, apiKey string, body *jsons.Ite
 	}
 
 	logs.Tip("开始发送辅助 Progress 进度记录, 内容: %v", body)
-	if err := inner(config.C.Emby.Host + "/emby/Sessions/Playing/Progress"); err != nil {
+	if err := inner(e.Host + apiUri(e, "/Sessions/Playing/Progress")); err != nil {
 		logs.Warn("辅助发送 Progress 进度记录失败: %v", err)
 		return
 	}
-	if err := inner(config.C.Emby.Host + "/emby/Sessions/Playing/Stopped"); err != nil {
+	if err := inner(e.Host + apiUri(e, "/Sessions/Playing/Stopped")); err != nil {
 		logs.Warn("辅助发送 Progress 进度记录失败: %v", err)
 		return
 	}
@@ -0,0 +1,207 @@
+package emby
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/path"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
+)
+
+// prefetchInterval 同一集在该时间内只预取一次
+const prefetchInterval = time.Minute * 30
+
+// maxEpisodeNodes 最多记录的剧集顺序信息数量, 超出时淘汰最早记录的剧集
+const maxEpisodeNodes = 10000
+
+// PrefetchPlaylist 异步缓存 openlist 转码资源的播放列表, 由 m3u8 包注入
+var PrefetchPlaylist func(openlistPath, templateId string)
+
+// episodeNode 剧集在剧集列表中的顺序信息
+type episodeNode struct {
+	nextId       string    // 下一集的 item id
+	runTimeTicks int64     // 当前集的时长
+	recordedAt   time.Time // 记录时间
+}
+
+var (
+	// episodeNodes 剧集顺序信息, key 为 emby 后端名称 + 剧集 item id
+	episodeNodes   = map[string]episodeNode{}
+	episodeNodesMu sync.RWMutex
+
+	// prefetched 已预取过的剧集, key 为 emby 后端名称 + 下一集的 item id, value 为预取时间
+	prefetched   = map[string]time.Time{}
+	prefetchedMu sync.Mutex
+)
+
+// recordEpisodes 根据剧集列表接口的原始响应记录每一集的下一集
+func recordEpisodes(backend string, items []json.RawMessage) {
+	type episode struct {
+		Id           string
+		RunTimeTicks int64
+	}
+	eps := make([]episode, 0, len(items))
+	for _, item := range items {
+		var ep episode
+		if err := json.Unmarshal(item, &ep); err != nil || ep.Id == "" {
+			continue
+		}
+		eps = append(eps, ep)
+	}
+
+	episodeNodesMu.Lock()
+	defer episodeNodesMu.Unlock()
+	added := 0
+	for i := 0; i+1 < len(eps); i++ {
+		if _, ok := episodeNodes[backend+"|"+eps[i].Id]; !ok {
+			added++
+		}
+	}
+	if overflow := len(episodeNodes) + added - maxEpisodeNodes; overflow > 0 {
+		evictEpisodeNodes(overflow)
+	}
+	now := time.Now()
+	for i := 0; i+1 < len(eps); i++ {
+		episodeNodes[backend+"|"+eps[i].Id] = episodeNode{nextId: eps[i+1].Id, runTimeTicks: eps[i].RunTimeTicks, recordedAt: now}
+	}
+}
+
+// evictEpisodeNodes 移除最早记录的 n 条剧集顺序信息, 调用方需持有写锁
+func evictEpisodeNodes(n int) {
+	keys := make([]string, 0, len(episodeNodes))
+	for k := range episodeNodes {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return episodeNodes[a].recordedAt.Compare(episodeNodes[b].recordedAt)
+	})
+	for _, k := range keys[:min(n, len(keys))] {
+		delete(episodeNodes, k)
+	}
+}
+
+// prefetchNextEpisode 播放进度超过配置的百分比时, 异步预取下一集的直链
+//
+// cur 为当前正在播放的剧集信息, header 为客户端的请求头, 用于匹配直链缓存的 UA 类型
+func prefetchNextEpisode(cur ItemInfo, positionTicks int64, header http.Header) {
+	cfg := cur.Emby.Prefetch
+	if !cfg.Enable || positionTicks <= 0 {
+		return
+	}
+
+	episodeNodesMu.RLock()
+	node, ok := episodeNodes[cur.Emby.Name+"|"+cur.Id]
+	episodeNodesMu.RUnlock()
+	if !ok || node.runTimeTicks <= 0 || positionTicks*100 < node.runTimeTicks*int64(cfg.Percent) {
+		return
+	}
+
+	prefetchedMu.Lock()
+	prefetchKey := cur.Emby.Name + "|" + node.nextId
+	if at, ok := prefetched[prefetchKey]; ok && time.Since(at) < prefetchInterval {
+		prefetchedMu.Unlock()
+		return
+	}
+	for id, at := range prefetched {
+		if time.Since(at) >= prefetchInterval {
+			delete(prefetched, id)
+		}
+	}
+	prefetched[prefetchKey] = time.Now()
+	prefetchedMu.Unlock()
+
+	next := ItemInfo{
+		Id:         node.nextId,
+		MsInfo:     MsInfo{Empty: true},
+		ApiKey:     cur.ApiKey,
+		ApiKeyType: cur.ApiKeyType,
+		ApiKeyName: cur.ApiKeyName,
+		RouteType:  RouteStream,
+		Emby:       cur.Emby,
+		Ctx:        cur.Ctx,
+	}
+	var err error
+	if next.PlaybackInfoUri, err = buildPlaybackInfoUri(next); err != nil {
+		logs.Ctx(cur.Ctx).Warn("预取下一集失败: %v", err)
+		return
+	}
+
+	// 客户端播放时会携带 MediaSourceId, 预取默认的媒体源, 保证路径解析缓存的 key 与播放时一致
+	embyPath, msId, err := getEmbyMediaSource(next)
+	if err != nil {
+		logs.Ctx(cur.Ctx).Warn("预取下一集 [%s] 失败: %v", next.Id, err)
+		return
+	}
+	if next.MsInfo, err = resolveMediaSourceId(msId); err != nil {
+		logs.Ctx(cur.Ctx).Warn("预取下一集 [%s] 失败: %v", next.Id, err)
+		return
+	}
+	logs.Ctx(cur.Ctx).Info("剧集 [%s] 播放进度已超过 %d%%, 开始预取下一集 [%s]", cur.Id, cfg.Percent, next.Id)
+
+	// 直接使用本次查询到的 emby 路径, 不再重复请求 PlaybackInfo
+	openlistPath, err := prefetchOpenlistLink(next, recordEmbyPath(next, embyPath), header)
+	if err != nil {
+		logs.Ctx(cur.Ctx).Warn("预取下一集 [%s] 失败: %v", next.Id, err)
+		return
+	}
+
+	// 当前使用转码播放时, 同时预取相同清晰度的转码播放列表
+	if cur.MsInfo.Transcode && config.C.VideoPreview.Enable && PrefetchPlaylist != nil {
+		PrefetchPlaylist(openlistPath, cur.MsInfo.TemplateId)
+	}
+	logs.Ctx(cur.Ctx).Success("预取下一集 [%s] 成功: %s", next.Id, openlistPath)
+}
+
+// prefetchOpenlistLink 根据 item 的 emby 路径解析结果解析 openlist 路径并请求直链, 直链会被缓存到直链缓存中
+//
+// 返回解析成功的 openlist 路径
+func prefetchOpenlistLink(itemInfo ItemInfo, resolution Resolution, header http.Header) (string, error) {
+	embyPath := resolution.EmbyPath
+	if urls.IsRemote(embyPath) || strings.HasPrefix(embyPath, itemInfo.Emby.LocalMediaRoot) {
+		return "", errors.New("非 openlist 媒体, 跳过预取")
+	}
+
+	fi := openlist.FetchInfo{Header: header}
+	fetch := func(p string) bool {
+		if !config.C.Cache.Link.Enable {
+			// 未启用直链缓存时, 只解析路径
+			return openlist.FetchFsGet(p, nil).Code == http.StatusOK
+		}
+		fi.Path = p
+		res := openlist.FetchResource(fi)
+		if res.Code != http.StatusOK {
+			return false
+		}
+		resolution.resolveOpenlistPath(p, res.Data)
+		return true
+	}
+
+	if resolution.OpenlistPath != "" {
+		if fetch(resolution.OpenlistPath) {
+			return resolution.OpenlistPath, nil
+		}
+		resolution = resolution.invalidateOpenlistPath()
+	}
+
+	openlistPathRes := path.Emby2OpenlistOf(itemInfo.Emby, embyPath)
+	if openlistPathRes.Success && fetch(openlistPathRes.Path) {
+		return openlistPathRes.Path, nil
+	}
+	paths, err := openlistPathRes.Candidates()
+	if err != nil {
+		return "", err
+	}
+	if idx := slices.IndexFunc(paths, fetch); idx != -1 {
+		openlistPathRes.Resolved(paths[idx])
+		return paths[idx], nil
+	}
+	return "", errors.New("所有候选路径均请求失败")
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/audit"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/goedge"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/oss"
@@ -37,18 +38,22 @@ func Redirect2Transcode(c *gin.Context) {
 		templateId = itemInfo.MsInfo.TemplateId
 	}
 
-	apiKey := c.Query(QueryApiKeyName)
+	_, _, apiKey := getApiKey(c)
 	openlistPath := c.Query("openlist_path")
-	if strs.AnyEmpty(templateId) {
-		ProxyOrigin(c)
+
+	// 只有 template id 时, 需要先获取 openlist path, 由 Redirect2OpenlistLink 记录审计日志
+	if strs.AllNotEmpty(templateId) && strs.AnyEmpty(openlistPath) {
+		Redirect2OpenlistLink(c)
 		return
 	}
 
-	// 只有 template id 时, 需要先获取 openlist path
-	if strs.AnyEmpty(openlistPath) {
-		Redirect2OpenlistLink(c)
+	rec := newAuditRecord(c, RouteTranscode)
+	defer logAudit(c, &rec)
+	if strs.AnyEmpty(templateId) {
+		ProxyOrigin(c)
 		return
 	}
+	rec.Backend, rec.OpenlistPath = audit.BackendTranscode, openlistPath
 
 	tu, _ := url.Parse(https.ClientRequestHost(c.Request) + "/videos/proxy_playlist")
 	q := tu.Query()
@@ -67,45 +72,67 @@ func Redirect2OpenlistLink(c *gin.Context) {
 		return
 	}
 
+	rec := newAuditRecord(c, RouteStream)
+	defer logAudit(c, &rec)
+
 	// 1 解析要请求的资源信息
 	itemInfo, err := resolveItemInfo(c, RouteStream)
 	if checkErr(c, err) {
 		return
 	}
-	logs.Info("解析到的 itemInfo: %v", itemInfo)
+	logs.Ctx(c).Info("解析到的 itemInfo: %v", itemInfo)
+	rec.ItemId = itemInfo.Id
+	if !allowSession(c, itemInfo) {
+		logs.Ctx(c).Warn("同时播放的设备数已达上限, 拒绝播放: %s", itemInfo.Id)
+		c.Header(cache.HeaderKeyExpired, "-1")
+		c.String(http.StatusTooManyRequests, "同时播放的设备数已达上限")
+		return
+	}
+	trackSession(c, itemInfo, SessionPlaying)
 
 	// 2 如果请求的是转码资源, 重定向到本地的 m3u8 代理服务
 	msInfo := itemInfo.MsInfo
 	useTranscode := !msInfo.Empty && msInfo.Transcode
 	if useTranscode && msInfo.OpenlistPath != "" {
+		rec.Backend, rec.OpenlistPath = audit.BackendTranscode, msInfo.OpenlistPath
 		u, _ := url.Parse(strings.ReplaceAll(MasterM3U8UrlTemplate, "${itemId}", itemInfo.Id))
 		q := u.Query()
 		q.Set("template_id", itemInfo.MsInfo.TemplateId)
 		q.Set(QueryApiKeyName, itemInfo.ApiKey)
 		q.Set("openlist_path", itemInfo.MsInfo.OpenlistPath)
 		u.RawQuery = q.Encode()
-		logs.Success("重定向 playlist: %s", u.String())
+		logs.Ctx(c).Success("重定向 playlist: %s", u.String())
 		c.Redirect(http.StatusTemporaryRedirect, u.String())
 		return
 	}
 
 	// 3 请求资源在 Emby 中的 Path 参数
-	embyPath, err := getEmbyFileLocalPath(itemInfo)
+	resolution, err := resolveEmbyPath(itemInfo)
 	if checkErr(c, err) {
 		return
 	}
+	embyPath := resolution.EmbyPath
+	rec.EmbyPath = embyPath
 
 	// 4 如果是远程地址 (strm), 重定向处理
+	strmCfg := embyOf(c).Strm
+	if urls.IsRemote(embyPath) && strmCfg.IsPassthrough(embyPath) {
+		rec.Backend = audit.BackendOrigin
+		logs.Ctx(c).Info("strm 流媒体协议: %s, 回源处理", embyPath)
+		ProxyOrigin(c)
+		return
+	}
 	if urls.IsRemote(embyPath) {
-		finalPath := config.C.Emby.Strm.MapPath(embyPath)
-		finalPath = getFinalRedirectLink(finalPath, c.Request.Header.Clone())
-		logs.Success("重定向 strm: %s", finalPath)
+		rec.Backend = audit.BackendStrm
+		finalPath := embyOf(c).Strm.MapPath(embyPath)
+		finalPath = getFinalRedirectLink(strmCfg, finalPath, c.Request.Header.Clone())
+		logs.Ctx(c).Success("重定向 strm: %s", finalPath)
 		c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute*10))
 		c.Redirect(http.StatusTemporaryRedirect, finalPath)
 
 		// 异步发送一个播放 Playback 请求, 触发 emby 解析 strm 视频格式
 		go func() {
-			originUrl, err := url.Parse(config.C.Emby.Host + itemInfo.PlaybackInfoUri)
+			originUrl, err := url.Parse(itemInfo.Emby.Host + itemInfo.PlaybackInfoUri)
 			if err != nil {
 				return
 			}
@@ -113,7 +140,9 @@ func Redirect2OpenlistLink(c *gin.Context) {
 			q.Set("IsPlayback", "true")
 			q.Set("AutoOpenLiveStream", "true")
 			originUrl.RawQuery = q.Encode()
-			resp, err := https.Post(originUrl.String()).Body(io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))).Do()
+			header := make(http.Header)
+			header.Set("Content-Type", "application/json")
+			resp, err := https.Post(originUrl.String()).Header(header).Body(io.NopCloser(bytes.NewBufferString(playbackPayload(itemInfo.Emby)))).Context(itemInfo.Ctx).Do()
 			if err != nil {
 				return
 			}
@@ -124,19 +153,26 @@ func Redirect2OpenlistLink(c *gin.Context) {
 	}
 
 	// 5 如果是本地地址, 回源处理
-	if strings.HasPrefix(embyPath, config.C.Emby.LocalMediaRoot) {
-		logs.Info("本地媒体: %s, 回源处理", embyPath)
+	if strings.HasPrefix(embyPath, embyOf(c).LocalMediaRoot) {
+		rec.Backend = audit.BackendLocal
+		if embyOf(c).LocalMediaServe.Enable && serveLocalMedia(c, embyPath) {
+			return
+		}
+		logs.Ctx(c).Info("本地媒体: %s, 回源处理", embyPath)
 		newUri := strings.Replace(c.Request.RequestURI, "stream", "original", 1)
 		c.Redirect(http.StatusTemporaryRedirect, newUri)
 		return
 	}
 
+	// strm 内容为 openlist 路径时, 直接请求 openlist
+	strmOpenlist := strmCfg.IsOpenlistPath(embyPath)
+
 	// 6 如果启用了 OSS 重定向, 直接重定向到对象存储
-	if config.C.Oss.Enable {
-		logs.Info("OSS 模式已启用, 使用对象存储直链")
+	if config.C.Oss.Enable && !strmOpenlist {
+		logs.Ctx(c).Info("OSS 模式已启用, 使用对象存储直链")
 		ossUrl, err := oss.BuildURL(embyPath)
 		if err != nil {
-			logs.Error("生成 OSS URL 失败: %v, embyPath: %s", err, embyPath)
+			logs.Ctx(c).Error("生成 OSS URL 失败: %v, embyPath: %s", err, embyPath)
 			// 根据错误处理策略决定
 			if checkErr(c, err) {
 				return
@@ -145,24 +181,25 @@ func Redirect2OpenlistLink(c *gin.Context) {
 			// 添加 API Key 到响应头
 			if config.C.Oss.ApiKey.Enable && config.C.Oss.ApiKey.Key != "" {
 				c.Header(config.C.Oss.ApiKey.HeaderName, config.C.Oss.ApiKey.Key)
-				logs.Info("已添加 API Key 响应头: %s", config.C.Oss.ApiKey.HeaderName)
+				logs.Ctx(c).Info("已添加 API Key 响应头: %s", config.C.Oss.ApiKey.HeaderName)
 			}
 
 			// 设置缓存时间 (10分钟)
 			c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute*10))
 
-			logs.Success("重定向到 OSS: %s", ossUrl)
+			logs.Ctx(c).Success("重定向到 OSS: %s", ossUrl)
+			rec.Backend = audit.BackendOss
 			c.Redirect(http.StatusFound, ossUrl)
 			return
 		}
 	}
 
 	// 7 如果启用了 GoEdge CDN 重定向, 直接重定向到 GoEdge
-	if config.C.GoEdge.Enable {
-		logs.Info("GoEdge 模式已启用, 使用 GoEdge CDN 直链")
+	if config.C.GoEdge.Enable && !strmOpenlist {
+		logs.Ctx(c).Info("GoEdge 模式已启用, 使用 GoEdge CDN 直链")
 		goedgeUrl, err := goedge.BuildURL(embyPath)
 		if err != nil {
-			logs.Error("生成 GoEdge URL 失败: %v, embyPath: %s", err, embyPath)
+			logs.Ctx(c).Error("生成 GoEdge URL 失败: %v, embyPath: %s", err, embyPath)
 			// 根据错误处理策略决定
 			if checkErr(c, err) {
 				return
@@ -171,24 +208,33 @@ func Redirect2OpenlistLink(c *gin.Context) {
 			// 设置缓存时间 (10分钟)
 			c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute*10))
 
-			logs.Success("重定向到 GoEdge: %s", goedgeUrl)
+			logs.Ctx(c).Success("重定向到 GoEdge: %s", goedgeUrl)
+			rec.Backend = audit.BackendGoEdge
 			c.Redirect(http.StatusFound, goedgeUrl)
 			return
 		}
 	}
 
 	// 8 请求 openlist 资源
+	rec.Backend = audit.BackendOpenlist
 	fi := openlist.FetchInfo{
 		Header:       c.Request.Header.Clone(),
 		UseTranscode: useTranscode,
 		Format:       msInfo.TemplateId,
 	}
-	openlistPathRes := path.Emby2Openlist(embyPath)
-
 	allErrors := strings.Builder{}
+	// 直链探测失败后依次尝试其他候选路径和网盘转码,
+	// openlist 每个文件只返回一个直链, 无法枚举同一文件的其他 CDN 节点, 因此不做节点级别的重试
+	probeCfg := config.C.Openlist.LinkProbe
+	// tried 已尝试过的路径, probeFailed 直链探测失败的路径
+	tried, probeFailed := map[string]struct{}{}, make([]string, 0)
 	// handleOpenlistResource 根据传递的 path 请求 openlist 资源
 	handleOpenlistResource := func(path string) bool {
-		logs.Info("尝试请求 Openlist 资源: %s", path)
+		if _, ok := tried[path]; ok {
+			return false
+		}
+		tried[path] = struct{}{}
+		logs.Ctx(c).Info("尝试请求 Openlist 资源: %s", path)
 		fi.Path = path
 		res := openlist.FetchResource(fi)
 
@@ -199,40 +245,102 @@ func Redirect2OpenlistLink(c *gin.Context) {
 
 		// 处理直链
 		if !fi.UseTranscode {
-			res.Data.Url = config.C.Emby.Strm.MapPath(res.Data.Url)
-			logs.Success("请求成功, 重定向到: %s", res.Data.Url)
+			res.Data.Url = embyOf(c).Strm.MapPath(res.Data.Url)
+			if probeCfg.Enable {
+				if err := openlist.ProbeLink(res.Data.Url, c.Request.Header); err != nil {
+					logs.Ctx(c).Warn("直链不可用: %v, path: %s", err, path)
+					openlist.InvalidateLink(path)
+					probeFailed = append(probeFailed, path)
+					allErrors.WriteString(fmt.Sprintf("直链不可用: %v, path: %s;", err, path))
+					return false
+				}
+			}
+			resolution.resolveOpenlistPath(path, res.Data)
+			setSessionOpenlistPath(c, path)
+			rec.Backend, rec.OpenlistPath = audit.BackendOpenlist, path
+			logs.Ctx(c).Success("请求成功, 重定向到: %s", res.Data.Url)
 			c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute*10))
 			c.Redirect(http.StatusTemporaryRedirect, res.Data.Url)
 			return true
 		}
 
 		// 代理转码 m3u
-		u, _ := url.Parse(https.ClientRequestHost(c.Request) + "/videos/proxy_playlist")
-		q := u.Query()
-		q.Set("template_id", itemInfo.MsInfo.TemplateId)
-		q.Set(QueryApiKeyName, itemInfo.ApiKey)
-		q.Set("openlist_path", openlist.PathEncode(path))
-		u.RawQuery = q.Encode()
-		c.Redirect(http.StatusTemporaryRedirect, u.String())
+		resolution.resolveOpenlistPath(path, res.Data)
+		setSessionOpenlistPath(c, path)
+		rec.Backend, rec.OpenlistPath = audit.BackendTranscode, path
+		redirectProxyPlaylist(c, itemInfo, path)
 		return true
 	}
 
+	// 优先使用缓存的 openlist 路径
+	if resolution.OpenlistPath != "" {
+		if handleOpenlistResource(resolution.OpenlistPath) {
+			return
+		}
+		resolution = resolution.invalidateOpenlistPath()
+	}
+
+	openlistPathRes := path.Emby2OpenlistOf(embyOf(c), embyPath)
 	if openlistPathRes.Success && handleOpenlistResource(openlistPathRes.Path) {
 		return
 	}
-	paths, err := openlistPathRes.Range()
-	if checkErr(c, err) {
+	paths, err := openlistPathRes.Candidates()
+	if err != nil && len(probeFailed) == 0 {
+		checkErr(c, err)
 		return
 	}
-	if slices.ContainsFunc(paths, func(path string) bool {
-		return handleOpenlistResource(path)
-	}) {
+	if idx := slices.IndexFunc(paths, handleOpenlistResource); idx != -1 {
+		// 候选路径请求成功, 缓存路径并尝试学习映射关系
+		openlistPathRes.Resolved(paths[idx])
 		return
 	}
 
+	// 所有直链都不可用, 回退到网盘转码播放
+	if len(probeFailed) > 0 && probeCfg.TranscodeFallback && config.C.VideoPreview.Enable {
+		if templateId, ok := fallbackTemplateId(probeFailed[0]); ok {
+			logs.Ctx(c).Warn("所有直链均不可用, 回退到转码播放: %s, 清晰度: %s", probeFailed[0], templateId)
+			itemInfo.MsInfo.TemplateId = templateId
+			rec.Backend, rec.OpenlistPath = audit.BackendTranscode, probeFailed[0]
+			redirectProxyPlaylist(c, itemInfo, probeFailed[0])
+			return
+		}
+	}
+
 	checkErr(c, fmt.Errorf("获取直链失败: %s", allErrors.String()))
 }
 
+// redirectProxyPlaylist 重定向到本地的转码 m3u 代理
+func redirectProxyPlaylist(c *gin.Context, itemInfo ItemInfo, openlistPath string) {
+	u, _ := url.Parse(https.ClientRequestHost(c.Request) + "/videos/proxy_playlist")
+	q := u.Query()
+	q.Set("template_id", itemInfo.MsInfo.TemplateId)
+	q.Set(QueryApiKeyName, itemInfo.ApiKey)
+	q.Set("openlist_path", openlist.PathEncode(openlistPath))
+	u.RawQuery = q.Encode()
+	c.Redirect(http.StatusTemporaryRedirect, u.String())
+}
+
+// fallbackTemplateId 获取 openlist 资源可用于回退播放的转码清晰度, 选择未被忽略的最高清晰度
+func fallbackTemplateId(openlistPath string) (string, bool) {
+	res := openlist.FetchFsOther(openlistPath, nil)
+	if res.Code != http.StatusOK {
+		return "", false
+	}
+	var best *openlist.TranscodingVideoInfo
+	for _, task := range res.Data.VideoPreviewPlayInfo.LiveTranscodingTaskList {
+		if task.Url == "" || config.C.VideoPreview.IsTemplateIgnore(task.TemplateId) {
+			continue
+		}
+		if best == nil || task.TemplateWidth > best.TemplateWidth {
+			best = &task
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.TemplateId, true
+}
+
 // ProxyOriginalResource 拦截 original 接口
 func ProxyOriginalResource(c *gin.Context) {
 	if strings.Contains(strings.ToLower(c.Request.RequestURI), "subtitles") {
@@ -245,13 +353,16 @@ func ProxyOriginalResource(c *gin.Context) {
 		return
 	}
 
-	embyPath, err := getEmbyFileLocalPath(itemInfo)
+	resolution, err := resolveEmbyPath(itemInfo)
 	if checkErr(c, err) {
 		return
 	}
 
-	// 如果是本地媒体, 代理回源
-	if strings.HasPrefix(embyPath, config.C.Emby.LocalMediaRoot) {
+	// 如果是本地媒体, 直接响应或代理回源
+	if strings.HasPrefix(resolution.EmbyPath, embyOf(c).LocalMediaRoot) {
+		if embyOf(c).LocalMediaServe.Enable && serveLocalMedia(c, resolution.EmbyPath) {
+			return
+		}
 		ProxyOrigin(c)
 		return
 	}
@@ -271,13 +382,13 @@ func checkErr(c *gin.Context, err error) bool {
 	c.Header(cache.HeaderKeyExpired, "-1")
 
 	// 采用拒绝策略, 直接返回错误
-	if config.C.Emby.ProxyErrorStrategy == config.PeStrategyReject {
-		logs.Error("代理接口失败: %v", err)
+	if embyOf(c).ProxyErrorStrategy == config.PeStrategyReject {
+		logs.Ctx(c).Error("代理接口失败: %v", err)
 		c.String(http.StatusInternalServerError, "代理接口失败, 请检查日志")
 		return true
 	}
 
-	logs.Error("代理接口失败: %v, 回源处理", err)
+	logs.Ctx(c).Error("代理接口失败: %v, 回源处理", err)
 	ProxyOrigin(c)
 	return true
 }
@@ -286,18 +397,32 @@ func checkErr(c *gin.Context, err error) bool {
 //
 // 检测到 internal-redirect-enable 配置未启用时, 直接返回原始链接
 //
+// 请求时会携带 strm.headers 中与原始链接匹配的请求头, 请求成功的最终链接会被缓存
+//
 // 请求中途出现任何失败都会返回原始链接
-func getFinalRedirectLink(originLink string, header http.Header) string {
-
-	if !config.C.Emby.Strm.InternalRedirectEnable {
+func getFinalRedirectLink(strmCfg *config.Strm, originLink string, header http.Header) string {
+	if !strmCfg.InternalRedirectEnable {
 		logs.Info("internal-redirect-enable 未启用, 使用原始链接")
 		return originLink
 	}
 
+	if finalLink, ok := loadFinalLink(originLink, header); ok {
+		logs.Tip("命中 strm 最终链接缓存: %s", finalLink)
+		return finalLink
+	}
+
+	reqHeader := header.Clone()
+	if reqHeader == nil {
+		reqHeader = make(http.Header)
+	}
+	for k, v := range strmCfg.HeaderOf(originLink) {
+		reqHeader.Set(k, v)
+	}
+
 	var finalLink string
 	err := trys.Try(func() (err error) {
 		logs.Info("正在尝试内部重定向, originLink: [%s]", originLink)
-		fl, resp, e := https.Get(originLink).Header(header).DoRedirect()
+		fl, resp, e := https.Get(originLink).Header(reqHeader).DoRedirect()
 		if e != nil {
 			return e
 		}
@@ -311,5 +436,6 @@ func getFinalRedirectLink(originLink string, header http.Header) string {
 		return originLink
 	}
 
+	storeFinalLink(strmCfg.FinalLinkCacheTTLDuration(), originLink, finalLink, header)
 	return finalLink
 }
@@ -0,0 +1,233 @@
+package emby
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// ResolutionFileName 媒体路径解析缓存的持久化文件名称
+const ResolutionFileName = "emby-resolutions.json"
+
+// Resolution 媒体路径解析结果
+type Resolution struct {
+	Backend       string    `json:"backend,omitempty"` // emby 后端名称
+	ItemId        string    `json:"item_id"`           // item id
+	MediaSourceId string    `json:"media_source_id"`   // 原始 MediaSourceId
+	EmbyPath      string    `json:"emby_path"`         // 媒体在 emby 中的路径
+	OpenlistPath  string    `json:"openlist_path"`     // 媒体在 openlist 中的路径
+	Sign          string    `json:"sign"`              // openlist 文件签名
+	Size          int64     `json:"size"`              // openlist 文件大小
+	UpdatedAt     time.Time `json:"updated_at"`        // 最后一次校验的时间
+}
+
+// resolutionSaveDelay 解析结果变更后延迟写入磁盘的时间
+const resolutionSaveDelay = time.Second * 5
+
+// resolutions 已缓存的解析结果, key 为 emby 后端名称 + itemId + mediaSourceId
+var (
+	resolutions     = map[string]Resolution{}
+	resolutionsMu   sync.RWMutex
+	resolutionsOnce sync.Once
+
+	// resolutionsDirty 是否有尚未写入磁盘的变更
+	resolutionsDirty bool
+	// resolutionsSaveTimer 延迟写入的定时器, 为 nil 表示没有待执行的写入
+	resolutionsSaveTimer *time.Timer
+)
+
+// resolutionKey 生成解析结果的缓存 key
+func resolutionKey(backend, itemId, mediaSourceId string) string {
+	return backend + "|" + itemId + "|" + mediaSourceId
+}
+
+// loadResolutions 从磁盘中加载解析结果, 只会执行一次
+func loadResolutions() {
+	resolutionsOnce.Do(func() {
+		bytes, err := os.ReadFile(filepath.Join(config.BasePath, ResolutionFileName))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logs.Warn("读取媒体路径解析缓存失败: %v", err)
+			}
+			return
+		}
+
+		var list []Resolution
+		if err = json.Unmarshal(bytes, &list); err != nil {
+			logs.Warn("解析媒体路径解析缓存失败: %v", err)
+			return
+		}
+
+		resolutionsMu.Lock()
+		defer resolutionsMu.Unlock()
+		for _, r := range list {
+			if r.Backend == "" {
+				r.Backend = config.C.Emby.Name
+			}
+			resolutions[resolutionKey(r.Backend, r.ItemId, r.MediaSourceId)] = r
+		}
+		logs.Info("已加载 %d 条媒体路径解析缓存", len(list))
+	})
+}
+
+// saveResolutions 延迟将解析结果写入磁盘, 合并短时间内的多次变更, 调用方需持有写锁
+func saveResolutions() {
+	resolutionsDirty = true
+	if resolutionsSaveTimer == nil {
+		resolutionsSaveTimer = time.AfterFunc(resolutionSaveDelay, SaveResolutions)
+	}
+}
+
+// SaveResolutions 将尚未写入的解析结果立即写入磁盘, 在程序退出前调用
+//
+// 先写入临时文件再重命名, 避免写入过程中退出导致文件损坏
+func SaveResolutions() {
+	resolutionsMu.Lock()
+	defer resolutionsMu.Unlock()
+	resolutionsSaveTimer = nil
+	if !resolutionsDirty {
+		return
+	}
+	resolutionsDirty = false
+
+	list := make([]Resolution, 0, len(resolutions))
+	for _, r := range resolutions {
+		list = append(list, r)
+	}
+	bytes, err := json.Marshal(list)
+	if err == nil {
+		fp := filepath.Join(config.BasePath, ResolutionFileName)
+		if err = os.WriteFile(fp+".tmp", bytes, 0644); err == nil {
+			err = os.Rename(fp+".tmp", fp)
+		}
+	}
+	if err != nil {
+		logs.Warn("保存媒体路径解析缓存失败: %v", err)
+	}
+}
+
+// resolveEmbyPath 获取媒体在 emby 中的路径, 优先使用缓存
+//
+// 缓存超过刷新间隔时, 会重新请求 emby 校验路径, 路径发生变化则丢弃已缓存的 openlist 路径
+func resolveEmbyPath(itemInfo ItemInfo) (Resolution, error) {
+	cfg := itemInfo.Emby.ResolutionCache
+	if !cfg.Enable {
+		embyPath, err := getEmbyFileLocalPath(itemInfo)
+		return Resolution{EmbyPath: embyPath}, err
+	}
+	loadResolutions()
+
+	key := resolutionKey(itemInfo.Emby.Name, itemInfo.Id, itemInfo.MsInfo.OriginId)
+	resolutionsMu.RLock()
+	r, ok := resolutions[key]
+	resolutionsMu.RUnlock()
+	if ok && time.Since(r.UpdatedAt) < cfg.RefreshDuration() {
+		logs.Ctx(itemInfo.Ctx).Tip("命中媒体路径解析缓存: %s => %s", key, r.EmbyPath)
+		return r, nil
+	}
+
+	embyPath, err := getEmbyFileLocalPath(itemInfo)
+	if err != nil {
+		return Resolution{}, err
+	}
+	return recordEmbyPath(itemInfo, embyPath), nil
+}
+
+// recordEmbyPath 记录刚从 emby 查询到的媒体路径, 路径发生变化则丢弃已缓存的 openlist 路径
+func recordEmbyPath(itemInfo ItemInfo, embyPath string) Resolution {
+	if !itemInfo.Emby.ResolutionCache.Enable {
+		return Resolution{EmbyPath: embyPath}
+	}
+	loadResolutions()
+
+	resolutionsMu.RLock()
+	r, ok := resolutions[resolutionKey(itemInfo.Emby.Name, itemInfo.Id, itemInfo.MsInfo.OriginId)]
+	resolutionsMu.RUnlock()
+	if !ok || r.EmbyPath != embyPath {
+		r = Resolution{Backend: itemInfo.Emby.Name, ItemId: itemInfo.Id, MediaSourceId: itemInfo.MsInfo.OriginId, EmbyPath: embyPath}
+	}
+	r.UpdatedAt = time.Now()
+	storeResolution(r)
+	return r
+}
+
+// lookupResolution 查询已缓存的解析结果, 指定的 MediaSourceId 未命中时, 返回该 item 任意一条解析结果
+func lookupResolution(backend, itemId, mediaSourceId string) (Resolution, bool) {
+	loadResolutions()
+
+	resolutionsMu.RLock()
+	defer resolutionsMu.RUnlock()
+	if r, ok := resolutions[resolutionKey(backend, itemId, mediaSourceId)]; ok {
+		return r, true
+	}
+	for _, r := range resolutions {
+		if r.Backend == backend && r.ItemId == itemId {
+			return r, true
+		}
+	}
+	return Resolution{}, false
+}
+
+// storeResolution 缓存解析结果, 只有启用了缓存的后端才会产生带有 ItemId 的解析结果
+func storeResolution(r Resolution) {
+	resolutionsMu.Lock()
+	defer resolutionsMu.Unlock()
+	resolutions[resolutionKey(r.Backend, r.ItemId, r.MediaSourceId)] = r
+	saveResolutions()
+}
+
+// resolveOpenlistPath 记录媒体解析成功的 openlist 路径
+func (r Resolution) resolveOpenlistPath(openlistPath string, res openlist.Resource) {
+	if r.ItemId == "" {
+		return
+	}
+	if r.Size > 0 && res.Size > 0 && r.Size != res.Size {
+		logs.Warn("媒体文件大小发生变化: %s, %d => %d", openlistPath, r.Size, res.Size)
+	}
+	if r.OpenlistPath == openlistPath && (res.Size == 0 || (r.Sign == res.Sign && r.Size == res.Size)) {
+		return
+	}
+	r.OpenlistPath = openlistPath
+	if res.Size > 0 {
+		r.Sign, r.Size = res.Sign, res.Size
+	}
+	r.UpdatedAt = time.Now()
+	storeResolution(r)
+}
+
+// InvalidateResolution 移除所有 emby 后端中指定 item 的解析缓存, 返回移除的数量
+func InvalidateResolution(itemId string) int {
+	loadResolutions()
+
+	resolutionsMu.Lock()
+	defer resolutionsMu.Unlock()
+	cnt := 0
+	for key, r := range resolutions {
+		if r.ItemId == itemId {
+			delete(resolutions, key)
+			cnt++
+		}
+	}
+	if cnt > 0 {
+		saveResolutions()
+		logs.Info("已移除 item [%s] 的 %d 条媒体路径解析缓存", itemId, cnt)
+	}
+	return cnt
+}
+
+// invalidateOpenlistPath 缓存的 openlist 路径失效时调用, 保留 emby 路径, 返回更新后的结果
+func (r Resolution) invalidateOpenlistPath() Resolution {
+	if r.ItemId == "" || r.OpenlistPath == "" {
+		return r
+	}
+	logs.Warn("缓存的 openlist 路径已失效: %s", r.OpenlistPath)
+	r.OpenlistPath, r.Sign, r.Size = "", "", 0
+	storeResolution(r)
+	return r
+}
@@ -0,0 +1,280 @@
+package emby
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionFileName 播放会话的持久化文件名称
+const SessionFileName = "emby-sessions.json"
+
+// 会话状态
+const (
+	SessionPreparing = "preparing" // 已请求 PlaybackInfo, 尚未请求资源
+	SessionPlaying   = "playing"   // 已请求资源或上报了播放进度
+)
+
+// sessionSaveDelay 会话变更后延迟写入磁盘的时间
+const sessionSaveDelay = time.Second * 5
+
+// Session 设备的播放会话
+type Session struct {
+	Backend       string    `json:"backend"`                   // emby 后端名称
+	UserId        string    `json:"user_id,omitempty"`         // emby 用户 id
+	DeviceId      string    `json:"device_id,omitempty"`       // 设备 id
+	Client        string    `json:"client,omitempty"`          // 客户端名称
+	Device        string    `json:"device,omitempty"`          // 设备名称
+	ItemId        string    `json:"item_id"`                   // 正在播放的 item id
+	MediaSourceId string    `json:"media_source_id,omitempty"` // 正在播放的 MediaSourceId
+	OpenlistPath  string    `json:"openlist_path,omitempty"`   // 提供直链的 openlist 路径
+	ClientIp      string    `json:"client_ip"`                 // 客户端 ip
+	State         string    `json:"state"`                     // 会话状态
+	PositionTicks int64     `json:"position_ticks"`            // 最近一次上报的播放进度
+	StartedAt     time.Time `json:"started_at"`                // 开始播放的时间
+	UpdatedAt     time.Time `json:"updated_at"`                // 最后一次活跃的时间
+
+	apiKey string // 客户端的 api_key, 用户 id 未知时用于区分用户, 不对外输出
+}
+
+var (
+	// sessions 播放会话, key 为 emby 后端名称 + 设备标识
+	sessions     = map[string]*Session{}
+	sessionsMu   sync.Mutex
+	sessionsOnce sync.Once
+	// sessionsSaveTimer 延迟写入的定时器, 为 nil 表示没有待执行的写入
+	sessionsSaveTimer *time.Timer
+
+	// deviceIdExtractReg 匹配 Authorization 头中的 DeviceId 字段
+	deviceIdExtractReg = regexp.MustCompile(`(?i)deviceid="([^"]+)"`)
+)
+
+// loadSessions 从磁盘中加载持久化的会话, 只会执行一次
+func loadSessions() {
+	sessionsOnce.Do(func() {
+		if !config.C.Session.Persist {
+			return
+		}
+		bytes, err := os.ReadFile(filepath.Join(config.BasePath, SessionFileName))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logs.Warn("读取播放会话失败: %v", err)
+			}
+			return
+		}
+		var list []*Session
+		if err = json.Unmarshal(bytes, &list); err != nil {
+			logs.Warn("解析播放会话失败: %v", err)
+			return
+		}
+
+		sessionsMu.Lock()
+		defer sessionsMu.Unlock()
+		for _, s := range list {
+			sessions[s.Backend+"|"+s.DeviceId] = s
+		}
+		logs.Info("已加载 %d 条播放会话", len(list))
+	})
+}
+
+// saveSessions 延迟将会话写入磁盘, 合并短时间内的多次变更, 调用方需持有锁
+func saveSessions() {
+	if !config.C.Session.Persist {
+		return
+	}
+	if sessionsSaveTimer == nil {
+		sessionsSaveTimer = time.AfterFunc(sessionSaveDelay, SaveSessions)
+	}
+}
+
+// SaveSessions 将内存中的会话 (包括最新的播放进度) 立即写入磁盘, 在程序退出前调用
+//
+// 先写入临时文件再重命名, 避免写入过程中退出导致文件损坏
+func SaveSessions() {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	sessionsSaveTimer = nil
+	if !config.C.Session.Persist {
+		return
+	}
+
+	list := make([]*Session, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, s)
+	}
+	bytes, err := json.Marshal(list)
+	if err == nil {
+		fp := filepath.Join(config.BasePath, SessionFileName)
+		if err = os.WriteFile(fp+".tmp", bytes, 0644); err == nil {
+			err = os.Rename(fp+".tmp", fp)
+		}
+	}
+	if err != nil {
+		logs.Warn("保存播放会话失败: %v", err)
+	}
+}
+
+// pruneSessions 移除空闲超时的会话, 调用方需持有锁
+func pruneSessions() bool {
+	timeout := config.C.Session.IdleTimeoutDuration()
+	pruned := false
+	for key, s := range sessions {
+		if time.Since(s.UpdatedAt) >= timeout {
+			delete(sessions, key)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// getDeviceId 获取请求的设备 id, 获取不到时使用客户端 ip + 客户端名称代替
+func getDeviceId(c *gin.Context) string {
+	if id := c.Query("DeviceId"); id != "" {
+		return id
+	}
+	if id := c.Query("deviceId"); id != "" {
+		return id
+	}
+	if id := c.GetHeader("X-Emby-Device-Id"); id != "" {
+		return id
+	}
+	auth := c.GetHeader(HeaderFullAuthName) + c.GetHeader(HeaderAuthName)
+	if m := deviceIdExtractReg.FindStringSubmatch(auth); len(m) > 1 {
+		return m[1]
+	}
+	client, _ := getClientInfo(c)
+	return c.ClientIP() + "@" + client
+}
+
+// trackSession 记录设备的播放会话, 设备切换媒体时会重新开始计时
+func trackSession(c *gin.Context, itemInfo ItemInfo, state string) {
+	loadSessions()
+	deviceId := getDeviceId(c)
+	key := itemInfo.Emby.Name + "|" + deviceId
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	now := time.Now()
+	s, ok := sessions[key]
+	if ok && s.ItemId != itemInfo.Id && s.State == SessionPlaying && state == SessionPreparing {
+		// 部分客户端会预先请求其他媒体的 PlaybackInfo, 以实际请求的资源为准
+		return
+	}
+	if !ok || s.ItemId != itemInfo.Id {
+		pruneSessions()
+		s = &Session{Backend: itemInfo.Emby.Name, DeviceId: deviceId, ItemId: itemInfo.Id, StartedAt: now}
+		s.Client, s.Device = getClientInfo(c)
+		sessions[key] = s
+		defer saveSessions()
+	}
+	if userId := c.Query("UserId"); userId != "" {
+		s.UserId = userId
+	}
+	if !itemInfo.MsInfo.Empty {
+		s.MediaSourceId = itemInfo.MsInfo.OriginId
+	}
+	if s.State != SessionPlaying {
+		s.State = state
+	}
+	s.apiKey = itemInfo.ApiKey
+	s.ClientIp = c.ClientIP()
+	s.UpdatedAt = now
+}
+
+// setSessionOpenlistPath 记录会话中提供直链的 openlist 路径
+func setSessionOpenlistPath(c *gin.Context, openlistPath string) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if s, ok := sessions[embyOf(c).Name+"|"+getDeviceId(c)]; ok {
+		s.OpenlistPath = openlistPath
+	}
+}
+
+// touchSession 根据播放进度上报刷新会话
+func touchSession(c *gin.Context, itemInfo ItemInfo, positionTicks int64) {
+	trackSession(c, itemInfo, SessionPlaying)
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if s, ok := sessions[itemInfo.Emby.Name+"|"+getDeviceId(c)]; ok {
+		s.PositionTicks = positionTicks
+	}
+}
+
+// endSession 设备停止播放时移除会话
+func endSession(c *gin.Context) {
+	loadSessions()
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	key := embyOf(c).Name + "|" + getDeviceId(c)
+	if _, ok := sessions[key]; ok {
+		delete(sessions, key)
+		saveSessions()
+	}
+}
+
+// allowSession 判断当前设备是否允许开始播放
+//
+// 同一用户在同一后端正在播放的其他设备数达到配置的上限时, 不允许播放
+func allowSession(c *gin.Context, itemInfo ItemInfo) bool {
+	limit := config.C.Session.MaxStreamsPerUser
+	if limit <= 0 {
+		return true
+	}
+	loadSessions()
+	key := itemInfo.Emby.Name + "|" + getDeviceId(c)
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	pruneSessions()
+	userId, apiKey := c.Query("UserId"), itemInfo.ApiKey
+	if s, ok := sessions[key]; ok && userId == "" {
+		userId = s.UserId
+	}
+
+	// sameUser 双方用户 id 都已知时按用户 id 判断, 否则退化为比较 api_key
+	sameUser := func(s *Session) bool {
+		if userId != "" && s.UserId != "" {
+			return s.UserId == userId
+		}
+		return apiKey != "" && s.apiKey == apiKey
+	}
+
+	cnt := 0
+	for k, s := range sessions {
+		if k == key || s.Backend != itemInfo.Emby.Name || s.State != SessionPlaying {
+			continue
+		}
+		if sameUser(s) {
+			cnt++
+		}
+	}
+	return cnt < limit
+}
+
+// Sessions 获取所有活跃的播放会话, 按开始时间倒序排列
+func Sessions() []Session {
+	loadSessions()
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if pruneSessions() {
+		saveSessions()
+	}
+
+	res := make([]Session, 0, len(sessions))
+	for _, s := range sessions {
+		res = append(res, *s)
+	}
+	slices.SortFunc(res, func(a, b Session) int {
+		return b.StartedAt.Compare(a.StartedAt)
+	})
+	return res
+}
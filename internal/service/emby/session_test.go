@@ -0,0 +1,142 @@
+package emby
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionCtx 构造指定设备, 用户的请求上下文
+func sessionCtx(deviceId, userId string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	u := "/videos/1/stream?DeviceId=" + deviceId
+	if userId != "" {
+		u += "&UserId=" + userId
+	}
+	c.Request = httptest.NewRequest("GET", u, nil)
+	return c
+}
+
+func TestAllowSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.C = &config.Config{Session: &config.Session{MaxStreamsPerUser: 1}}
+	if err := config.C.Session.Init(); err != nil {
+		t.Fatal(err)
+	}
+	loadSessions()
+
+	e := &config.Emby{Name: "main"}
+	other := &config.Emby{Name: "other"}
+
+	// existing 已存在的会话
+	type existing struct {
+		emby     *config.Emby
+		deviceId string
+		userId   string
+		apiKey   string
+		state    string
+		idle     time.Duration
+	}
+
+	tests := []struct {
+		name     string
+		limit    int
+		existing []existing
+		deviceId string
+		userId   string
+		apiKey   string
+		want     bool
+	}{
+		{name: "无会话", limit: 1, deviceId: "d1", userId: "u1", want: true},
+		{name: "不限制", limit: 0, existing: []existing{{e, "d1", "u1", "k1", SessionPlaying, 0}}, deviceId: "d2", userId: "u1", want: true},
+		{name: "同一用户达到上限", limit: 1, existing: []existing{{e, "d1", "u1", "k1", SessionPlaying, 0}}, deviceId: "d2", userId: "u1", want: false},
+		{name: "同一用户未达上限", limit: 2, existing: []existing{{e, "d1", "u1", "k1", SessionPlaying, 0}}, deviceId: "d2", userId: "u1", want: true},
+		{name: "同一设备重复请求", limit: 1, existing: []existing{{e, "d1", "u1", "k1", SessionPlaying, 0}}, deviceId: "d1", userId: "u1", want: true},
+		{name: "不同用户", limit: 1, existing: []existing{{e, "d1", "u1", "k1", SessionPlaying, 0}}, deviceId: "d2", userId: "u2", apiKey: "k2", want: true},
+		{name: "不同后端", limit: 1, existing: []existing{{other, "d1", "u1", "k1", SessionPlaying, 0}}, deviceId: "d2", userId: "u1", want: true},
+		{name: "准备中的会话不计数", limit: 1, existing: []existing{{e, "d1", "u1", "k1", SessionPreparing, 0}}, deviceId: "d2", userId: "u1", want: true},
+		{name: "用户未知时按 api_key 判断", limit: 1, existing: []existing{{e, "d1", "", "k1", SessionPlaying, 0}}, deviceId: "d2", apiKey: "k1", want: false},
+		{name: "用户未知且 api_key 不同", limit: 1, existing: []existing{{e, "d1", "", "k1", SessionPlaying, 0}}, deviceId: "d2", apiKey: "k2", want: true},
+		{name: "一方用户未知时按 api_key 判断", limit: 1, existing: []existing{{e, "d1", "", "k1", SessionPlaying, 0}}, deviceId: "d2", userId: "u1", apiKey: "k1", want: false},
+		{name: "空闲超时的会话被清理", limit: 1, existing: []existing{{e, "d1", "u1", "k1", SessionPlaying, time.Hour}}, deviceId: "d2", userId: "u1", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions = map[string]*Session{}
+			config.C.Session.MaxStreamsPerUser = tt.limit
+			for _, ex := range tt.existing {
+				trackSession(sessionCtx(ex.deviceId, ex.userId), ItemInfo{Id: "1", ApiKey: ex.apiKey, Emby: ex.emby}, ex.state)
+				sessions[ex.emby.Name+"|"+ex.deviceId].UpdatedAt = time.Now().Add(-ex.idle)
+			}
+
+			got := allowSession(sessionCtx(tt.deviceId, tt.userId), ItemInfo{Id: "2", ApiKey: tt.apiKey, Emby: e})
+			if got != tt.want {
+				t.Errorf("allowSession() = %v, want %v", got, tt.want)
+			}
+			for _, ex := range tt.existing {
+				_, ok := sessions[ex.emby.Name+"|"+ex.deviceId]
+				if pruned := ex.idle >= config.C.Session.IdleTimeoutDuration(); tt.limit > 0 && ok == pruned {
+					t.Errorf("会话 [%s] 清理状态异常, 存在: %v", ex.deviceId, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestSaveSessionsDebounced(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.BasePath = t.TempDir()
+	config.C = &config.Config{Session: &config.Session{Persist: true}}
+	if err := config.C.Session.Init(); err != nil {
+		t.Fatal(err)
+	}
+	loadSessions()
+	sessions = map[string]*Session{}
+	t.Cleanup(func() {
+		sessionsMu.Lock()
+		defer sessionsMu.Unlock()
+		if sessionsSaveTimer != nil {
+			sessionsSaveTimer.Stop()
+			sessionsSaveTimer = nil
+		}
+	})
+
+	e := &config.Emby{Name: "main"}
+	for _, deviceId := range []string{"d1", "d2", "d3"} {
+		trackSession(sessionCtx(deviceId, "u1"), ItemInfo{Id: "1", Emby: e}, SessionPlaying)
+	}
+
+	fp := filepath.Join(config.BasePath, SessionFileName)
+	if _, err := os.Stat(fp); !os.IsNotExist(err) {
+		t.Fatalf("会话变更后不应立即写入磁盘, err: %v", err)
+	}
+	sessionsMu.Lock()
+	pending := sessionsSaveTimer != nil
+	sessionsMu.Unlock()
+	if !pending {
+		t.Fatal("会话变更后应等待延迟写入")
+	}
+
+	SaveSessions()
+	bytes, err := os.ReadFile(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []Session
+	if err = json.Unmarshal(bytes, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Errorf("写入的会话数 = %d, want 3", len(list))
+	}
+	if _, err := os.Stat(fp + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("临时文件未被清理, err: %v", err)
+	}
+}
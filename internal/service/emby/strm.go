@@ -0,0 +1,81 @@
+package emby
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+)
+
+// maxFinalLinkCount strm 最终链接缓存的最大数量
+const maxFinalLinkCount = 1024
+
+// finalLinkEntry strm 最终链接缓存项
+type finalLinkEntry struct {
+	link      string
+	expiresAt time.Time
+}
+
+// finalLinks strm 内部重定向得到的最终链接, key 为原始链接 + UA 类型
+var (
+	finalLinks   = map[string]finalLinkEntry{}
+	finalLinksMu sync.RWMutex
+)
+
+// finalLinkKey 生成最终链接缓存的 key
+func finalLinkKey(originLink string, header http.Header) string {
+	return originLink + "|" + openlist.UAClass(header)
+}
+
+// loadFinalLink 获取未过期的最终链接缓存
+func loadFinalLink(originLink string, header http.Header) (string, bool) {
+	finalLinksMu.RLock()
+	defer finalLinksMu.RUnlock()
+	fle, ok := finalLinks[finalLinkKey(originLink, header)]
+	if !ok || !fle.expiresAt.After(time.Now()) {
+		return "", false
+	}
+	return fle.link, true
+}
+
+// storeFinalLink 缓存最终链接, 缓存时间为 ttl, 最终链接自身带有过期时间时, 提前 1 分钟失效
+func storeFinalLink(ttl time.Duration, originLink, finalLink string, header http.Header) {
+	if ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	if exp, ok := openlist.LinkExpiry(finalLink); ok && exp.Add(-time.Minute).Before(expiresAt) {
+		expiresAt = exp.Add(-time.Minute)
+	}
+	if !expiresAt.After(now) {
+		return
+	}
+
+	finalLinksMu.Lock()
+	defer finalLinksMu.Unlock()
+	key := finalLinkKey(originLink, header)
+	if _, ok := finalLinks[key]; !ok && len(finalLinks) >= maxFinalLinkCount {
+		evictFinalLinks(now)
+	}
+	finalLinks[key] = finalLinkEntry{link: finalLink, expiresAt: expiresAt}
+}
+
+// evictFinalLinks 移除过期的最终链接缓存, 仍然达到数量上限时移除最早过期的一项, 调用方需持有写锁
+func evictFinalLinks(now time.Time) {
+	var earliestKey string
+	var earliestAt time.Time
+	for k, fle := range finalLinks {
+		if !fle.expiresAt.After(now) {
+			delete(finalLinks, k)
+			continue
+		}
+		if earliestKey == "" || fle.expiresAt.Before(earliestAt) {
+			earliestKey, earliestAt = k, fle.expiresAt
+		}
+	}
+	if len(finalLinks) >= maxFinalLinkCount {
+		delete(finalLinks, earliestKey)
+	}
+}
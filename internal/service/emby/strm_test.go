@@ -0,0 +1,91 @@
+package emby
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// resetFinalLinks 清空最终链接缓存
+func resetFinalLinks(t *testing.T) {
+	finalLinksMu.Lock()
+	finalLinks = map[string]finalLinkEntry{}
+	finalLinksMu.Unlock()
+	t.Cleanup(func() {
+		finalLinksMu.Lock()
+		finalLinks = map[string]finalLinkEntry{}
+		finalLinksMu.Unlock()
+	})
+}
+
+func TestStoreFinalLinkTTL(t *testing.T) {
+	header := http.Header{"User-Agent": {"Infuse"}}
+	exp := func(d time.Duration) string {
+		return fmt.Sprintf("http://cdn/a.mkv?Expires=%d", time.Now().Add(d).Unix())
+	}
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		finalLink string
+		wantOk    bool
+		wantMax   time.Duration
+	}{
+		{name: "无过期时间", ttl: 10 * time.Minute, finalLink: "http://cdn/a.mkv", wantOk: true, wantMax: 10 * time.Minute},
+		{name: "过期时间晚于 ttl", ttl: 10 * time.Minute, finalLink: exp(time.Hour), wantOk: true, wantMax: 10 * time.Minute},
+		{name: "过期时间早于 ttl 时提前 1 分钟失效", ttl: 10 * time.Minute, finalLink: exp(5 * time.Minute), wantOk: true, wantMax: 4 * time.Minute},
+		{name: "即将过期不缓存", ttl: 10 * time.Minute, finalLink: exp(30 * time.Second), wantOk: false},
+		{name: "ttl 为 0 不缓存", ttl: 0, finalLink: "http://cdn/a.mkv", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetFinalLinks(t)
+			storeFinalLink(tt.ttl, "http://origin/a.mkv", tt.finalLink, header)
+
+			link, ok := loadFinalLink("http://origin/a.mkv", header)
+			if ok != tt.wantOk {
+				t.Fatalf("loadFinalLink() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if link != tt.finalLink {
+				t.Errorf("loadFinalLink() = %s, want %s", link, tt.finalLink)
+			}
+			fle := finalLinks[finalLinkKey("http://origin/a.mkv", header)]
+			if d := time.Until(fle.expiresAt); d > tt.wantMax || d < tt.wantMax-5*time.Second {
+				t.Errorf("缓存时间 = %v, want %v", d, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestStoreFinalLinkCap(t *testing.T) {
+	resetFinalLinks(t)
+	now := time.Now()
+	finalLinksMu.Lock()
+	for i := range maxFinalLinkCount {
+		finalLinks[fmt.Sprintf("old-%d", i)] = finalLinkEntry{link: "x", expiresAt: now.Add(time.Minute + time.Duration(i)*time.Millisecond)}
+	}
+	finalLinksMu.Unlock()
+
+	for i := range 3 {
+		storeFinalLink(10*time.Minute, fmt.Sprintf("http://origin/%d.mkv", i), "http://cdn/a.mkv", nil)
+	}
+	if len(finalLinks) != maxFinalLinkCount {
+		t.Errorf("缓存数量 = %d, want %d", len(finalLinks), maxFinalLinkCount)
+	}
+	for i := range 3 {
+		if _, ok := loadFinalLink(fmt.Sprintf("http://origin/%d.mkv", i), nil); !ok {
+			t.Errorf("缺少新缓存的链接: %d", i)
+		}
+	}
+
+	// 淘汰最早过期的缓存
+	for i := range maxFinalLinkCount {
+		_, ok := finalLinks[fmt.Sprintf("old-%d", i)]
+		if want := i >= 3; ok != want {
+			t.Errorf("old-%d 是否保留 = %v, want %v", i, ok, want)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package emby
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 )
 
 // MsInfo MediaSourceId 解析信息
@@ -37,13 +40,16 @@ const (
 
 // ItemInfo emby 资源 item 解析信息
 type ItemInfo struct {
-	Id              string     // item id
-	MsInfo          MsInfo     // MediaSourceId 解析信息
-	ApiKey          string     // emby 接口密钥
-	ApiKeyType      ApiKeyType // emby 接口密钥类型
-	ApiKeyName      string     // emby 接口密钥名称
-	PlaybackInfoUri string     // item 信息查询接口 uri, 通过源服务器查询
+	Id              string       // item id
+	MsInfo          MsInfo       // MediaSourceId 解析信息
+	ApiKey          string       // emby 接口密钥
+	ApiKeyType      ApiKeyType   // emby 接口密钥类型
+	ApiKeyName      string       // emby 接口密钥名称
+	PlaybackInfoUri string       // item 信息查询接口 uri, 通过源服务器查询
+	Emby            *config.Emby // 请求选中的 emby 后端配置
 	RouteType
+
+	Ctx context.Context // 发起请求的上下文, 用于日志以及向上游传递请求 id
 }
 
 // String 序列化输出
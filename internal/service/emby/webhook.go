@@ -0,0 +1,179 @@
+package emby
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/notify"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jellyfinWebhookEvents Jellyfin webhook 插件的 NotificationType 与 emby 事件类型的对应关系
+var jellyfinWebhookEvents = map[string]string{
+	"PlaybackStart":         notify.EventPlaybackStart,
+	"PlaybackStop":          notify.EventPlaybackStop,
+	"ItemAdded":             notify.EventLibraryNew,
+	"ItemDeleted":           notify.EventLibraryDeleted,
+	"AuthenticationSuccess": notify.EventUserAuthenticated,
+}
+
+// webhookPayload emby webhook 请求体, 同时兼容 Jellyfin webhook 插件的默认模板字段
+type webhookPayload struct {
+	Title   string
+	Event   string
+	User    struct{ Name string }
+	Server  struct{ Name string }
+	Item    struct{ Id, Name, SeriesName, Type, Path string }
+	Session struct{ Client, DeviceName string }
+
+	// Jellyfin
+	NotificationType     string
+	NotificationUsername string
+	ServerName           string
+	ItemId               string
+	Name                 string
+	SeriesName           string
+	ClientName           string
+	DeviceName           string
+}
+
+// toEvent 转换为通知事件
+func (p webhookPayload) toEvent() notify.Event {
+	ev := notify.Event{
+		Type:   strings.ToLower(p.Event),
+		Title:  p.Title,
+		Source: "emby",
+		Server: p.Server.Name,
+		User:   p.User.Name,
+		Item:   p.Item.Name,
+		ItemId: p.Item.Id,
+		Client: p.Session.Client,
+		Device: p.Session.DeviceName,
+	}
+	if p.Item.SeriesName != "" {
+		ev.Item = p.Item.SeriesName + " " + p.Item.Name
+	}
+
+	if p.NotificationType != "" {
+		ev.Type = p.NotificationType
+		if t, ok := jellyfinWebhookEvents[p.NotificationType]; ok {
+			ev.Type = t
+		}
+		ev.Server, ev.User, ev.ItemId = p.ServerName, p.NotificationUsername, p.ItemId
+		ev.Client, ev.Device, ev.Item = p.ClientName, p.DeviceName, p.Name
+		if p.SeriesName != "" {
+			ev.Item = p.SeriesName + " " + p.Name
+		}
+	}
+	return ev
+}
+
+// HandleWebhook 接收 emby webhook 事件, 推送到配置的通知渠道
+//
+// 媒体被删除时, 会同时移除该媒体的路径解析缓存; 未配置 notify.webhook-token 时拒绝处理
+func HandleWebhook(c *gin.Context) {
+	c.Header(cache.HeaderKeyExpired, "-1")
+	cfg := config.C.Notify
+	if c.Request.Method != http.MethodPost {
+		c.String(http.StatusMethodNotAllowed, "请使用 POST 请求")
+		return
+	}
+	if cfg.WebhookToken == "" {
+		c.String(http.StatusForbidden, "未配置 notify.webhook-token, 不接收 webhook")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(cfg.WebhookToken)) != 1 {
+		logs.Ctx(c).Warn("webhook 鉴权失败, ip: %s", c.ClientIP())
+		c.String(http.StatusUnauthorized, "webhook 鉴权失败")
+		return
+	}
+
+	// emby 旧版本以表单的 data 字段传递事件
+	var data []byte
+	if strings.HasPrefix(c.ContentType(), "multipart/") || c.ContentType() == "application/x-www-form-urlencoded" {
+		data = []byte(c.PostForm("data"))
+	} else {
+		var err error
+		if data, err = io.ReadAll(c.Request.Body); err != nil {
+			c.String(http.StatusBadRequest, "读取请求体失败: %v", err)
+			return
+		}
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		c.String(http.StatusBadRequest, "解析 webhook 事件失败: %v", err)
+		return
+	}
+	ev := payload.toEvent()
+	if ev.Type == "" {
+		c.String(http.StatusBadRequest, "缺少事件类型")
+		return
+	}
+	ev.Backend = embyOf(c).Name
+	logs.Ctx(c).Info("收到 webhook 事件: %s, 后端: %s, 媒体: %s", ev.Type, ev.Backend, ev.Item)
+
+	if ev.Type == notify.EventLibraryDeleted && ev.ItemId != "" {
+		InvalidateResolution(ev.ItemId)
+	}
+
+	notify.Publish(ev)
+	c.Status(http.StatusNoContent)
+}
+
+var (
+	// clientExtractReg 匹配 Authorization 头中的 Client 字段
+	clientExtractReg = regexp.MustCompile(`(?i)client="([^"]+)"`)
+	// deviceExtractReg 匹配 Authorization 头中的 Device 字段
+	deviceExtractReg = regexp.MustCompile(`(?i)device="([^"]+)"`)
+)
+
+// getClientInfo 获取请求的客户端名称以及设备名称
+func getClientInfo(c *gin.Context) (client, device string) {
+	client, device = c.GetHeader("X-Emby-Client"), c.GetHeader("X-Emby-Device-Name")
+	auth := c.GetHeader(HeaderFullAuthName) + c.GetHeader(HeaderAuthName)
+	if m := clientExtractReg.FindStringSubmatch(auth); client == "" && len(m) > 1 {
+		client = m[1]
+	}
+	if m := deviceExtractReg.FindStringSubmatch(auth); device == "" && len(m) > 1 {
+		device = m[1]
+	}
+	return
+}
+
+// publishProxyPlaybackStop 推送代理程序观察到的停止播放事件, 附带提供服务的后端以及 openlist 路径
+func publishProxyPlaybackStop(c *gin.Context, itemId, mediaSourceId string, positionTicks int64) {
+	if !config.C.Notify.Enable {
+		return
+	}
+	e := embyOf(c)
+	ev := notify.Event{
+		Type:    notify.EventProxyPlaybackStop,
+		Source:  "proxy",
+		Backend: e.Name,
+		ItemId:  itemId,
+		Extra: map[string]string{
+			"emby_host": e.Host,
+			"position":  strconv.FormatInt(positionTicks/10_000_000/60, 10) + " 分钟",
+		},
+	}
+	ev.Client, ev.Device = getClientInfo(c)
+
+	msInfo, _ := resolveMediaSourceId(mediaSourceId)
+	if r, ok := lookupResolution(e.Name, itemId, msInfo.OriginId); ok {
+		ev.Item = r.EmbyPath
+		if r.OpenlistPath != "" {
+			ev.Extra["openlist_path"] = r.OpenlistPath
+		}
+	}
+	notify.Publish(ev)
+}
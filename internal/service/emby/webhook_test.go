@@ -0,0 +1,139 @@
+package emby
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+// embyWebhookBody emby webhook 插件推送的事件
+const embyWebhookBody = `{"Title":"ambitious 开始播放","Event":"playback.start","User":{"Name":"ambitious"},"Server":{"Name":"home"},"Item":{"Id":"42","Name":"第 1 集","SeriesName":"漫长的季节"},"Session":{"Client":"Infuse","DeviceName":"iPhone"}}`
+
+// jellyfinWebhookBody jellyfin webhook 插件默认模板推送的事件
+const jellyfinWebhookBody = `{"NotificationType":"ItemDeleted","NotificationUsername":"ambitious","ServerName":"jf","ItemId":"43","Name":"第 2 集","SeriesName":"漫长的季节","ClientName":"Findroid","DeviceName":"Pixel"}`
+
+func TestWebhookPayloadToEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want notify.Event
+	}{
+		{
+			name: "emby",
+			body: embyWebhookBody,
+			want: notify.Event{Type: notify.EventPlaybackStart, Title: "ambitious 开始播放", Source: "emby", Server: "home", User: "ambitious",
+				Item: "漫长的季节 第 1 集", ItemId: "42", Client: "Infuse", Device: "iPhone"},
+		},
+		{
+			name: "jellyfin",
+			body: jellyfinWebhookBody,
+			want: notify.Event{Type: notify.EventLibraryDeleted, Source: "emby", Server: "jf", User: "ambitious",
+				Item: "漫长的季节 第 2 集", ItemId: "43", Client: "Findroid", Device: "Pixel"},
+		},
+		{
+			name: "emby 事件类型转小写",
+			body: `{"Event":"Library.New","Item":{"Id":"1","Name":"电影"}}`,
+			want: notify.Event{Type: notify.EventLibraryNew, Source: "emby", Item: "电影", ItemId: "1"},
+		},
+		{
+			name: "jellyfin 未知事件保留原始类型",
+			body: `{"NotificationType":"TaskCompleted","ServerName":"jf"}`,
+			want: notify.Event{Type: "TaskCompleted", Source: "emby", Server: "jf"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p webhookPayload
+			if err := json.Unmarshal([]byte(tt.body), &p); err != nil {
+				t.Fatal(err)
+			}
+			got := p.toEvent()
+			gotJson, _ := json.Marshal(got)
+			wantJson, _ := json.Marshal(tt.want)
+			if string(gotJson) != string(wantJson) {
+				t.Errorf("toEvent() = %s, want %s", gotJson, wantJson)
+			}
+		})
+	}
+}
+
+// setupWebhook 初始化通知配置, 通知渠道为模拟的 webhook 服务器, 返回接收到的事件
+func setupWebhook(t *testing.T) <-chan notify.Event {
+	events := make(chan notify.Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev notify.Event
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &ev); err != nil {
+			t.Errorf("解析通知失败: %v", err)
+		}
+		events <- ev
+	}))
+	t.Cleanup(srv.Close)
+
+	gin.SetMode(gin.TestMode)
+	n := &config.Notify{Enable: true, WebhookToken: "hook", Sinks: []*config.NotifySink{{Name: "test", Type: config.NotifySinkWebhook, Url: srv.URL}}}
+	if err := n.Init(); err != nil {
+		t.Fatal(err)
+	}
+	e := &config.Emby{Host: "http://emby"}
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	config.C = &config.Config{Emby: e, Notify: n}
+	return events
+}
+
+func TestHandleWebhook(t *testing.T) {
+	form := url.Values{"data": {jellyfinWebhookBody}}.Encode()
+	tests := []struct {
+		name        string
+		token       string
+		contentType string
+		body        string
+		wantCode    int
+		wantType    string
+		wantItemId  string
+	}{
+		{name: "emby json", token: "hook", contentType: "application/json", body: embyWebhookBody, wantCode: http.StatusNoContent, wantType: notify.EventPlaybackStart, wantItemId: "42"},
+		{name: "表单 data 字段", token: "hook", contentType: "application/x-www-form-urlencoded", body: form, wantCode: http.StatusNoContent, wantType: notify.EventLibraryDeleted, wantItemId: "43"},
+		{name: "密钥错误", token: "wrong", contentType: "application/json", body: embyWebhookBody, wantCode: http.StatusUnauthorized},
+		{name: "请求体错误", token: "hook", contentType: "application/json", body: "not json", wantCode: http.StatusBadRequest},
+		{name: "缺少事件类型", token: "hook", contentType: "application/json", body: `{"Title":"x"}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := setupWebhook(t)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/ge2o/webhook?token="+tt.token, strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", tt.contentType)
+
+			HandleWebhook(c)
+			if code := c.Writer.Status(); code != tt.wantCode {
+				t.Fatalf("响应码 = %d, want %d, body: %s", code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantType == "" {
+				return
+			}
+
+			select {
+			case ev := <-events:
+				if ev.Type != tt.wantType || ev.ItemId != tt.wantItemId || ev.Backend != config.C.Emby.Name {
+					t.Errorf("推送的事件 = %+v", ev)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("未推送通知")
+			}
+		})
+	}
+}
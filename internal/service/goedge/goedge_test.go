@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/mappings"
 )
 
 func TestGenerateAuthSign(t *testing.T) {
@@ -42,10 +43,10 @@ func TestBuildURL(t *testing.T) {
 	}
 
 	// 初始化路径映射
-	config.C.GoEdge.PathMapping = []string{
+	config.C.GoEdge.PathMapping = mappings.Strings(
 		"/movie:/images",
 		"/series:/videos",
-	}
+	)
 	config.C.GoEdge.Init()
 
 	tests := []struct {
@@ -89,7 +90,7 @@ func TestMapPath(t *testing.T) {
 	config.C = &config.Config{
 		GoEdge: &config.GoEdge{
 			Enable:      true,
-			PathMapping: []string{"/movie:/images", "/series:/videos"},
+			PathMapping: mappings.Strings("/movie:/images", "/series:/videos"),
 		},
 	}
 	config.C.GoEdge.Init()
@@ -234,7 +234,7 @@ func (i *Info) UpdateContent() error {
 	// 解析地址
 	newInfo, err := NewByRemote(res.Data.Url, nil)
 	if err != nil {
-		return fmt.Errorf("解析远程 m3u8 失败, url: %s, err: %v", res.Data, err)
+		return fmt.Errorf("解析远程 m3u8 失败, url: %s, err: %v", res.Data.Url, err)
 	}
 
 	// 拷贝最新数据
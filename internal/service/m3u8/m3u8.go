@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
 )
@@ -21,6 +22,9 @@ const (
 
 func init() {
 	go loopMaintainPlaylist()
+	emby.PrefetchPlaylist = func(openlistPath, templateId string) {
+		PushPlaylistAsync(Info{OpenlistPath: openlistPath, TemplateId: templateId})
+	}
 }
 
 // GetPlaylist 获取 m3u 播放列表, 返回 m3u 文本
@@ -188,6 +192,7 @@ func loopMaintainPlaylist() {
 				break
 			}
 		}
+		playlists.Set(float64(len(infoArr)))
 	}
 
 	// updateAll 更新内存中的 info 信息
@@ -224,6 +229,7 @@ func loopMaintainPlaylist() {
 			}
 		}
 
+		activePlaylists.Set(float64(active))
 		if len(cpArr) > 0 {
 			logs.Progress("当前正在维护的 playlist 个数: %d, 活跃个数: %d", tot, active)
 		}
@@ -254,6 +260,7 @@ func loopMaintainPlaylist() {
 		if !exist {
 			infoMap[key] = info
 			infoArr = append(infoArr, info)
+			playlists.Set(float64(len(infoArr)))
 		}
 
 		if len(infoArr) <= MaxPlaylistNum {
@@ -0,0 +1,11 @@
+package m3u8
+
+import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+
+var (
+	// playlists 内存中正在维护的播放列表个数
+	playlists = metrics.NewGauge("ge2o_m3u8_playlists", "内存中正在维护的 m3u8 播放列表个数")
+
+	// activePlaylists 最近一次维护时仍在被读取的播放列表个数
+	activePlaylists = metrics.NewGauge("ge2o_m3u8_active_playlists", "最近一次维护时仍在被客户端读取的 m3u8 播放列表个数")
+)
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// 事件类型
+const (
+	EventPlaybackStart     = "playback.start"      // 开始播放
+	EventPlaybackStop      = "playback.stop"       // 停止播放
+	EventLibraryNew        = "library.new"         // 媒体库新增媒体
+	EventLibraryDeleted    = "library.deleted"     // 媒体库删除媒体
+	EventUserAuthenticated = "user.authenticated"  // 用户登录
+	EventProxyPlaybackStop = "proxy.playback.stop" // 代理程序观察到的停止播放, 附带提供服务的后端信息
+)
+
+// Event 通知事件, 同时作为通知模板的渲染数据
+type Event struct {
+	Type    string            `json:"type"`            // 事件类型
+	Title   string            `json:"title,omitempty"` // 事件标题, 来自 emby webhook
+	Source  string            `json:"source"`          // 事件来源, emby: 源服务器 webhook, proxy: 代理程序
+	Backend string            `json:"backend"`         // emby 后端名称
+	Server  string            `json:"server,omitempty"`
+	User    string            `json:"user,omitempty"`
+	Item    string            `json:"item,omitempty"`    // 媒体名称
+	ItemId  string            `json:"item_id,omitempty"` // 媒体 item id
+	Client  string            `json:"client,omitempty"`
+	Device  string            `json:"device,omitempty"`
+	Extra   map[string]string `json:"extra,omitempty"` // 附加信息, 如播放进度, openlist 路径
+	Time    time.Time         `json:"time"`
+}
+
+// Publish 将事件异步推送到所有订阅了该事件的通知渠道
+func Publish(ev Event) {
+	cfg := config.C.Notify
+	if !cfg.Enable || len(cfg.Sinks) == 0 {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	for _, sink := range cfg.Sinks {
+		if !sink.Subscribed(ev.Type) {
+			continue
+		}
+		go func(sink *config.NotifySink) {
+			if err := Send(sink, ev); err != nil {
+				logs.Warn("推送通知到渠道 [%s] 失败: %v", sink.Name, err)
+				return
+			}
+			logs.Success("推送通知到渠道 [%s] 成功, 事件: %s", sink.Name, ev.Type)
+		}(sink)
+	}
+}
@@ -0,0 +1,114 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/notify"
+)
+
+type captured struct {
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+func standIn(t *testing.T) (*httptest.Server, *captured) {
+	t.Helper()
+	got := new(captured)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*got = captured{path: r.URL.Path, query: r.URL.RawQuery, header: r.Header.Clone(), body: string(body)}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+func TestSend(t *testing.T) {
+	ev := notify.Event{
+		Type:    notify.EventPlaybackStart,
+		Title:   "开始播放",
+		Backend: "default",
+		User:    "ambitious",
+		Item:    "漫长的季节 S01E01",
+	}
+
+	tests := []struct {
+		name  string
+		sink  config.NotifySink
+		check func(t *testing.T, got *captured)
+	}{
+		{
+			name: "webhook",
+			sink: config.NotifySink{Type: config.NotifySinkWebhook, Url: "/hook"},
+			check: func(t *testing.T, got *captured) {
+				var body map[string]any
+				if err := json.Unmarshal([]byte(got.body), &body); err != nil {
+					t.Fatal(err)
+				}
+				if got.path != "/hook" || body["backend"] != "default" || body["notify_title"] != "开始播放" {
+					t.Errorf("unexpected request: %s %s", got.path, got.body)
+				}
+			},
+		},
+		{
+			name: "telegram",
+			sink: config.NotifySink{Type: config.NotifySinkTelegram, Token: "123:abc", ChatId: "42"},
+			check: func(t *testing.T, got *captured) {
+				if got.path != "/bot123:abc/sendMessage" || !strings.Contains(got.body, `"chat_id":"42"`) {
+					t.Errorf("unexpected request: %s %s", got.path, got.body)
+				}
+			},
+		},
+		{
+			name: "ntfy",
+			sink: config.NotifySink{Type: config.NotifySinkNtfy, Url: "/topic", Token: "tk", Template: "{{.User}} 正在播放 {{.Item}}"},
+			check: func(t *testing.T, got *captured) {
+				if got.body != "ambitious 正在播放 漫长的季节 S01E01" || got.header.Get("Authorization") != "Bearer tk" {
+					t.Errorf("unexpected request: %v %s", got.header, got.body)
+				}
+			},
+		},
+		{
+			name: "gotify",
+			sink: config.NotifySink{Type: config.NotifySinkGotify, Token: "app"},
+			check: func(t *testing.T, got *captured) {
+				if got.path != "/message" || got.query != "token=app" || !strings.Contains(got.body, "后端: default") {
+					t.Errorf("unexpected request: %s?%s %s", got.path, got.query, got.body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, got := standIn(t)
+			sink := tt.sink
+			sink.Url = srv.URL + sink.Url
+			if err := sink.Init(); err != nil {
+				t.Fatal(err)
+			}
+			if err := notify.Send(&sink, ev); err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, got)
+		})
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	sink := config.NotifySink{Type: config.NotifySinkBark, Url: "https://api.day.app/key", Events: []string{"Playback.Start"}}
+	if err := sink.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if !sink.Subscribed(notify.EventPlaybackStart) || sink.Subscribed(notify.EventLibraryNew) {
+		t.Errorf("unexpected subscription result")
+	}
+}
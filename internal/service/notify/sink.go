@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+)
+
+// sendTimeout 单次推送的超时时间
+const sendTimeout = time.Second * 10
+
+// Send 使用渠道模板渲染事件, 并同步推送到指定渠道
+func Send(sink *config.NotifySink, ev Event) error {
+	title, body, err := sink.Render(ev)
+	if err != nil {
+		return err
+	}
+
+	header := make(http.Header)
+	var (
+		u       = sink.Url
+		payload []byte
+	)
+	switch sink.Type {
+	case config.NotifySinkWebhook:
+		header.Set("Content-Type", "application/json")
+		payload, err = json.Marshal(struct {
+			Event
+			NotifyTitle   string `json:"notify_title"`
+			NotifyMessage string `json:"notify_message"`
+		}{ev, title, body})
+	case config.NotifySinkTelegram:
+		u = fmt.Sprintf("%s/bot%s/sendMessage", sink.Url, sink.Token)
+		header.Set("Content-Type", "application/json")
+		payload, err = json.Marshal(map[string]any{
+			"chat_id": sink.ChatId,
+			"text":    strings.TrimSpace(title + "\n\n" + body),
+		})
+	case config.NotifySinkBark:
+		header.Set("Content-Type", "application/json")
+		payload, err = json.Marshal(map[string]any{"title": title, "body": body, "group": "go-emby2openlist"})
+	case config.NotifySinkNtfy:
+		// ntfy 的请求头只支持 ascii, 标题使用 RFC 2047 编码
+		header.Set("Title", "=?UTF-8?B?"+base64.StdEncoding.EncodeToString([]byte(title))+"?=")
+		if sink.Token != "" {
+			header.Set("Authorization", "Bearer "+sink.Token)
+		}
+		payload = []byte(body)
+	case config.NotifySinkGotify:
+		u = sink.Url + "/message?token=" + url.QueryEscape(sink.Token)
+		header.Set("Content-Type", "application/json")
+		payload, err = json.Marshal(map[string]any{"title": title, "message": body, "priority": 5})
+	default:
+		return fmt.Errorf("不支持的渠道类型: %s", sink.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("序列化推送内容失败: %v", err)
+	}
+
+	resp, err := https.Post(u).Header(header).Body(io.NopCloser(bytes.NewReader(payload))).Timeout(sendTimeout).Do()
+	if err != nil {
+		// 推送地址中可能包含密钥 (如 Telegram 的 /bot<token>/), 只保留底层异常
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("推送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if !https.IsSuccessCode(resp.StatusCode) {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("推送请求失败, 响应码: %s, 响应: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
@@ -2,11 +2,15 @@ package openlist
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/model"
@@ -17,7 +21,87 @@ import (
 )
 
 // FetchResource 请求 openlist 资源 url 直链
+//
+// 启用直链缓存时, 优先返回缓存中的直链;
+// 启用熔断时, 请求被熔断的情况下也会尝试返回缓存中未过期的直链
 func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
+	linkCfg, breakerCfg := config.C.Cache.Link, config.C.Openlist.CircuitBreaker
+	if !linkCfg.Enable && !breakerCfg.Enable {
+		return fetchResource(fi)
+	}
+
+	key := linkCacheKey(fi)
+	if linkCfg.Enable {
+		if link, expiresAt, ok := loadLink(key); ok {
+			logs.Tip("命中直链缓存: %s, 过期时间: %s", key, expiresAt.Format(time.DateTime))
+			return model.HttpRes[Resource]{Code: http.StatusOK, Data: link}
+		}
+	}
+
+	res := fetchResource(fi)
+	if res.Code == http.StatusOK {
+		storeLink(key, res.Data)
+		return res
+	}
+	if res.Code == http.StatusServiceUnavailable {
+		if link, _, ok := loadLink(key); ok {
+			logs.Warn("openlist 请求已熔断, 使用缓存的直链: %s", fi.Path)
+			return model.HttpRes[Resource]{Code: http.StatusOK, Data: link}
+		}
+	}
+	return res
+}
+
+// fetchRawResource 请求原画资源, 根据路径配置的直链获取方式生成直链
+//
+// fs/link 返回的地址需要携带请求头访问时, 客户端重定向后无法携带, 回退为 /d/ 签名地址
+func fetchRawResource(fi FetchInfo) model.HttpRes[Resource] {
+	mode := config.C.Openlist.LinkModeOf(fi.Path)
+	res := FetchFsGet(fi.Path, fi.Header)
+	if res.Code != http.StatusOK {
+		return model.HttpRes[Resource]{Code: res.Code, Msg: res.Msg}
+	}
+
+	link := res.Data.RawUrl
+	switch mode {
+	case config.LinkModeFsLink:
+		// 与 fs/get 请求同一个实例, 保证文件信息与直链一致
+		linkRes := fetchFsLink(fi.Path, fi.Header, res.Data.Host)
+		if linkRes.Code != http.StatusOK {
+			return model.HttpRes[Resource]{Code: linkRes.Code, Msg: linkRes.Msg}
+		}
+		link = linkRes.Data.Url
+		if len(linkRes.Data.Header) > 0 {
+			logs.Warn("fs/link 地址需要携带请求头访问, 回退为 /d/ 地址: %s", fi.Path)
+			link = SignedUrl(res.Data.Host, fi.Path, res.Data.Sign, config.LinkModeD)
+		}
+	case config.LinkModeD, config.LinkModeP:
+		// 签名只在生成签名的实例上有效
+		link = SignedUrl(res.Data.Host, fi.Path, res.Data.Sign, mode)
+	}
+	return model.HttpRes[Resource]{Code: http.StatusOK, Data: Resource{Url: link, Sign: res.Data.Sign, Size: res.Data.Size}}
+}
+
+// SignedUrl 生成 openlist 路径 path 的 /d/ 或 /p/ 签名访问地址
+//
+// host 为生成签名的 openlist 实例地址, 为空时使用负责该路径的首选实例
+func SignedUrl(host, path, sign string, mode config.LinkMode) string {
+	if host == "" {
+		host = HostOf(path)
+	}
+	segs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	link := fmt.Sprintf("%s/%s/%s", host, mode, strings.Join(segs, "/"))
+	if sign != "" {
+		link += "?sign=" + sign
+	}
+	return link
+}
+
+// fetchResource 请求 openlist 资源 url 直链
+func fetchResource(fi FetchInfo) model.HttpRes[Resource] {
 	if strs.AnyEmpty(fi.Path) {
 		return model.HttpRes[Resource]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
 	}
@@ -25,11 +109,7 @@ func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
 
 	if !fi.UseTranscode {
 		// 请求原画资源
-		res := FetchFsGet(fi.Path, fi.Header)
-		if res.Code == http.StatusOK {
-			return model.HttpRes[Resource]{Code: http.StatusOK, Data: Resource{Url: res.Data.RawUrl}}
-		}
-		return model.HttpRes[Resource]{Code: res.Code, Msg: res.Msg}
+		return fetchRawResource(fi)
 	}
 
 	// 转码资源请求失败后, 递归请求原画资源
@@ -39,7 +119,7 @@ func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
 		}
 		logs.Error("请求转码资源失败, 尝试请求原画资源, 原始响应: %v", jsons.FromObject(originRes))
 		fi.UseTranscode = false
-		return FetchResource(fi)
+		return fetchResource(fi)
 	}
 
 	// 请求转码资源
@@ -93,14 +173,15 @@ func FetchFsList(path string, header http.Header) model.HttpRes[FsList] {
 	defer removeMainApiRunner()
 
 	var res FsList
-	err := Fetch("/api/fs/list", http.MethodPost, header, map[string]any{
+	ins, err := fetchServed("/api/fs/list", http.MethodPost, header, map[string]any{
 		"refresh":  false,
-		"password": "",
+		"password": config.C.Openlist.PasswordOf(path),
 		"path":     path,
 	}, &res, false)
 	if err != nil {
-		return model.HttpRes[FsList]{Code: http.StatusInternalServerError, Msg: fmt.Sprintf("FsList 请求失败: %v", err)}
+		return model.HttpRes[FsList]{Code: errCode(err), Msg: fmt.Sprintf("FsList 请求失败: %v", err)}
 	}
+	res.setHost(ins.Host)
 	return model.HttpRes[FsList]{Code: http.StatusOK, Data: res}
 }
 
@@ -116,14 +197,15 @@ func FetchFsGet(path string, header http.Header) model.HttpRes[FsGet] {
 	defer removeMainApiRunner()
 
 	var res FsGet
-	err := Fetch("/api/fs/get", http.MethodPost, header, map[string]any{
+	ins, err := fetchServed("/api/fs/get", http.MethodPost, header, map[string]any{
 		"refresh":  false,
-		"password": "",
+		"password": config.C.Openlist.PasswordOf(path),
 		"path":     path,
 	}, &res, false)
 	if err != nil {
-		return model.HttpRes[FsGet]{Code: http.StatusInternalServerError, Msg: fmt.Sprintf("FsGet 请求失败: %v", err)}
+		return model.HttpRes[FsGet]{Code: errCode(err), Msg: fmt.Sprintf("FsGet 请求失败: %v", err)}
 	}
+	res.Host = ins.Host
 	return model.HttpRes[FsGet]{Code: http.StatusOK, Data: res}
 }
 
@@ -141,23 +223,224 @@ func FetchFsOther(path string, header http.Header) model.HttpRes[FsOther] {
 	var res FsOther
 	err := Fetch("/api/fs/other", http.MethodPost, header, map[string]any{
 		"method":   "video_preview",
-		"password": "",
+		"password": config.C.Openlist.PasswordOf(path),
 		"path":     path,
 	}, &res, false)
 	if err != nil {
-		return model.HttpRes[FsOther]{Code: http.StatusInternalServerError, Msg: fmt.Sprintf("FsOther 请求失败: %v", err)}
+		return model.HttpRes[FsOther]{Code: errCode(err), Msg: fmt.Sprintf("FsOther 请求失败: %v", err)}
 	}
 	return model.HttpRes[FsOther]{Code: http.StatusOK, Data: res}
 }
 
+// FetchFsLink 请求 openlist "/api/fs/link" 接口, 获取文件经过 openlist 驱动处理后的访问地址
+//
+// 该接口需要管理员权限
+func FetchFsLink(path string, header http.Header) model.HttpRes[FsLink] {
+	return fetchFsLink(path, header, "")
+}
+
+// fetchFsLink 请求 openlist "/api/fs/link" 接口, host 不为空时只请求该地址对应的实例
+func fetchFsLink(path string, header http.Header, host string) model.HttpRes[FsLink] {
+	if strs.AnyEmpty(path) {
+		return model.HttpRes[FsLink]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
+	}
+
+	addMainApiRunner()
+	defer removeMainApiRunner()
+
+	instances := config.C.Openlist.Route(path)
+	if idx := slices.IndexFunc(instances, func(ins *config.OpenlistInstance) bool { return ins.Host == host }); idx != -1 {
+		instances = instances[idx : idx+1]
+	}
+	var res FsLink
+	body := map[string]any{
+		"password": config.C.Openlist.PasswordOf(path),
+		"path":     path,
+	}
+	_, err := fetchAmong(instances, path, "/api/fs/link", http.MethodPost, header, body, &res, false)
+	if err != nil {
+		return model.HttpRes[FsLink]{Code: errCode(err), Msg: fmt.Sprintf("FsLink 请求失败: %v", err)}
+	}
+	if res.Url == "" {
+		return model.HttpRes[FsLink]{Code: http.StatusNotFound, Msg: "FsLink 响应的地址为空"}
+	}
+	return model.HttpRes[FsLink]{Code: http.StatusOK, Data: res}
+}
+
+// FetchFsSearch 请求 openlist "/api/fs/search" 接口, 只搜索文件
+//
+// 需要 openlist 开启索引, parent 为搜索的根目录, keywords 为搜索关键字
+func FetchFsSearch(parent, keywords string, limit int, header http.Header) model.HttpRes[FsSearch] {
+	if strs.AnyEmpty(parent, keywords) {
+		return model.HttpRes[FsSearch]{Code: http.StatusBadRequest, Msg: "参数 parent 和 keywords 不能为空"}
+	}
+
+	addMainApiRunner()
+	defer removeMainApiRunner()
+
+	var res FsSearch
+	err := Fetch("/api/fs/search", http.MethodPost, header, map[string]any{
+		"parent":   parent,
+		"keywords": keywords,
+		"scope":    2,
+		"page":     1,
+		"per_page": limit,
+		"password": config.C.Openlist.PasswordOf(parent),
+	}, &res, false)
+	if err != nil {
+		return model.HttpRes[FsSearch]{Code: errCode(err), Msg: fmt.Sprintf("FsSearch 请求失败: %v", err)}
+	}
+	return model.HttpRes[FsSearch]{Code: http.StatusOK, Data: res}
+}
+
+// FetchStorageList 请求 openlist "/api/admin/storage/list" 接口
+//
+// 需要使用管理员 token, 一次性返回所有实例的所有存储
+func FetchStorageList() model.HttpRes[StorageList] {
+	var all StorageList
+	var errs []error
+	for _, ins := range config.C.Openlist.Instances {
+		var res StorageList
+		err := fetchInstance(ins, "/api/admin/storage/list?page=1&per_page=1000", http.MethodGet, nil, nil, &res, false)
+		if err != nil {
+			logs.Warn("openlist 实例 [%s] 读取存储列表失败: %v", ins.Name, err)
+			errs = append(errs, err)
+			continue
+		}
+		all.Total += res.Total
+		all.Content = append(all.Content, res.Content...)
+	}
+	if len(config.C.Openlist.Instances) == 0 {
+		return model.HttpRes[StorageList]{Code: http.StatusInternalServerError, Msg: "openlist.host 配置为空"}
+	}
+	if len(errs) == len(config.C.Openlist.Instances) {
+		return model.HttpRes[StorageList]{Code: http.StatusInternalServerError, Msg: fmt.Sprintf("StorageList 请求失败: %v", errors.Join(errs...))}
+	}
+	return model.HttpRes[StorageList]{Code: http.StatusOK, Data: all}
+}
+
+// HostOf 获取负责 openlist 路径 path 的首选实例访问地址
+func HostOf(path string) string {
+	instances := config.C.Openlist.Route(path)
+	if len(instances) == 0 {
+		return config.C.Openlist.Host
+	}
+	return instances[0].Host
+}
+
 // Fetch 请求 openlist api, 响应封装在 v 指针指向的结构中
+//
+// 根据请求体中的 path (或 parent) 参数选择 openlist 实例,
+// 请求失败时按配置顺序转移到下一个实例, 文件不存在以及密码错误除外
 func Fetch(uri, method string, header http.Header, body map[string]any, v any, closeConn bool) error {
-	host := config.C.Openlist.Host
-	token := config.C.Openlist.Token
-	if strs.AnyEmpty(host, token) {
-		return fmt.Errorf("openlist.host 或 openlist.token 配置为空")
+	_, err := fetchServed(uri, method, header, body, v, closeConn)
+	return err
+}
+
+// fetchServed 与 Fetch 相同, 同时返回实际响应请求的 openlist 实例
+func fetchServed(uri, method string, header http.Header, body map[string]any, v any, closeConn bool) (*config.OpenlistInstance, error) {
+	routePath, _ := body["path"].(string)
+	if routePath == "" {
+		routePath, _ = body["parent"].(string)
+	}
+	return fetchAmong(config.C.Openlist.Route(routePath), routePath, uri, method, header, body, v, closeConn)
+}
+
+// fetchAmong 按顺序请求 instances 中的实例, 返回第一个请求成功的实例
+func fetchAmong(instances []*config.OpenlistInstance, routePath, uri, method string, header http.Header, body map[string]any, v any, closeConn bool) (*config.OpenlistInstance, error) {
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("openlist.host 配置为空")
+	}
+
+	breakerEnable := config.C.Openlist.CircuitBreaker.Enable
+	errs := make([]error, 0, len(instances))
+	for i, ins := range instances {
+		var b *breaker
+		if breakerEnable {
+			if b = getBreaker(breakerKey(ins, routePath)); !b.allow() {
+				errs = append(errs, fmt.Errorf("[%s] %w", ins.Name, ErrCircuitOpen))
+				continue
+			}
+		}
+
+		err := fetchInstance(ins, uri, method, header, body, v, closeConn)
+		apiRequests.Inc(ins.Name, uri)
+		if err != nil {
+			apiErrors.Inc(ins.Name, uri)
+		}
+		if b != nil {
+			if err == nil {
+				b.success()
+			} else {
+				b.failure(err)
+			}
+		}
+		if err == nil {
+			return ins, nil
+		}
+		if len(instances) == 1 || isGlobalError(err) {
+			return nil, err
+		}
+		if i < len(instances)-1 {
+			logs.Warn("openlist 实例 [%s] 请求失败: %v, 尝试下一个实例", ins.Name, err)
+		}
+		errs = append(errs, fmt.Errorf("[%s] %w", ins.Name, err))
+	}
+	return nil, fmt.Errorf("所有 openlist 实例请求失败: %w", errors.Join(errs...))
+}
+
+// isGlobalError 判断请求异常是否在所有实例上都会出现 (文件不存在, 密码错误), 这类异常无需转移实例
+//
+// 存储不存在, 驱动获取直链失败等异常只与当前实例有关, 仍然需要转移
+func isGlobalError(err error) bool {
+	var apiErr *ApiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code == http.StatusForbidden || apiErr.Code == http.StatusNotFound {
+		return true
+	}
+	return strings.Contains(strings.ToLower(apiErr.Message), "object not found")
+}
+
+// fetchInstance 请求指定的 openlist 实例, 响应封装在 v 指针指向的结构中
+//
+// 使用账号密码登录时, token 失效会自动重新登录并重试一次
+func fetchInstance(ins *config.OpenlistInstance, uri, method string, header http.Header, body map[string]any, v any, closeConn bool) error {
+	token, err := authToken(ins)
+	if err != nil {
+		return fmt.Errorf("Fetch 获取 token 失败: %v", err)
 	}
 
+	res, err := fetchRemote(ins, uri, method, header, body, token, closeConn)
+	if errors.Is(err, errUnauthorized) && ins.UseLogin() {
+		logs.Warn("openlist 实例 [%s] token 已失效, 尝试重新登录", ins.Name)
+		if token, err = refreshToken(ins, token); err != nil {
+			return fmt.Errorf("Fetch 刷新 token 失败: %v", err)
+		}
+		res, err = fetchRemote(ins, uri, method, header, body, token, closeConn)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 如果 v 参数为不为 nil 的指针, 写入响应数据
+	vf := reflect.ValueOf(v)
+	if vf.Kind() != reflect.Ptr || vf.IsNil() {
+		return nil
+	}
+	if err = json.Unmarshal(res.Data, v); err != nil {
+		return fmt.Errorf("Fetch 请求响应数据解析失败: %v, 响应内容: %s", err, string(res.Data))
+	}
+	return nil
+}
+
+// fetchRemote 携带 token 发出请求, 并检测响应状态是否正常
+//
+// token 无效时返回的错误包装了 errUnauthorized
+func fetchRemote(ins *config.OpenlistInstance, uri, method string, header http.Header, body map[string]any, token string, closeConn bool) (RemoteCommonResult, error) {
+	var res RemoteCommonResult
+
 	// 1 发出请求
 	if header == nil {
 		header = make(http.Header)
@@ -167,42 +450,56 @@ func Fetch(uri, method string, header http.Header, body map[string]any, v any, c
 	header.Set("Content-Type", "application/json;charset=utf-8")
 	header.Set("Authorization", token)
 
-	holder := https.Request(method, host+uri).Header(header).Body(https.MapBody(body))
+	holder := https.Request(method, ins.Host+uri).Header(header).Body(https.MapBody(body)).Timeout(ins.TimeoutDuration())
 	if closeConn {
 		holder.CloseConn()
 	}
 	resp, err := holder.Do()
 	if err != nil {
-		return fmt.Errorf("Fetch 请求失败: %v", err)
+		return res, fmt.Errorf("Fetch 请求失败: %v", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return res, fmt.Errorf("Fetch 请求失败: %w", errUnauthorized)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Fetch 请求失败, 错误响应码: %v", resp.Status)
+		return res, fmt.Errorf("Fetch 请求失败, 错误响应码: %v", resp.Status)
 	}
 
 	// 2 检测响应状态是否正常
 	resBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("Fetch 请求读取响应失败: %v", err)
+		return res, fmt.Errorf("Fetch 请求读取响应失败: %v", err)
 	}
-
-	var res RemoteCommonResult
 	if err = json.Unmarshal(resBytes, &res); err != nil {
-		return fmt.Errorf("Fetch 请求响应解析失败: %v, 响应内容: %v", err, string(resBytes))
+		return res, fmt.Errorf("Fetch 请求响应解析失败: %v, 响应内容: %v", err, string(resBytes))
+	}
+	if res.Code == http.StatusUnauthorized {
+		return res, fmt.Errorf("Fetch 请求响应状态异常: %w, 消息: %s", errUnauthorized, res.Message)
 	}
 	if res.Code != http.StatusOK {
-		return fmt.Errorf("Fetch 请求响应状态异常: %d, 消息: %s", res.Code, res.Message)
+		return res, &ApiError{Code: res.Code, Message: res.Message}
 	}
+	return res, nil
+}
 
-	// 3 如果 v 参数为不为 nil 的指针, 写入响应数据
-	vf := reflect.ValueOf(v)
-	if vf.Kind() != reflect.Ptr || vf.IsNil() {
-		return nil
-	}
-	if err = json.Unmarshal(res.Data, v); err != nil {
-		return fmt.Errorf("Fetch 请求响应数据解析失败: %v, 响应内容: %s", err, string(res.Data))
+// ApiError openlist 接口响应的业务异常
+type ApiError struct {
+	Code    int    // 响应状态码
+	Message string // 响应消息
+}
+
+// Error 实现 error 接口
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("Fetch 请求响应状态异常: %d, 消息: %s", e.Code, e.Message)
+}
+
+// errCode 根据请求异常生成响应码, 被熔断的请求响应 503
+func errCode(err error) int {
+	if errors.Is(err, ErrCircuitOpen) {
+		return http.StatusServiceUnavailable
 	}
-	return nil
+	return http.StatusInternalServerError
 }
 
 // addMainApiRunner 添加主 api 请求标记
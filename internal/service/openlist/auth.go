@@ -0,0 +1,113 @@
+package openlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/encrypts"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// errUnauthorized openlist 接口返回未授权
+var errUnauthorized = errors.New("openlist token 无效或已过期")
+
+// loginTokens 通过账号密码登录获取到的 token, key 为实例名称
+var (
+	loginTokens = map[string]string{}
+	loginMu     sync.Mutex
+)
+
+// authToken 获取请求 openlist 实例接口需要携带的 token
+//
+// 配置了账号密码时, 使用登录获取到的 token, 否则使用配置的静态 token
+func authToken(ins *config.OpenlistInstance) (string, error) {
+	if !ins.UseLogin() {
+		if ins.Token == "" {
+			return "", errors.New("token 配置为空")
+		}
+		return ins.Token, nil
+	}
+
+	loginMu.Lock()
+	defer loginMu.Unlock()
+	if token := loginTokens[ins.Name]; token != "" {
+		return token, nil
+	}
+	token, err := login(ins)
+	if err != nil {
+		return "", err
+	}
+	loginTokens[ins.Name] = token
+	return token, nil
+}
+
+// refreshToken token 失效时调用, 重新登录获取新的 token
+//
+// 如果 token 已经被其他请求刷新过, 则直接返回新的 token
+func refreshToken(ins *config.OpenlistInstance, expired string) (string, error) {
+	loginMu.Lock()
+	defer loginMu.Unlock()
+	if token := loginTokens[ins.Name]; token != "" && token != expired {
+		return token, nil
+	}
+	delete(loginTokens, ins.Name)
+	token, err := login(ins)
+	if err != nil {
+		return "", err
+	}
+	loginTokens[ins.Name] = token
+	return token, nil
+}
+
+// login 请求 openlist "/api/auth/login" 接口, 返回 token
+func login(ins *config.OpenlistInstance) (string, error) {
+	body := map[string]any{
+		"username": ins.Username,
+		"password": ins.Password,
+	}
+	if ins.OtpSecret != "" {
+		code, err := encrypts.Totp(ins.OtpSecret, time.Now())
+		if err != nil {
+			return "", err
+		}
+		body["otp_code"] = code
+	}
+
+	header := http.Header{"Content-Type": []string{"application/json;charset=utf-8"}}
+	resp, err := https.Post(ins.Host + "/api/auth/login").Header(header).Body(https.MapBody(body)).Timeout(ins.TimeoutDuration()).Do()
+	if err != nil {
+		return "", fmt.Errorf("登录 openlist 失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("登录 openlist 失败, 错误响应码: %v", resp.Status)
+	}
+
+	resBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("登录 openlist 读取响应失败: %v", err)
+	}
+	var res RemoteCommonResult
+	if err = json.Unmarshal(resBytes, &res); err != nil {
+		return "", fmt.Errorf("登录 openlist 响应解析失败: %v, 响应内容: %v", err, string(resBytes))
+	}
+	if res.Code != http.StatusOK {
+		return "", fmt.Errorf("登录 openlist 响应状态异常: %d, 消息: %s", res.Code, res.Message)
+	}
+
+	var data struct {
+		Token string `json:"token"`
+	}
+	if err = json.Unmarshal(res.Data, &data); err != nil || data.Token == "" {
+		return "", fmt.Errorf("登录 openlist 响应数据异常: %s", string(res.Data))
+	}
+	logs.Success("openlist 实例 [%s] 登录成功, 用户: %s", ins.Name, ins.Username)
+	return data.Token, nil
+}
@@ -0,0 +1,246 @@
+package openlist
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// BreakerState 熔断器状态
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // 正常
+	BreakerOpen     BreakerState = "open"      // 熔断中, 拒绝所有请求
+	BreakerHalfOpen BreakerState = "half-open" // 冷却结束, 放行一个试探请求
+)
+
+// ErrCircuitOpen 请求被熔断器拒绝
+var ErrCircuitOpen = errors.New("openlist 请求已熔断")
+
+// breakerProbeWait 半开状态下试探请求尚未返回时, 后台任务的轮询间隔
+const breakerProbeWait = time.Second
+
+// BreakerStatus 熔断器状态快照
+type BreakerStatus struct {
+	Key       string       `json:"key"`                  // 实例名称:存储
+	State     BreakerState `json:"state"`                // 当前状态
+	Failures  int          `json:"failures"`             // 连续失败次数
+	Cooldown  string       `json:"cooldown"`             // 当前冷却时间
+	RetryAt   *time.Time   `json:"retry_at,omitempty"`   // 熔断中时, 下一次允许试探的时间
+	LastError string       `json:"last_error,omitempty"` // 最后一次失败的错误信息
+}
+
+// breaker 单个 openlist 实例存储的熔断器
+type breaker struct {
+	mu       sync.Mutex
+	key      string
+	state    BreakerState
+	failures int
+	cooldown time.Duration
+	openedAt time.Time
+	probing  bool
+	lastErr  string
+}
+
+// breakers 所有熔断器, key 为实例名称:存储
+var breakers sync.Map
+
+// breakerKey 根据实例和 openlist 路径生成熔断器 key, 以路径的第一级目录作为存储
+func breakerKey(ins *config.OpenlistInstance, path string) string {
+	storage := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+	return ins.Name + ":/" + storage
+}
+
+// getBreaker 获取熔断器, 不存在则创建
+func getBreaker(key string) *breaker {
+	if b, ok := breakers.Load(key); ok {
+		return b.(*breaker)
+	}
+	cfg := config.C.Openlist.CircuitBreaker
+	b, _ := breakers.LoadOrStore(key, &breaker{
+		key:      key,
+		state:    BreakerClosed,
+		cooldown: time.Duration(cfg.Cooldown) * time.Second,
+	})
+	return b.(*breaker)
+}
+
+// allow 判断当前是否允许请求
+//
+// 冷却结束后进入半开状态, 只放行一个试探请求
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if time.Now().Before(b.openedAt.Add(b.cooldown)) {
+			return false
+		}
+		b.state, b.probing = BreakerHalfOpen, true
+		logs.Info("openlist 熔断器 [%s] 冷却结束, 进入半开状态", b.key)
+		return true
+	default:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+}
+
+// success 记录一次成功的请求, 熔断器恢复正常
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != BreakerClosed {
+		logs.Success("openlist 熔断器 [%s] 已恢复", b.key)
+	}
+	b.state, b.failures, b.probing, b.lastErr = BreakerClosed, 0, false, ""
+	b.cooldown = time.Duration(config.C.Openlist.CircuitBreaker.Cooldown) * time.Second
+}
+
+// failure 记录一次失败的请求
+//
+// 普通的业务异常 (如文件不存在) 说明实例可以正常访问, 视为成功
+func (b *breaker) failure(err error) {
+	cfg := config.C.Openlist.CircuitBreaker
+	if errors.Is(err, errUnauthorized) || isBizError(err) {
+		b.success()
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.lastErr = err.Error()
+	switch {
+	case b.state == BreakerHalfOpen:
+		// 试探失败, 冷却时间翻倍
+		b.cooldown = min(b.cooldown*2, time.Duration(cfg.MaxCooldown)*time.Second)
+	case b.failures >= cfg.FailureThreshold || cfg.ShouldTrip(b.lastErr):
+	default:
+		return
+	}
+	b.state, b.openedAt, b.probing = BreakerOpen, time.Now(), false
+	logs.Warn("openlist 熔断器 [%s] 已熔断, 冷却时间: %v, 连续失败次数: %d, 错误: %s", b.key, b.cooldown, b.failures, b.lastErr)
+}
+
+// isBizError 判断请求异常是否为 openlist 正常响应的业务异常 (如文件不存在)
+//
+// 限流以及命中熔断关键字的响应视为实例故障, 不属于业务异常
+func isBizError(err error) bool {
+	var apiErr *ApiError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code != http.StatusTooManyRequests && !config.C.Openlist.CircuitBreaker.ShouldTrip(apiErr.Message)
+}
+
+// status 获取熔断器状态快照
+func (b *breaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bs := BreakerStatus{
+		Key:       b.key,
+		State:     b.state,
+		Failures:  b.failures,
+		Cooldown:  b.cooldown.String(),
+		LastError: b.lastErr,
+	}
+	if b.state == BreakerOpen {
+		retryAt := b.openedAt.Add(b.cooldown)
+		bs.RetryAt = &retryAt
+	}
+	return bs
+}
+
+// blockedFor 判断熔断器当前是否拒绝请求, 返回需要等待的时间
+//
+// 半开状态下试探请求尚未返回时同样视为拒绝, 等待 breakerProbeWait 后重新判断
+func (b *breaker) blockedFor() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if d := time.Until(b.openedAt.Add(b.cooldown)); d > 0 {
+			return d, true
+		}
+		return 0, false
+	case BreakerHalfOpen:
+		return breakerProbeWait, b.probing
+	default:
+		return 0, false
+	}
+}
+
+// Breakers 获取所有熔断器的状态, 按 key 排序
+func Breakers() []BreakerStatus {
+	res := make([]BreakerStatus, 0)
+	breakers.Range(func(_, value any) bool {
+		res = append(res, value.(*breaker).status())
+		return true
+	})
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	return res
+}
+
+// ResetBreaker 手动恢复指定的熔断器, key 为空时恢复所有熔断器
+//
+// 返回恢复的熔断器数量
+func ResetBreaker(key string) int {
+	cnt := 0
+	breakers.Range(func(k, value any) bool {
+		if key == "" || k == key {
+			value.(*breaker).success()
+			cnt++
+		}
+		return true
+	})
+	return cnt
+}
+
+// WaitBreaker 阻塞等待, 直到负责 openlist 路径 path 的实例中至少有一个未处于熔断状态
+//
+// 用于在熔断期间暂停后台任务, ctx 结束时返回 ctx 的错误
+func WaitBreaker(ctx context.Context, path string) error {
+	if !config.C.Openlist.CircuitBreaker.Enable {
+		return nil
+	}
+	logged := false
+	for {
+		var wait time.Duration
+		for _, ins := range config.C.Openlist.Route(path) {
+			b, ok := breakers.Load(breakerKey(ins, path))
+			if !ok {
+				return nil
+			}
+			d, blocked := b.(*breaker).blockedFor()
+			if !blocked {
+				return nil
+			}
+			if wait == 0 || d < wait {
+				wait = d
+			}
+		}
+
+		if !logged {
+			logs.Warn("openlist 路径 [%s] 的所有实例均处于熔断状态, 暂停 %v", path, wait.Round(time.Second))
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package openlist
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+// setupBreaker 初始化熔断配置, 返回实例 ins 负责 /115 存储的熔断器
+func setupBreaker(t *testing.T) (*config.OpenlistInstance, *breaker) {
+	ins := &config.OpenlistInstance{Name: "main", Host: "http://main"}
+	config.C = &config.Config{Openlist: &config.Openlist{
+		Instances:      []*config.OpenlistInstance{ins},
+		CircuitBreaker: &config.CircuitBreaker{Enable: true, FailureThreshold: 2, Cooldown: 10, MaxCooldown: 30},
+	}}
+	if err := config.C.Openlist.Init(); err != nil {
+		t.Fatal(err)
+	}
+	breakers.Clear()
+	t.Cleanup(breakers.Clear)
+	return ins, getBreaker(breakerKey(ins, "/115/a.mkv"))
+}
+
+// expire 使熔断器的冷却时间立即结束
+func (b *breaker) expire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.openedAt = time.Now().Add(-b.cooldown)
+}
+
+func TestBreakerStateMachine(t *testing.T) {
+	_, b := setupBreaker(t)
+	errNet := errors.New("connection refused")
+
+	// closed -> open
+	b.failure(errNet)
+	if st := b.status(); st.State != BreakerClosed || st.Failures != 1 {
+		t.Fatalf("未达到阈值时状态 = %+v", st)
+	}
+	b.failure(errNet)
+	st := b.status()
+	if st.State != BreakerOpen || st.RetryAt == nil {
+		t.Fatalf("达到阈值后状态 = %+v, want open", st)
+	}
+	if b.allow() {
+		t.Error("熔断中仍允许请求")
+	}
+	if _, blocked := b.blockedFor(); !blocked {
+		t.Error("熔断中 blockedFor() 未拒绝")
+	}
+
+	// open -> half-open, 只放行一个试探请求
+	b.expire()
+	if !b.allow() {
+		t.Fatal("冷却结束后未放行试探请求")
+	}
+	if st := b.status(); st.State != BreakerHalfOpen {
+		t.Fatalf("冷却结束后状态 = %s, want half-open", st.State)
+	}
+	if b.allow() {
+		t.Error("半开状态放行了第二个请求")
+	}
+	if d, blocked := b.blockedFor(); !blocked || d != breakerProbeWait {
+		t.Errorf("试探期间 blockedFor() = %v %v, want %v true", d, blocked, breakerProbeWait)
+	}
+
+	// half-open -> open, 冷却时间翻倍
+	b.failure(errNet)
+	if st := b.status(); st.State != BreakerOpen || st.Cooldown != (20*time.Second).String() {
+		t.Fatalf("试探失败后状态 = %+v, want open 20s", st)
+	}
+	b.expire()
+	b.allow()
+	b.failure(errNet)
+	if st := b.status(); st.Cooldown != (30 * time.Second).String() {
+		t.Errorf("冷却时间 = %s, want 上限 30s", st.Cooldown)
+	}
+
+	// half-open -> closed, 冷却时间复位
+	b.expire()
+	b.allow()
+	b.success()
+	st = b.status()
+	if st.State != BreakerClosed || st.Failures != 0 || st.Cooldown != (10*time.Second).String() || st.RetryAt != nil {
+		t.Errorf("试探成功后状态 = %+v, want closed", st)
+	}
+	if _, blocked := b.blockedFor(); blocked {
+		t.Error("恢复后 blockedFor() 仍拒绝")
+	}
+}
+
+func TestBreakerBizError(t *testing.T) {
+	_, b := setupBreaker(t)
+	b.failure(errors.New("timeout"))
+	b.failure(&ApiError{Code: 500, Message: "object not found"})
+	if st := b.status(); st.State != BreakerClosed || st.Failures != 0 {
+		t.Errorf("业务异常后状态 = %+v, want closed", st)
+	}
+	b.failure(&ApiError{Code: 429, Message: "too many requests"})
+	b.failure(&ApiError{Code: 429, Message: "too many requests"})
+	if st := b.status(); st.State != BreakerOpen {
+		t.Errorf("连续限流后状态 = %s, want open", st.State)
+	}
+}
+
+func TestWaitBreakerHalfOpen(t *testing.T) {
+	_, b := setupBreaker(t)
+	b.failure(errors.New("timeout"))
+	b.failure(errors.New("timeout"))
+	b.expire()
+	b.allow()
+
+	// 试探请求未返回时需要继续等待
+	ctx, cancel := context.WithTimeout(context.Background(), breakerProbeWait/2)
+	defer cancel()
+	if err := WaitBreaker(ctx, "/115/a.mkv"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("半开状态下 WaitBreaker() = %v, want 超时", err)
+	}
+
+	// 试探成功后恢复
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		b.success()
+	}()
+	if err := WaitBreaker(context.Background(), "/115/a.mkv"); err != nil {
+		t.Errorf("WaitBreaker() = %v", err)
+	}
+
+	// 其他存储不受影响
+	b.failure(errors.New("timeout"))
+	b.failure(errors.New("timeout"))
+	if err := WaitBreaker(context.Background(), "/ali/a.mkv"); err != nil {
+		t.Errorf("WaitBreaker() = %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package openlist_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+)
+
+// standInOpenlist 模拟 openlist 实例, 以 httpCode 响应, 响应体中的业务状态码为 code
+func standInOpenlist(t *testing.T, httpCode, code int, msg string) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(httpCode)
+		json.NewEncoder(w).Encode(map[string]any{"code": code, "message": msg, "data": map[string]any{"name": r.Host}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// setupInstances 使用给定的实例初始化 openlist 配置
+func setupInstances(t *testing.T, instances ...*config.OpenlistInstance) {
+	config.C = &config.Config{Openlist: &config.Openlist{Instances: instances}}
+	if err := config.C.Openlist.Init(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchFailover(t *testing.T) {
+	tests := []struct {
+		name         string
+		httpCode     int
+		code         int
+		msg          string
+		wantErr      bool
+		wantFallback bool
+	}{
+		{name: "成功", httpCode: 200, code: 200, wantErr: false, wantFallback: false},
+		{name: "http 5xx", httpCode: 502, code: 0, wantErr: false, wantFallback: true},
+		{name: "实例存储不存在", httpCode: 200, code: 500, msg: "failed get storage: storage not found", wantErr: false, wantFallback: true},
+		{name: "驱动获取直链失败", httpCode: 200, code: 500, msg: "failed get link: 429 too many requests", wantErr: false, wantFallback: true},
+		{name: "鉴权失败", httpCode: 200, code: 401, msg: "token is invalidated", wantErr: false, wantFallback: true},
+		{name: "文件不存在", httpCode: 200, code: 500, msg: "failed get objs: object not found", wantErr: true, wantFallback: false},
+		{name: "密码错误", httpCode: 200, code: 403, msg: "password is incorrect or you have no permission", wantErr: true, wantFallback: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, primaryHits := standInOpenlist(t, tt.httpCode, tt.code, tt.msg)
+			fallback, fallbackHits := standInOpenlist(t, 200, 200, "success")
+			setupInstances(t,
+				&config.OpenlistInstance{Name: "primary", Host: primary.URL, Token: "t"},
+				&config.OpenlistInstance{Name: "fallback", Host: fallback.URL, Token: "t"},
+			)
+
+			var res map[string]any
+			err := openlist.Fetch("/api/fs/get", http.MethodPost, nil, map[string]any{"path": "/movie/a.mkv"}, &res, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if primaryHits.Load() != 1 {
+				t.Errorf("主实例请求次数 = %d, want 1", primaryHits.Load())
+			}
+			if got := fallbackHits.Load() > 0; got != tt.wantFallback {
+				t.Errorf("是否转移到备用实例 = %v, want %v", got, tt.wantFallback)
+			}
+		})
+	}
+
+	t.Run("网络异常", func(t *testing.T) {
+		primary, _ := standInOpenlist(t, 200, 200, "")
+		primary.Close()
+		fallback, fallbackHits := standInOpenlist(t, 200, 200, "success")
+		setupInstances(t,
+			&config.OpenlistInstance{Name: "primary", Host: primary.URL, Token: "t"},
+			&config.OpenlistInstance{Name: "fallback", Host: fallback.URL, Token: "t"},
+		)
+		if err := openlist.Fetch("/api/fs/get", http.MethodPost, nil, map[string]any{"path": "/a"}, nil, false); err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if fallbackHits.Load() != 1 {
+			t.Errorf("备用实例请求次数 = %d, want 1", fallbackHits.Load())
+		}
+	})
+}
+
+func TestOpenlistRoute(t *testing.T) {
+	setupInstances(t,
+		&config.OpenlistInstance{Name: "all", Host: "http://all"},
+		&config.OpenlistInstance{Name: "115", Host: "http://115", Prefixes: []string{"/115"}},
+		&config.OpenlistInstance{Name: "115-mirror", Host: "http://mirror", Prefixes: []string{"/115", "/ali"}},
+	)
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/115/movie/a.mkv", []string{"115", "115-mirror", "all"}},
+		{"/ali/a.mkv", []string{"115-mirror", "all"}},
+		{"/local/a.mkv", []string{"all"}},
+	}
+	for _, tt := range tests {
+		got := make([]string, 0)
+		for _, ins := range config.C.Openlist.Route(tt.path) {
+			got = append(got, ins.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Route(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package openlist
+
+import (
+	"net/http"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+// CheckToken 请求 openlist 实例的当前用户信息接口, 校验 token 是否有效
+//
+// 使用账号密码登录时, token 失效会自动重新登录
+func CheckToken(ins *config.OpenlistInstance) error {
+	return fetchInstance(ins, "/api/me", http.MethodGet, nil, nil, nil, true)
+}
@@ -0,0 +1,161 @@
+package openlist
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+const (
+	// linkExpireMargin 直链提前失效的时间, 避免客户端拿到即将过期的直链
+	linkExpireMargin = time.Minute
+
+	// maxLinkCount 直链缓存的最大数量
+	maxLinkCount = 1024
+)
+
+// linkEntry 直链缓存项
+type linkEntry struct {
+	res       Resource
+	expiresAt time.Time
+}
+
+// links 直链缓存, key 为 openlist 路径 + 转码格式 + UA 类型
+var (
+	links   = map[string]linkEntry{}
+	linksMu sync.RWMutex
+)
+
+// linkCacheKey 生成直链缓存的 key
+func linkCacheKey(fi FetchInfo) string {
+	format := "raw"
+	if fi.UseTranscode {
+		format = fi.Format
+	}
+	return fmt.Sprintf("%s|%s|%s", fi.Path, format, UAClass(fi.Header))
+}
+
+// UAClass 根据请求头中的 User-Agent 划分客户端类型
+//
+// 取 UA 的第一个产品标识 (如 Mozilla/5.0 => mozilla), 部分网盘的直链与 UA 绑定,
+// 同一类型的客户端可以共享直链
+func UAClass(header http.Header) string {
+	ua := strings.ToLower(strings.TrimSpace(header.Get("User-Agent")))
+	if idx := strings.IndexAny(ua, "/ ;("); idx != -1 {
+		ua = ua[:idx]
+	}
+	if ua == "" {
+		return "-"
+	}
+	return ua
+}
+
+// LinkExpiry 从直链的 query 参数中解析过期时间
+//
+// 支持: X-Oss-Date + X-Oss-Expires (OSS V4 签名, 有效秒数), x-oss-expires, Expires (unix 时间戳),
+// X-Amz-Date + X-Amz-Expires
+func LinkExpiry(link string) (time.Time, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return time.Time{}, false
+	}
+	q := make(url.Values)
+	for k, v := range u.Query() {
+		q[strings.ToLower(k)] = v
+	}
+
+	for _, prefix := range []string{"x-oss-", "x-amz-"} {
+		if q.Get(prefix+"date") != "" {
+			return relativeExpiry(q.Get(prefix+"date"), q.Get(prefix+"expires"))
+		}
+	}
+
+	for _, key := range []string{"x-oss-expires", "expires"} {
+		if ts, err := strconv.ParseInt(q.Get(key), 10, 64); err == nil && ts > 0 {
+			return time.Unix(ts, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// relativeExpiry 根据签名时间以及有效秒数计算过期时间
+func relativeExpiry(date, expires string) (time.Time, bool) {
+	d, err := time.Parse("20060102T150405Z", date)
+	if err != nil {
+		return time.Time{}, false
+	}
+	secs, err := strconv.Atoi(expires)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return d.Add(time.Duration(secs) * time.Second), true
+}
+
+// storeLink 缓存直链
+func storeLink(key string, res Resource) {
+	cfg := config.C.Cache.Link
+	now := time.Now()
+	expiresAt := now.Add(cfg.DefaultTTLDuration())
+	if exp, ok := LinkExpiry(res.Url); ok {
+		expiresAt = exp.Add(-linkExpireMargin)
+	}
+	if maxAt := now.Add(cfg.MaxTTLDuration()); expiresAt.After(maxAt) {
+		expiresAt = maxAt
+	}
+	if !expiresAt.After(now) {
+		return
+	}
+
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	if _, ok := links[key]; !ok && len(links) >= maxLinkCount {
+		evictLinks(now)
+	}
+	links[key] = linkEntry{res: res, expiresAt: expiresAt}
+}
+
+// evictLinks 移除过期的直链缓存, 仍然达到数量上限时移除最早过期的一项, 调用方需持有写锁
+func evictLinks(now time.Time) {
+	var earliestKey string
+	var earliestAt time.Time
+	for k, le := range links {
+		if !le.expiresAt.After(now) {
+			delete(links, k)
+			continue
+		}
+		if earliestKey == "" || le.expiresAt.Before(earliestAt) {
+			earliestKey, earliestAt = k, le.expiresAt
+		}
+	}
+	if len(links) >= maxLinkCount {
+		delete(links, earliestKey)
+	}
+}
+
+// loadLink 获取未过期的直链缓存
+func loadLink(key string) (Resource, time.Time, bool) {
+	linksMu.RLock()
+	defer linksMu.RUnlock()
+	le, ok := links[key]
+	if !ok || !le.expiresAt.After(time.Now()) {
+		return Resource{}, time.Time{}, false
+	}
+	return le.res, le.expiresAt, true
+}
+
+// InvalidateLink 移除 openlist 路径 path 的所有直链缓存
+func InvalidateLink(path string) {
+	linksMu.Lock()
+	defer linksMu.Unlock()
+	for k := range links {
+		if strings.HasPrefix(k, path+"|") {
+			delete(links, k)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package openlist_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+)
+
+func TestLinkExpiry(t *testing.T) {
+	tests := []struct {
+		link string
+		want time.Time
+		ok   bool
+	}{
+		{"https://bucket.oss.com/a.mp4?x-oss-expires=1700000000&x-oss-signature=abc", time.Unix(1700000000, 0), true},
+		{"https://cdn.com/a.mp4?Expires=1700000600&Signature=abc", time.Unix(1700000600, 0), true},
+		{"https://bucket.oss.com/a.mp4?x-oss-date=20231114T221320Z&x-oss-expires=1800&x-oss-signature=abc", time.Date(2023, 11, 14, 22, 43, 20, 0, time.UTC), true},
+		{"https://bucket.oss.com/a.mp4?x-oss-date=bad&x-oss-expires=1800", time.Time{}, false},
+		{"https://s3.com/a.mp4?X-Amz-Date=20231114T221320Z&X-Amz-Expires=3600", time.Date(2023, 11, 14, 23, 13, 20, 0, time.UTC), true},
+		{"https://cdn.com/a.mp4?sign=abc", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := openlist.LinkExpiry(tt.link)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("LinkExpiry(%s) = %v, %v; want %v, %v", tt.link, got, ok, tt.want, tt.ok)
+		}
+	}
+}
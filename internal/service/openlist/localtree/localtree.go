@@ -1,12 +1,14 @@
 package localtree
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"errors"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 )
 
@@ -28,29 +30,107 @@ func Init() error {
 	return nil
 }
 
+// SyncStatus 目录树同步状态
+type SyncStatus struct {
+	Enabled   bool      `json:"enabled"`              // 是否启用目录树生成
+	Synced    bool      `json:"synced"`               // 是否已经成功同步过至少一次
+	Syncing   bool      `json:"syncing"`              // 是否正在同步
+	LastSync  time.Time `json:"last_sync,omitzero"`   // 最近一次成功同步的完成时间
+	LastError string    `json:"last_error,omitempty"` // 最近一次同步失败的原因, 同步成功后清空
+}
+
+var (
+	// status 当前的同步状态
+	status   SyncStatus
+	statusMu sync.Mutex
+
+	// syncCtx 同步任务的根上下文, 调用 Stop 时取消
+	syncCtx, cancelSync = context.WithCancel(context.Background())
+
+	// syncStopped 定时同步的 goroutine 退出时关闭
+	syncStopped = make(chan struct{})
+)
+
+// Status 获取目录树同步状态
+func Status() SyncStatus {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	res := status
+	res.Enabled = config.C.Openlist.LocalTreeGen.Enable
+	return res
+}
+
 // startSync 立即同步一次目录树, 并开始定时扫描同步变更
 func startSync(s *Synchronizer) {
 	doSync := func() {
 		logf(colors.Blue, "开始同步")
+		statusMu.Lock()
+		status.Syncing = true
+		statusMu.Unlock()
+
 		start := time.Now()
-		total, added, deleted, err := s.Sync()
+		total, added, deleted, err := s.SyncContext(syncCtx)
+		syncDuration.Set(time.Since(start).Seconds())
+
+		statusMu.Lock()
+		status.Syncing = false
 		if err != nil {
+			status.LastError = err.Error()
+		} else {
+			status.Synced, status.LastSync, status.LastError = true, time.Now(), ""
+		}
+		statusMu.Unlock()
+
+		if err != nil && syncCtx.Err() != nil {
+			logf(colors.Yellow, "同步已取消")
+			return
+		}
+		if err != nil {
+			syncs.Inc("failure")
 			logf(colors.Red, "同步失败: %v", err)
 			return
 		}
+		syncs.Inc("success")
+		syncFiles.Set(float64(total), "total")
+		syncFiles.Set(float64(added), "added")
+		syncFiles.Set(float64(deleted), "deleted")
 		logf(colors.Green, "同步完成, 总数: %d, 新增: %d, 删除: %d, 耗时: %v", total, added, deleted, time.Since(start))
 	}
+	defer close(syncStopped)
 	doSync()
 
 	d := time.Minute * time.Duration(config.C.Openlist.LocalTreeGen.RefreshInterval)
 	timer := time.NewTicker(d)
-	for range timer.C {
-		doSync()
+	defer timer.Stop()
+	for {
+		select {
+		case <-syncCtx.Done():
+			return
+		case <-timer.C:
+			doSync()
+		}
+	}
+}
+
+// Stop 取消正在进行的同步并停止定时同步, 在 timeout 内等待同步退出
+func Stop(timeout time.Duration) error {
+	if !config.C.Openlist.LocalTreeGen.Enable {
+		return nil
+	}
+	cancelSync()
+	select {
+	case <-syncStopped:
+		return nil
+	case <-time.After(timeout):
+		return errors.New("等待目录树同步退出超时")
 	}
 }
 
-// logf 带上前缀的日志输出
+// logf 带上前缀的日志输出, 红色日志按 error 级别输出
 func logf(c colors.C, format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	log.Println(colors.WrapColor(c, "[openlist 目录树]: "+s))
+	level := logs.LevelInfo
+	if c == colors.Red {
+		level = logs.LevelError
+	}
+	logs.Print(level, c, "[openlist 目录树]: "+format, v...)
 }
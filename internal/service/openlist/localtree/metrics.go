@@ -0,0 +1,14 @@
+package localtree
+
+import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+
+var (
+	// syncs 按结果统计的同步次数
+	syncs = metrics.NewCounter("ge2o_localtree_syncs_total", "按结果 (success/failure) 统计的目录树同步次数", "result")
+
+	// syncDuration 最近一次同步的耗时
+	syncDuration = metrics.NewGauge("ge2o_localtree_last_sync_duration_seconds", "最近一次目录树同步的耗时 (秒)")
+
+	// syncFiles 最近一次成功同步的文件数
+	syncFiles = metrics.NewGauge("ge2o_localtree_last_sync_files", "最近一次成功同步的文件数, type 为 total/added/deleted", "type")
+)
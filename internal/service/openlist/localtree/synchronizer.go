@@ -62,6 +62,11 @@ func NewSynchronizer(baseDir string, pageSize int) *Synchronizer {
 
 // Sync 触发一次同步操作
 func (s *Synchronizer) Sync() (total, added, deleted int, err error) {
+	return s.SyncContext(context.Background())
+}
+
+// SyncContext 触发一次同步操作, ctx 被取消时中断同步, 不会更新快照以及删除本地文件
+func (s *Synchronizer) SyncContext(ctx context.Context) (total, added, deleted int, err error) {
 	if err := s.InitSnapshot(); err != nil {
 		return 0, 0, 0, fmt.Errorf("初始化快照异常: %w", err)
 	}
@@ -69,7 +74,7 @@ func (s *Synchronizer) Sync() (total, added, deleted int, err error) {
 	// 初始化状态
 	s.toSyncTasks = make(chan []FileTask, 1024)
 	okTaskChan := make(chan FileTask, 1024)
-	s.eg, s.ctx = errgroup.WithContext(context.Background())
+	s.eg, s.ctx = errgroup.WithContext(ctx)
 	s.threadsSem = make(chan struct{}, config.C.Openlist.LocalTreeGen.Threads)
 	s.hasScanFinish, s.hasScanTotal = 0, 0
 
@@ -169,6 +174,9 @@ func (s *Synchronizer) walkDir2SyncTasks(prefix string) error {
 
 	eof := false
 	err = trys.Try(func() (innerErr error) {
+		if innerErr = openlist.WaitBreaker(s.ctx, prefix); innerErr != nil {
+			return
+		}
 		page, innerErr = walker.Next()
 		if innerErr == openlist.ErrWalkEOF {
 			eof = true
@@ -187,6 +195,9 @@ func (s *Synchronizer) walkDir2SyncTasks(prefix string) error {
 		atomic.AddInt64(&s.hasScanTotal, int64(len(taskList)))
 
 		err = trys.Try(func() (innerErr error) {
+			if innerErr = openlist.WaitBreaker(s.ctx, prefix); innerErr != nil {
+				return
+			}
 			page, innerErr = walker.Next()
 			if innerErr == openlist.ErrWalkEOF {
 				eof = true
@@ -256,6 +267,13 @@ func (s *Synchronizer) handleSyncTasks(okTaskChan chan<- FileTask) {
 			return fmt.Errorf("初始化父目录异常 [%s]: %w", localAbsPath, err)
 		}
 
+		// 熔断期间暂停需要访问 openlist 的写入任务
+		if needsOpenlist(writer) {
+			if err := openlist.WaitBreaker(s.ctx, task.Path); err != nil {
+				return err
+			}
+		}
+
 		// 写入文件
 		return writer.Write(*task, localAbsPath)
 	}
@@ -357,6 +375,11 @@ func (s *Synchronizer) updateLocalTree(okTaskChan <-chan FileTask, total, added,
 		}
 	}
 
+	// 同步被中断时 okTaskChan 也会被关闭, 此时快照不完整, 不能据此删除本地文件
+	if s.ctx.Err() != nil {
+		return
+	}
+
 	toDelete := make([]string, 0, 1<<6)
 
 	// 统计并删除本地过期文件
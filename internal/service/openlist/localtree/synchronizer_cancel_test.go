@@ -0,0 +1,43 @@
+package localtree
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+func TestSynchronizer_UpdateLocalTreeCanceled(t *testing.T) {
+	config.C = &config.Config{Openlist: &config.Openlist{LocalTreeGen: &config.LocalTreeGen{AutoRemoveMaxCount: 100}}}
+
+	baseDir := t.TempDir()
+	stale := filepath.Join(baseDir, "movie.strm")
+	if err := os.WriteFile(stale, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟同步中途被取消: 上下文已取消, 任务通道已关闭, 新快照中缺少 movie.strm
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	okTaskChan := make(chan FileTask)
+	close(okTaskChan)
+
+	s := NewSynchronizer(baseDir, 50)
+	s.ctx = ctx
+
+	// select 在多个就绪分支间随机选择, 多跑几次覆盖先读到通道关闭的情况
+	for range 20 {
+		s.snapshot = NewSnapshot()
+		s.snapshot.Put("/movie.strm", false)
+		var total, added, deleted int
+		s.updateLocalTree(okTaskChan, &total, &added, &deleted)
+		if deleted != 0 {
+			t.Fatalf("同步取消后不应删除本地文件, deleted: %d", deleted)
+		}
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("本地文件被误删: %v", err)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -42,6 +41,9 @@ type FileTask struct {
 	// Sign openlist 文件签名
 	Sign string
 
+	// Host 生成签名的 openlist 实例地址
+	Host string
+
 	// Modified 文件的最后修改时间
 	Modified time.Time
 }
@@ -54,6 +56,7 @@ func FsGetTask(prefix string, info openlist.FsGet) FileTask {
 		LocalPath: fp,
 		IsDir:     info.IsDir,
 		Sign:      info.Sign,
+		Host:      info.Host,
 		Container: container,
 		Modified:  info.Modified,
 	}
@@ -92,6 +95,18 @@ func LoadTaskWriter(container string) TaskWriter {
 	return &rw
 }
 
+// needsOpenlist 判断 writer 写入文件时是否需要请求 openlist
+func needsOpenlist(writer TaskWriter) bool {
+	switch writer.(type) {
+	case *RawWriter:
+		return true
+	case *VirtualWriter, *MusicWriter:
+		return config.C.Openlist.LocalTreeGen.FFmpegEnable
+	default:
+		return false
+	}
+}
+
 // VirtualWriter 写同名空文件, 尝试写入媒体时长
 type VirtualWriter struct{}
 
@@ -135,17 +150,7 @@ type StrmWriter struct{}
 
 // OpenlistPath 生成媒体的 openlist http 访问地址
 func (sw *StrmWriter) OpenlistPath(task FileTask) string {
-	segs := strings.Split(strings.TrimPrefix(task.Path, "/"), "/")
-	for i, seg := range segs {
-		segs[i] = url.PathEscape(seg)
-	}
-
-	return fmt.Sprintf(
-		"%s/d/%s?sign=%s",
-		config.C.Openlist.Host,
-		strings.Join(segs, "/"),
-		task.Sign,
-	)
+	return openlist.SignedUrl(task.Host, task.Path, task.Sign, config.LinkModeD)
 }
 
 // Path 将 openlist 文件路径中的文件名
@@ -281,7 +286,7 @@ func getRealDownloadUrl(task FileTask) string {
 		AddHeader("User-Agent", constant.CommonDlUserAgent).
 		DoRedirect()
 	if err != nil {
-		logs.Warn("获取真实下载链接失败: %w", err)
+		logs.Warn("获取真实下载链接失败: %v", err)
 		return openlistUrl
 	}
 	defer resp.Body.Close()
@@ -0,0 +1,11 @@
+package openlist
+
+import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+
+var (
+	// apiRequests 按实例以及接口统计的 openlist api 请求数
+	apiRequests = metrics.NewCounter("ge2o_openlist_api_requests_total", "按实例以及接口统计的 openlist api 请求数", "instance", "uri")
+
+	// apiErrors 按实例以及接口统计的 openlist api 请求失败数
+	apiErrors = metrics.NewCounter("ge2o_openlist_api_errors_total", "按实例以及接口统计的 openlist api 请求失败数", "instance", "uri")
+)
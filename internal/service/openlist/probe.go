@@ -0,0 +1,40 @@
+package openlist
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+)
+
+// ProbeLink 探测直链是否可用
+//
+// 先使用 HEAD 请求, 部分网盘的直链签名与请求方法绑定, HEAD 请求失败时
+// 再使用 Range: bytes=0-0 的 GET 请求, 以 GET 请求的结果为准
+func ProbeLink(link string, header http.Header) error {
+	timeout := config.C.Openlist.LinkProbe.TimeoutDuration()
+	header = CleanHeader(header)
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	resp, err := https.Head(link).Header(header).Timeout(timeout).Do()
+	if err == nil {
+		resp.Body.Close()
+		if https.IsSuccessCode(resp.StatusCode) {
+			return nil
+		}
+	}
+
+	header.Set("Range", "bytes=0-0")
+	resp, err = https.Get(link).Header(header).Timeout(timeout).Do()
+	if err != nil {
+		return fmt.Errorf("探测直链失败: %v", err)
+	}
+	resp.Body.Close()
+	if !https.IsSuccessCode(resp.StatusCode) {
+		return fmt.Errorf("探测直链失败, 响应码: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package openlist_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+)
+
+// standInCdn 模拟网盘直链, 分别以 headCode 和 getCode 响应 HEAD 和 GET 请求
+//
+// 记录 GET 请求携带的 Range 请求头
+func standInCdn(t *testing.T, headCode, getCode int) (*httptest.Server, *atomic.Int32, *atomic.Value) {
+	var gets atomic.Int32
+	var rangeHeader atomic.Value
+	rangeHeader.Store("")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(headCode)
+			return
+		}
+		gets.Add(1)
+		rangeHeader.Store(r.Header.Get("Range"))
+		w.WriteHeader(getCode)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &gets, &rangeHeader
+}
+
+// setupLinkProbe 初始化直链探测配置
+func setupLinkProbe(t *testing.T, timeout int) {
+	lp := &config.LinkProbe{Enable: true, Timeout: timeout}
+	if err := lp.Init(); err != nil {
+		t.Fatal(err)
+	}
+	config.C = &config.Config{Openlist: &config.Openlist{LinkProbe: lp}}
+}
+
+func TestProbeLink(t *testing.T) {
+	tests := []struct {
+		name      string
+		headCode  int
+		getCode   int
+		wantErr   bool
+		wantGet   bool
+		wantRange string
+	}{
+		{name: "HEAD 成功", headCode: http.StatusOK, getCode: http.StatusForbidden, wantErr: false, wantGet: false},
+		{name: "HEAD 签名失败时使用 Range 请求", headCode: http.StatusForbidden, getCode: http.StatusPartialContent, wantErr: false, wantGet: true, wantRange: "bytes=0-0"},
+		{name: "不支持 HEAD", headCode: http.StatusMethodNotAllowed, getCode: http.StatusOK, wantErr: false, wantGet: true, wantRange: "bytes=0-0"},
+		{name: "直链过期", headCode: http.StatusForbidden, getCode: http.StatusForbidden, wantErr: true, wantGet: true, wantRange: "bytes=0-0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupLinkProbe(t, 0)
+			srv, gets, rangeHeader := standInCdn(t, tt.headCode, tt.getCode)
+
+			err := openlist.ProbeLink(srv.URL+"/a.mkv", http.Header{"User-Agent": {"test"}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProbeLink() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := gets.Load() > 0; got != tt.wantGet {
+				t.Errorf("是否发送 Range 请求 = %v, want %v", got, tt.wantGet)
+			}
+			if got := rangeHeader.Load().(string); got != tt.wantRange {
+				t.Errorf("Range = %q, want %q", got, tt.wantRange)
+			}
+		})
+	}
+
+	t.Run("跟随重定向", func(t *testing.T) {
+		setupLinkProbe(t, 0)
+		cdn, _, _ := standInCdn(t, http.StatusOK, http.StatusOK)
+		srv := httptest.NewServer(http.RedirectHandler(cdn.URL+"/a.mkv", http.StatusFound))
+		t.Cleanup(srv.Close)
+		if err := openlist.ProbeLink(srv.URL, nil); err != nil {
+			t.Errorf("ProbeLink() error = %v", err)
+		}
+	})
+
+	t.Run("超时", func(t *testing.T) {
+		setupLinkProbe(t, 50)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		t.Cleanup(srv.Close)
+		if err := openlist.ProbeLink(srv.URL, nil); err == nil {
+			t.Error("ProbeLink() 超时未返回错误")
+		}
+	})
+}
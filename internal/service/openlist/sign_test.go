@@ -0,0 +1,82 @@
+package openlist_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+)
+
+func TestSignedUrl(t *testing.T) {
+	setupInstances(t,
+		&config.OpenlistInstance{Name: "all", Host: "http://all"},
+		&config.OpenlistInstance{Name: "115", Host: "http://115", Prefixes: []string{"/115"}},
+	)
+	tests := []struct {
+		name string
+		host string
+		path string
+		sign string
+		mode config.LinkMode
+		want string
+	}{
+		{name: "指定实例", host: "http://mirror", path: "/115/a.mkv", sign: "s1", mode: config.LinkModeD, want: "http://mirror/d/115/a.mkv?sign=s1"},
+		{name: "未指定实例使用首选实例", path: "/115/a.mkv", sign: "s1", mode: config.LinkModeP, want: "http://115/p/115/a.mkv?sign=s1"},
+		{name: "路径转义", host: "http://all", path: "/电影/a b#1.mkv", sign: "s1", mode: config.LinkModeD, want: "http://all/d/%E7%94%B5%E5%BD%B1/a%20b%231.mkv?sign=s1"},
+		{name: "无签名", host: "http://all", path: "/a.mkv", mode: config.LinkModeD, want: "http://all/d/a.mkv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := openlist.SignedUrl(tt.host, tt.path, tt.sign, tt.mode); got != tt.want {
+				t.Errorf("SignedUrl() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkModeOf(t *testing.T) {
+	a := config.Openlist{LinkModes: map[string]string{
+		"/115":       "d",
+		"/115/movie": "link",
+		"/ali/":      "p",
+	}}
+	tests := []struct {
+		path string
+		want config.LinkMode
+	}{
+		{"/115/a.mkv", config.LinkModeD},
+		{"/115/movie/a.mkv", config.LinkModeFsLink},
+		{"/115/movies/a.mkv", config.LinkModeD},
+		{"/ali/a.mkv", config.LinkModeP},
+		{"/ali", config.LinkModeP},
+		{"/alist/a.mkv", config.LinkModeRaw},
+		{"/local/a.mkv", config.LinkModeRaw},
+	}
+	for _, tt := range tests {
+		if got := a.LinkModeOf(tt.path); got != tt.want {
+			t.Errorf("LinkModeOf(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFetchFsGetServedHost(t *testing.T) {
+	primary, _ := standInOpenlist(t, 502, 0, "")
+	fallback, _ := standInOpenlist(t, 200, 200, "success")
+	setupInstances(t,
+		&config.OpenlistInstance{Name: "primary", Host: primary.URL, Token: "t"},
+		&config.OpenlistInstance{Name: "fallback", Host: fallback.URL, Token: "t"},
+	)
+
+	res := openlist.FetchFsGet("/movie/a.mkv", nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("FetchFsGet() = %d %s", res.Code, res.Msg)
+	}
+	if res.Data.Host != fallback.URL {
+		t.Errorf("FetchFsGet() 响应实例 = %s, want %s", res.Data.Host, fallback.URL)
+	}
+	want := fallback.URL + "/d/movie/a.mkv?sign=s1"
+	if got := openlist.SignedUrl(res.Data.Host, "/movie/a.mkv", "s1", config.LinkModeD); got != want {
+		t.Errorf("SignedUrl() = %s, want %s", got, want)
+	}
+}
@@ -19,6 +19,8 @@ type FetchInfo struct {
 type Resource struct {
 	Url       string                    // 资源远程路径
 	Subtitles []TranscodingSubtitleInfo // 字幕信息
+	Sign      string                    // 文件签名, 仅原画资源有值
+	Size      int64                     // 文件大小, 仅原画资源有值
 }
 
 // TranscodingSubtitleInfo 转码资源内嵌的字幕信息
@@ -46,6 +48,12 @@ type RemoteCommonResult struct {
 	Data    json.RawMessage `json:"data"`    // 响应数据
 }
 
+// FsLink /api/fs/link 接口响应数据结构
+type FsLink struct {
+	Url    string              `json:"url"`    // 资源链接
+	Header map[string][]string `json:"header"` // 访问资源需要携带的请求头
+}
+
 // FsOther /api/fs/other 接口响应数据结构
 type FsOther struct {
 	Category                    string `json:"category"`                       // 资源分类
@@ -81,6 +89,8 @@ type FsGet struct {
 	Created  time.Time `json:"created"`  // 创建时间
 	HashInfo string    `json:"hashinfo"` // 哈希信息
 	Header   string    `json:"header"`   // 头信息
+
+	Host string `json:"-"` // 响应请求的 openlist 实例地址, 签名只在该实例上有效
 }
 
 // FsList /api/fs/list 接口响应数据结构
@@ -92,3 +102,40 @@ type FsList struct {
 	Header   string  `json:"header"`   // 头信息
 	Content  []FsGet `json:"content"`  // 文件列表
 }
+
+// setHost 记录响应请求的 openlist 实例地址
+func (fl *FsList) setHost(host string) {
+	for i := range fl.Content {
+		fl.Content[i].Host = host
+	}
+}
+
+// Storage openlist 存储信息
+type Storage struct {
+	Id        int    `json:"id"`         // 存储 ID
+	MountPath string `json:"mount_path"` // 挂载路径
+	Driver    string `json:"driver"`     // 驱动名称
+	Status    string `json:"status"`     // 存储状态
+	Disabled  bool   `json:"disabled"`   // 是否被禁用
+}
+
+// StorageList /api/admin/storage/list 接口响应数据结构
+type StorageList struct {
+	Total   int       `json:"total"`   // 总数
+	Content []Storage `json:"content"` // 存储列表
+}
+
+// FsSearchItem /api/fs/search 接口返回的单个搜索结果
+type FsSearchItem struct {
+	Parent string `json:"parent"` // 所在目录
+	Name   string `json:"name"`   // 文件名
+	IsDir  bool   `json:"is_dir"` // 是否为文件夹
+	Size   int64  `json:"size"`   // 文件大小
+	Type   int    `json:"type"`   // 类型
+}
+
+// FsSearch /api/fs/search 接口响应数据结构
+type FsSearch struct {
+	Total   int            `json:"total"`   // 总数
+	Content []FsSearchItem `json:"content"` // 搜索结果
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"sync"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 )
 
@@ -57,9 +58,9 @@ func WalkFsList(path string, perPage int) *Walker[FsList] {
 		waitForMainComplete()
 
 		var res FsList
-		err := Fetch("/api/fs/list", http.MethodPost, nil, map[string]any{
+		ins, err := fetchServed("/api/fs/list", http.MethodPost, nil, map[string]any{
 			"refresh":  false,
-			"password": "",
+			"password": config.C.Openlist.PasswordOf(path),
 			"path":     path,
 			"page":     w.curPage,
 			"per_page": perPage,
@@ -67,6 +68,7 @@ func WalkFsList(path string, perPage int) *Walker[FsList] {
 		if err != nil {
 			return FsList{}, fmt.Errorf("FsList 请求失败: %w", err)
 		}
+		res.setHost(ins.Host)
 		w.curPage++
 
 		if len(res.Content) == 0 {
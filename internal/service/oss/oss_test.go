@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/mappings"
 )
 
 func TestGenerateAuthKey(t *testing.T) {
@@ -45,10 +46,10 @@ func TestBuildURL(t *testing.T) {
 	}
 
 	// 初始化路径映射
-	config.C.Oss.PathMapping = []string{
+	config.C.Oss.PathMapping = mappings.Strings(
 		"/movie:/media",
 		"/series:/tv",
-	}
+	)
 	config.C.Oss.Init()
 
 	tests := []struct {
@@ -92,7 +93,7 @@ func TestMapPath(t *testing.T) {
 	config.C = &config.Config{
 		Oss: &config.Oss{
 			Enable:      true,
-			PathMapping: []string{"/movie:/media", "/series:/tv-shows"},
+			PathMapping: mappings.Strings("/movie:/media", "/series:/tv-shows"),
 		},
 	}
 	config.C.Oss.Init()
@@ -0,0 +1,236 @@
+package path
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// LearnedFileName 自动学习到的映射持久化文件名称
+const LearnedFileName = "emby2openlist-learned.json"
+
+const (
+	LearnSourceDiscovery = "discovery" // 启动时探测
+	LearnSourcePlayback  = "playback"  // 播放时学习
+)
+
+// LearnedMapping 自动学习到的 emby2openlist 映射
+type LearnedMapping struct {
+	Backend   string    `json:"backend"`    // emby 后端名称
+	From      string    `json:"from"`       // 移除 mount-path 后的 emby 路径前缀
+	To        string    `json:"to"`         // openlist 路径前缀
+	Source    string    `json:"source"`     // 学习来源, discovery 或 playback
+	LearnedAt time.Time `json:"learned_at"` // 学习时间
+}
+
+// learnedSaveDelay 映射变更后延迟写入磁盘的时间
+const learnedSaveDelay = time.Second * 5
+
+// learned 已学习到的映射, key 为 emby 后端名称 + emby 路径前缀
+var (
+	learned     = map[string]LearnedMapping{}
+	learnedMu   sync.RWMutex
+	learnedOnce sync.Once
+
+	// learnedDirty 是否有尚未写入磁盘的变更
+	learnedDirty bool
+	// learnedSaveTimer 延迟写入的定时器, 为 nil 表示没有待执行的写入
+	learnedSaveTimer *time.Timer
+)
+
+// learnedKey 生成已学习映射的 key
+func learnedKey(backend, from string) string {
+	return backend + "|" + from
+}
+
+// learnedFilePath 映射持久化文件的绝对路径
+func learnedFilePath() string {
+	return filepath.Join(config.BasePath, LearnedFileName)
+}
+
+// loadLearned 从磁盘中加载已学习到的映射, 只会执行一次
+func loadLearned() {
+	learnedOnce.Do(func() {
+		bytes, err := os.ReadFile(learnedFilePath())
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logs.Warn("读取已学习的 emby2openlist 映射失败: %v", err)
+			}
+			return
+		}
+
+		var list []LearnedMapping
+		if err = json.Unmarshal(bytes, &list); err != nil {
+			logs.Warn("解析已学习的 emby2openlist 映射失败: %v", err)
+			return
+		}
+
+		learnedMu.Lock()
+		defer learnedMu.Unlock()
+		for _, lm := range list {
+			if lm.Backend == "" {
+				lm.Backend = config.C.Emby.Name
+			}
+			learned[learnedKey(lm.Backend, lm.From)] = lm
+		}
+		logs.Info("已加载 %d 条自动学习的 emby2openlist 映射", len(list))
+	})
+}
+
+// saveLearned 延迟将已学习到的映射写入磁盘, 合并短时间内的多次变更, 调用方需持有写锁
+func saveLearned() {
+	learnedDirty = true
+	if learnedSaveTimer == nil {
+		learnedSaveTimer = time.AfterFunc(learnedSaveDelay, SaveLearned)
+	}
+}
+
+// SaveLearned 将尚未写入的映射立即写入磁盘, 在程序退出前调用
+//
+// 先写入临时文件再重命名, 避免写入过程中退出导致文件损坏
+func SaveLearned() {
+	learnedMu.Lock()
+	defer learnedMu.Unlock()
+	learnedSaveTimer = nil
+	if !learnedDirty {
+		return
+	}
+	learnedDirty = false
+
+	bytes, err := json.MarshalIndent(sortedLearned(), "", "  ")
+	if err == nil {
+		fp := learnedFilePath()
+		if err = os.WriteFile(fp+".tmp", bytes, 0644); err == nil {
+			err = os.Rename(fp+".tmp", fp)
+		}
+	}
+	if err != nil {
+		logs.Warn("保存自动学习的 emby2openlist 映射失败: %v", err)
+	}
+}
+
+// sortedLearned 按照 emby 后端名称以及 emby 路径前缀排序返回所有映射, 调用方需持有读锁
+func sortedLearned() []LearnedMapping {
+	list := make([]LearnedMapping, 0, len(learned))
+	for _, lm := range learned {
+		list = append(list, lm)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Backend != list[j].Backend {
+			return list[i].Backend < list[j].Backend
+		}
+		return list[i].From < list[j].From
+	})
+	return list
+}
+
+// Learned 返回所有已学习到的映射
+func Learned() []LearnedMapping {
+	loadLearned()
+	learnedMu.RLock()
+	defer learnedMu.RUnlock()
+	return sortedLearned()
+}
+
+// mapLearned 使用 emby 后端 backend 已学习到的映射转换路径, 优先匹配最长的前缀
+func mapLearned(backend, embyPath string) (string, bool) {
+	if !config.C.Path.Discovery.Persistent() {
+		return "", false
+	}
+	loadLearned()
+
+	learnedMu.RLock()
+	defer learnedMu.RUnlock()
+	var hit *LearnedMapping
+	for _, lm := range learned {
+		if lm.Backend != backend || !hasPathPrefix(embyPath, lm.From) {
+			continue
+		}
+		if hit == nil || len(lm.From) > len(hit.From) {
+			hit = &lm
+		}
+	}
+	if hit == nil {
+		return "", false
+	}
+	logs.Tip("命中自动学习的 emby2openlist 映射: %s => %s", hit.From, hit.To)
+	return hit.To + strings.TrimPrefix(embyPath, hit.From), true
+}
+
+// Learn 根据 emby 后端 backend 的 emby 路径 (已移除 mount-path) 以及成功请求的 openlist 路径推导出映射
+//
+// 两个路径去除公共的后缀片段后, 剩余的前缀即为映射关系;
+// persist 为 true 时, 映射会被保存并在该后端后续的路径转换中生效
+func Learn(backend, embyPath, openlistPath, source string, persist bool) (LearnedMapping, bool) {
+	from, to, ok := deriveMapping(embyPath, openlistPath)
+	if !ok {
+		return LearnedMapping{}, false
+	}
+	lm := LearnedMapping{Backend: backend, From: from, To: to, Source: source, LearnedAt: time.Now()}
+	if !persist {
+		return lm, true
+	}
+	loadLearned()
+
+	learnedMu.Lock()
+	defer learnedMu.Unlock()
+	key := learnedKey(backend, from)
+	if old, ok := learned[key]; ok && old.To == to {
+		return old, true
+	}
+	learned[key] = lm
+	saveLearned()
+	logs.Success("自动学习到 emby 后端 [%s] 的 emby2openlist 映射: %s => %s, 来源: %s", backend, from, to, source)
+	return lm, true
+}
+
+// Forget 移除 emby 后端 backend 的一个已学习到的映射
+func Forget(backend, from string) {
+	loadLearned()
+	learnedMu.Lock()
+	defer learnedMu.Unlock()
+	key := learnedKey(backend, from)
+	if _, ok := learned[key]; !ok {
+		return
+	}
+	delete(learned, key)
+	saveLearned()
+}
+
+// deriveMapping 去除两个路径公共的后缀片段, 返回剩余的前缀
+func deriveMapping(embyPath, openlistPath string) (string, string, bool) {
+	es := strings.Split(strings.Trim(embyPath, "/"), "/")
+	ls := strings.Split(strings.Trim(openlistPath, "/"), "/")
+
+	common := 0
+	for common < len(es) && common < len(ls) && es[len(es)-1-common] == ls[len(ls)-1-common] {
+		common++
+	}
+
+	// 至少需要保留文件名和一层目录作为公共部分, 且两个路径都需要剩余前缀
+	if common < 2 || common == len(es) || common == len(ls) {
+		return "", "", false
+	}
+
+	from := "/" + strings.Join(es[:len(es)-common], "/")
+	to := "/" + strings.Join(ls[:len(ls)-common], "/")
+	if from == to {
+		return "", "", false
+	}
+	return from, to, true
+}
+
+// hasPathPrefix 判断路径 p 是否以 prefix 为前缀, 前缀必须是完整的路径片段
+func hasPathPrefix(p, prefix string) bool {
+	if !strings.HasPrefix(p, prefix) {
+		return false
+	}
+	return len(p) == len(prefix) || p[len(prefix)] == '/' || strings.HasSuffix(prefix, "/")
+}
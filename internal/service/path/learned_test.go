@@ -0,0 +1,102 @@
+package path
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+func TestDeriveMapping(t *testing.T) {
+	tests := []struct {
+		name         string
+		embyPath     string
+		openlistPath string
+		wantFrom     string
+		wantTo       string
+		wantOk       bool
+	}{
+		{name: "前缀不同", embyPath: "/media/电影/A (2020)/a.mkv", openlistPath: "/115/电影/A (2020)/a.mkv", wantFrom: "/media", wantTo: "/115", wantOk: true},
+		{name: "多层前缀", embyPath: "/mnt/nas/movie/A/a.mkv", openlistPath: "/ali/movie/A/a.mkv", wantFrom: "/mnt/nas", wantTo: "/ali", wantOk: true},
+		{name: "公共部分不足两层", embyPath: "/media/A/a.mkv", openlistPath: "/115/B/a.mkv", wantOk: false},
+		{name: "文件名不同", embyPath: "/media/A/a.mkv", openlistPath: "/115/A/b.mkv", wantOk: false},
+		{name: "emby 路径没有剩余前缀", embyPath: "/A/a.mkv", openlistPath: "/115/A/a.mkv", wantOk: false},
+		{name: "openlist 路径没有剩余前缀", embyPath: "/media/A/a.mkv", openlistPath: "/A/a.mkv", wantOk: false},
+		{name: "路径相同", embyPath: "/115/A/a.mkv", openlistPath: "/115/A/a.mkv", wantOk: false},
+		{name: "忽略首尾斜杠", embyPath: "media/A/a.mkv/", openlistPath: "/115/A/a.mkv", wantFrom: "/media", wantTo: "/115", wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, ok := deriveMapping(tt.embyPath, tt.openlistPath)
+			if from != tt.wantFrom || to != tt.wantTo || ok != tt.wantOk {
+				t.Errorf("deriveMapping() = (%s, %s, %v), want (%s, %s, %v)", from, to, ok, tt.wantFrom, tt.wantTo, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		p      string
+		prefix string
+		want   bool
+	}{
+		{"/media/movie/a.mkv", "/media", true},
+		{"/media", "/media", true},
+		{"/media2/a.mkv", "/media", false},
+		{"/media/a.mkv", "/media/", true},
+		{"/a.mkv", "/", true},
+		{"/med", "/media", false},
+	}
+	for _, tt := range tests {
+		if got := hasPathPrefix(tt.p, tt.prefix); got != tt.want {
+			t.Errorf("hasPathPrefix(%s, %s) = %v, want %v", tt.p, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestSaveLearnedDebounced(t *testing.T) {
+	config.BasePath = t.TempDir()
+	config.C = &config.Config{
+		Path: &config.Path{Discovery: &config.Discovery{AutoLearn: true}},
+		Emby: &config.Emby{Name: "main"},
+	}
+	if err := config.C.Path.Init(); err != nil {
+		t.Fatal(err)
+	}
+	loadLearned()
+	learnedMu.Lock()
+	learned = map[string]LearnedMapping{}
+	learnedMu.Unlock()
+	t.Cleanup(func() {
+		learnedMu.Lock()
+		defer learnedMu.Unlock()
+		if learnedSaveTimer != nil {
+			learnedSaveTimer.Stop()
+			learnedSaveTimer = nil
+		}
+	})
+
+	Learn("main", "/media/电影/A/a.mkv", "/115/电影/A/a.mkv", LearnSourcePlayback, true)
+	Learn("main", "/data/剧集/B/S01/b.mkv", "/ali/剧集/B/S01/b.mkv", LearnSourcePlayback, true)
+	if _, err := os.Stat(learnedFilePath()); !os.IsNotExist(err) {
+		t.Fatalf("映射变更后不应立即写入磁盘, err: %v", err)
+	}
+
+	SaveLearned()
+	bytes, err := os.ReadFile(learnedFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []LearnedMapping
+	if err = json.Unmarshal(bytes, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].From != "/data" || list[1].From != "/media" {
+		t.Errorf("写入的映射错误: %+v", list)
+	}
+	if _, err := os.Stat(learnedFilePath() + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("临时文件未被清理, err: %v", err)
+	}
+}
@@ -22,10 +22,62 @@ type OpenlistPathRes struct {
 
 	// Range 遍历所有 Openlist 根路径生成的子路径
 	Range func() ([]string, error)
+
+	// EmbyPath 移除 mount-path 之后的 emby 路径
+	EmbyPath string
+
+	// Mapped 路径是否已经命中了映射配置 (包括自动学习的映射和已解析的缓存)
+	Mapped bool
+
+	// Size 媒体文件大小, 用于匹配搜索结果, 未知时为 0
+	Size int64
+
+	// Backend 转换路径使用的 emby 后端名称, 已解析路径的缓存和学习到的映射按后端区分
+	Backend string
+}
+
+// Candidates 首次转换的路径请求失败后, 获取候选的 openlist 路径
+//
+// 开启 path.search 配置时, 优先使用搜索接口解析, 解析不到结果再遍历根目录
+func (r OpenlistPathRes) Candidates() ([]string, error) {
+	if config.C.Path.Search.Enable {
+		paths, err := search(r.EmbyPath, r.Size)
+		if err != nil {
+			logs.Warn("搜索 openlist 路径失败: %v, 尝试遍历根目录", err)
+		}
+		if len(paths) > 0 {
+			return paths, nil
+		}
+	}
+	return r.Range()
+}
+
+// Resolved 通过 Candidates 成功请求到资源后, 调用该方法缓存路径并学习映射关系
+func (r OpenlistPathRes) Resolved(openlistPath string) {
+	if openlistPath == r.Path {
+		return
+	}
+	Remember(r.Backend, r.EmbyPath, openlistPath)
+	r.Learn(openlistPath)
+}
+
+// Learn 通过 Range 成功请求到资源后, 调用该方法学习映射关系
+//
+// 仅在开启 path.discovery.auto-learn 配置并且原始路径未命中映射时生效
+func (r OpenlistPathRes) Learn(openlistPath string) {
+	if r.Mapped || !config.C.Path.Discovery.AutoLearn || openlistPath == r.Path {
+		return
+	}
+	Learn(r.Backend, r.EmbyPath, openlistPath, LearnSourcePlayback, true)
 }
 
 // Emby2Openlist Emby 资源路径转 Openlist 资源路径
 func Emby2Openlist(embyPath string) OpenlistPathRes {
+	return Emby2OpenlistOf(config.C.Emby, embyPath)
+}
+
+// Emby2OpenlistOf 使用指定 emby 后端的 mount-path 以及路径映射, 将 embyPath 转换为 openlist 路径
+func Emby2OpenlistOf(e *config.Emby, embyPath string) OpenlistPathRes {
 	pathRoutes := strings.Builder{}
 	pathRoutes.WriteString("[")
 	pathRoutes.WriteString("\n【原始路径】 => " + embyPath)
@@ -36,13 +88,34 @@ func Emby2Openlist(embyPath string) OpenlistPathRes {
 	embyPath = urls.TransferSlash(embyPath)
 	pathRoutes.WriteString("\n\n【Windows 反斜杠转换】 => " + embyPath)
 
-	embyMount := config.C.Emby.MountPath
+	// strm 内容本身就是 openlist 路径, 不做任何映射
+	if e.Strm.IsOpenlistPath(embyPath) {
+		pathRoutes.WriteString("\n\n【strm openlist 路径】 => " + embyPath + "\n]")
+		logs.Tip("embyPath 转换路径: %s", pathRoutes.String())
+		return OpenlistPathRes{
+			Success:  true,
+			Path:     embyPath,
+			Range:    func() ([]string, error) { return nil, nil },
+			EmbyPath: embyPath,
+			Mapped:   true,
+			Backend:  e.Name,
+		}
+	}
+
+	embyMount := e.MountPath
 	openlistFilePath := strings.TrimPrefix(embyPath, embyMount)
 	pathRoutes.WriteString("\n\n【移除 mount-path】 => " + openlistFilePath)
 
-	if mapPath, ok := config.C.Path.MapEmby2Openlist(openlistFilePath); ok {
-		openlistFilePath = mapPath
+	embyRelPath, mapped := openlistFilePath, false
+	if mapPath, ok := e.MapEmby2Openlist(openlistFilePath); ok {
+		openlistFilePath, mapped = mapPath, true
 		pathRoutes.WriteString("\n\n【命中 emby2openlist 映射】 => " + openlistFilePath)
+	} else if mapPath, ok := lookupResolved(e.Name, openlistFilePath); ok {
+		openlistFilePath, mapped = mapPath, true
+		pathRoutes.WriteString("\n\n【命中已解析路径缓存】 => " + openlistFilePath)
+	} else if mapPath, ok := mapLearned(e.Name, openlistFilePath); ok {
+		openlistFilePath, mapped = mapPath, true
+		pathRoutes.WriteString("\n\n【命中自动学习映射】 => " + openlistFilePath)
 	}
 	pathRoutes.WriteString("\n]")
 	logs.Tip("embyPath 转换路径: %s", pathRoutes.String())
@@ -70,10 +143,27 @@ func Emby2Openlist(embyPath string) OpenlistPathRes {
 	}
 
 	return OpenlistPathRes{
-		Success: true,
-		Path:    openlistFilePath,
-		Range:   rangeFunc,
+		Success:  true,
+		Path:     openlistFilePath,
+		Range:    rangeFunc,
+		EmbyPath: embyRelPath,
+		Mapped:   mapped,
+		Backend:  e.Name,
+	}
+}
+
+// StorageCandidates 依次去除 emby 路径开头的 1 ~ maxDrop 层目录,
+// 拼接到每个 openlist 存储挂载路径下, 生成候选的 openlist 路径
+func StorageCandidates(embyPath string, mountPaths []string, maxDrop int) []string {
+	segments := strings.Split(strings.Trim(embyPath, "/"), "/")
+	res := make([]string, 0, len(mountPaths)*maxDrop)
+	for drop := 1; drop <= maxDrop && drop < len(segments); drop++ {
+		rest := strings.Join(segments[drop:], "/")
+		for _, mp := range mountPaths {
+			res = append(res, strings.TrimSuffix(mp, "/")+"/"+rest)
+		}
 	}
+	return res
 }
 
 // SplitFromSecondSlash 找到给定字符串 str 中第二个 '/' 字符的位置
@@ -2,8 +2,11 @@ package path_test
 
 import (
 	"log"
+	"reflect"
 	"testing"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/path"
 )
 
@@ -11,3 +14,119 @@ func TestSplit(t *testing.T) {
 	str := `H:\Phim4K\The.Lockdown.2024.2160p.WEB-DL.DDP5.1.DV.HDR.H.265-FLUX.mkv`
 	log.Println(path.SplitFromSecondSlash(str))
 }
+
+func TestRankSearchResults(t *testing.T) {
+	items := []openlist.FsSearchItem{
+		{Parent: "/115/电影/A (2020)", Name: "a.mkv", Size: 100},
+		{Parent: "/阿里/电影/A (2020)", Name: "a.mkv", Size: 200},
+		{Parent: "/115/其他", Name: "a.mkv", Size: 300},
+		{Parent: "/115/电影", Name: "a.mkv", IsDir: true},
+		{Parent: "/115/电影/A (2020)", Name: "b.mkv", Size: 100},
+	}
+	tests := []struct {
+		name string
+		size int64
+		want []string
+	}{
+		{name: "size-match", size: 200, want: []string{"/阿里/电影/A (2020)/a.mkv"}},
+		{name: "size-miss", size: 400, want: []string{}},
+		{name: "ambiguous", size: 0, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := path.RankSearchResults("/movie/电影/A (2020)/a.mkv", tt.size, items)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RankSearchResults() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	got := path.RankSearchResults("/movie/其他/a.mkv", 0, items)
+	if len(got) != 3 || got[0] != "/115/其他/a.mkv" {
+		t.Errorf("RankSearchResults() = %v", got)
+	}
+}
+
+func TestBackendIsolation(t *testing.T) {
+	config.BasePath = t.TempDir()
+	config.C = &config.Config{
+		Path: &config.Path{Discovery: &config.Discovery{AutoLearn: true}, Search: &config.Search{Enable: true}},
+		Emby: &config.Emby{Name: "a", Host: "http://a"},
+	}
+	if err := config.C.Path.Init(); err != nil {
+		t.Fatal(err)
+	}
+	a := config.C.Emby
+	b := &config.Emby{Name: "b", Host: "http://b"}
+	for _, e := range []*config.Emby{a, b} {
+		if err := e.Init(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := path.Learn("a", "/media/电影/A/a.mkv", "/115/电影/A/a.mkv", path.LearnSourcePlayback, true); !ok {
+		t.Fatal("Learn() 推导映射失败")
+	}
+	path.Remember("a", "/data/b.mkv", "/ali/b.mkv")
+
+	tests := []struct {
+		name       string
+		emby       *config.Emby
+		embyPath   string
+		wantPath   string
+		wantMapped bool
+	}{
+		{name: "命中学习的映射", emby: a, embyPath: "/media/电影/B/b.mkv", wantPath: "/115/电影/B/b.mkv", wantMapped: true},
+		{name: "其他后端不命中学习的映射", emby: b, embyPath: "/media/电影/B/b.mkv", wantPath: "/media/电影/B/b.mkv", wantMapped: false},
+		{name: "命中已解析路径", emby: a, embyPath: "/data/b.mkv", wantPath: "/ali/b.mkv", wantMapped: true},
+		{name: "其他后端不命中已解析路径", emby: b, embyPath: "/data/b.mkv", wantPath: "/data/b.mkv", wantMapped: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := path.Emby2OpenlistOf(tt.emby, tt.embyPath)
+			if res.Path != tt.wantPath || res.Mapped != tt.wantMapped || res.Backend != tt.emby.Name {
+				t.Errorf("Emby2OpenlistOf() = (%s, %v, %s), want (%s, %v, %s)", res.Path, res.Mapped, res.Backend, tt.wantPath, tt.wantMapped, tt.emby.Name)
+			}
+		})
+	}
+}
+
+func TestStorageCandidates(t *testing.T) {
+	tests := []struct {
+		name       string
+		embyPath   string
+		mountPaths []string
+		maxDrop    int
+		want       []string
+	}{
+		{
+			name:       "去除一到两层目录",
+			embyPath:   "/media/电影/A/a.mkv",
+			mountPaths: []string{"/115", "/ali/"},
+			maxDrop:    2,
+			want:       []string{"/115/电影/A/a.mkv", "/ali/电影/A/a.mkv", "/115/A/a.mkv", "/ali/A/a.mkv"},
+		},
+		{
+			name:       "至少保留文件名",
+			embyPath:   "/media/a.mkv",
+			mountPaths: []string{"/115"},
+			maxDrop:    3,
+			want:       []string{"/115/a.mkv"},
+		},
+		{
+			name:       "没有存储",
+			embyPath:   "/media/a.mkv",
+			mountPaths: nil,
+			maxDrop:    2,
+			want:       []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := path.StorageCandidates(tt.embyPath, tt.mountPaths, tt.maxDrop)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StorageCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
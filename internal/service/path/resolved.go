@@ -0,0 +1,133 @@
+package path
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// ResolvedFileName 已解析路径的持久化文件名称
+const ResolvedFileName = "emby2openlist-resolved.json"
+
+// resolvedSaveDelay 已解析路径变更后延迟写入磁盘的时间
+const resolvedSaveDelay = time.Second * 5
+
+// resolved 已解析成功的路径, 按 emby 后端名称分组,
+// 组内 key 为移除 mount-path 后的 emby 路径, value 为 openlist 路径
+var (
+	resolved     = map[string]map[string]string{}
+	resolvedMu   sync.RWMutex
+	resolvedOnce sync.Once
+
+	// resolvedDirty 是否有尚未写入磁盘的变更
+	resolvedDirty bool
+	// resolvedSaveTimer 延迟写入的定时器, 为 nil 表示没有待执行的写入
+	resolvedSaveTimer *time.Timer
+)
+
+// resolvedFilePath 已解析路径持久化文件的绝对路径
+func resolvedFilePath() string {
+	return filepath.Join(config.BasePath, ResolvedFileName)
+}
+
+// loadResolved 从磁盘中加载已解析的路径, 只会执行一次
+func loadResolved() {
+	resolvedOnce.Do(func() {
+		bytes, err := os.ReadFile(resolvedFilePath())
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logs.Warn("读取已解析的 openlist 路径失败: %v", err)
+			}
+			return
+		}
+
+		m := map[string]map[string]string{}
+		if err = json.Unmarshal(bytes, &m); err != nil {
+			// 兼容未区分 emby 后端的旧格式, 归属于顶层 emby 配置
+			legacy := map[string]string{}
+			if json.Unmarshal(bytes, &legacy) != nil {
+				logs.Warn("解析已缓存的 openlist 路径失败: %v", err)
+				return
+			}
+			m = map[string]map[string]string{config.C.Emby.Name: legacy}
+		}
+
+		resolvedMu.Lock()
+		defer resolvedMu.Unlock()
+		resolved = m
+		cnt := 0
+		for _, paths := range m {
+			cnt += len(paths)
+		}
+		logs.Info("已加载 %d 条已解析的 openlist 路径", cnt)
+	})
+}
+
+// saveResolved 延迟将已解析的路径写入磁盘, 合并短时间内的多次变更, 调用方需持有写锁
+func saveResolved() {
+	resolvedDirty = true
+	if resolvedSaveTimer == nil {
+		resolvedSaveTimer = time.AfterFunc(resolvedSaveDelay, SaveResolved)
+	}
+}
+
+// SaveResolved 将尚未写入的已解析路径立即写入磁盘, 在程序退出前调用
+//
+// 先写入临时文件再重命名, 避免写入过程中退出导致文件损坏
+func SaveResolved() {
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+	resolvedSaveTimer = nil
+	if !resolvedDirty {
+		return
+	}
+	resolvedDirty = false
+
+	bytes, err := json.MarshalIndent(resolved, "", "  ")
+	if err == nil {
+		fp := resolvedFilePath()
+		if err = os.WriteFile(fp+".tmp", bytes, 0644); err == nil {
+			err = os.Rename(fp+".tmp", fp)
+		}
+	}
+	if err != nil {
+		logs.Warn("保存已解析的 openlist 路径失败: %v", err)
+	}
+}
+
+// lookupResolved 查询 emby 后端 backend 的 emby 路径已解析的 openlist 路径
+func lookupResolved(backend, embyPath string) (string, bool) {
+	if !config.C.Path.Search.Enable {
+		return "", false
+	}
+	loadResolved()
+
+	resolvedMu.RLock()
+	defer resolvedMu.RUnlock()
+	p, ok := resolved[backend][embyPath]
+	return p, ok
+}
+
+// Remember 缓存 emby 后端 backend 的 emby 路径解析成功的 openlist 路径
+func Remember(backend, embyPath, openlistPath string) {
+	if !config.C.Path.Search.Enable {
+		return
+	}
+	loadResolved()
+
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+	if resolved[backend][embyPath] == openlistPath {
+		return
+	}
+	if resolved[backend] == nil {
+		resolved[backend] = map[string]string{}
+	}
+	resolved[backend][embyPath] = openlistPath
+	saveResolved()
+}
@@ -0,0 +1,55 @@
+package path
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+func TestSaveResolvedDebounced(t *testing.T) {
+	config.BasePath = t.TempDir()
+	config.C = &config.Config{
+		Path: &config.Path{Search: &config.Search{Enable: true}},
+		Emby: &config.Emby{Name: "main"},
+	}
+	if err := config.C.Path.Init(); err != nil {
+		t.Fatal(err)
+	}
+	loadResolved()
+	resolvedMu.Lock()
+	resolved = map[string]map[string]string{}
+	resolvedMu.Unlock()
+	t.Cleanup(func() {
+		resolvedMu.Lock()
+		defer resolvedMu.Unlock()
+		if resolvedSaveTimer != nil {
+			resolvedSaveTimer.Stop()
+			resolvedSaveTimer = nil
+		}
+	})
+
+	Remember("main", "/media/a.mkv", "/115/a.mkv")
+	Remember("main", "/media/b.mkv", "/115/b.mkv")
+	Remember("other", "/media/a.mkv", "/ali/a.mkv")
+	if _, err := os.Stat(resolvedFilePath()); !os.IsNotExist(err) {
+		t.Fatalf("路径变更后不应立即写入磁盘, err: %v", err)
+	}
+
+	SaveResolved()
+	bytes, err := os.ReadFile(resolvedFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]map[string]string
+	if err = json.Unmarshal(bytes, &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m["main"]) != 2 || m["other"]["/media/a.mkv"] != "/ali/a.mkv" {
+		t.Errorf("写入的路径错误: %v", m)
+	}
+	if _, err := os.Stat(resolvedFilePath() + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("临时文件未被清理, err: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package path
+
+import (
+	"fmt"
+	"net/http"
+	stdpath "path"
+	"sort"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// search 使用 openlist 搜索接口, 根据文件名查找 emby 路径对应的 openlist 路径
+func search(embyPath string, size int64) ([]string, error) {
+	cfg := config.C.Path.Search
+	name := stdpath.Base(embyPath)
+	res := openlist.FetchFsSearch(cfg.Parent, name, cfg.MaxResults, nil)
+	if res.Code != http.StatusOK {
+		return nil, fmt.Errorf("请求 openlist fs search 接口异常: %s", res.Msg)
+	}
+
+	paths := RankSearchResults(embyPath, size, res.Data.Content)
+	logs.Info("openlist 搜索 [%s], 结果数: %d, 匹配数: %d", name, len(res.Data.Content), len(paths))
+	return paths, nil
+}
+
+// RankSearchResults 从搜索结果中筛选出与 emby 路径匹配的 openlist 路径, 按匹配度从高到低排序
+//
+// 文件名必须完全一致; 双方大小都已知时, 大小必须一致;
+// 匹配度为两者父目录从后往前连续相同的层级数,
+// 大小未知且无法通过父目录区分多个结果时, 认为结果不可信, 返回空
+func RankSearchResults(embyPath string, size int64, items []openlist.FsSearchItem) []string {
+	dir, name := stdpath.Split(embyPath)
+	embyDirs := splitSegments(dir)
+
+	type scored struct {
+		path  string
+		score int
+	}
+	hits := make([]scored, 0)
+	for _, item := range items {
+		if item.IsDir || item.Name != name {
+			continue
+		}
+		if size > 0 && item.Size > 0 && size != item.Size {
+			continue
+		}
+		score := commonSuffix(embyDirs, splitSegments(item.Parent))
+		hits = append(hits, scored{path: stdpath.Join(item.Parent, item.Name), score: score})
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+
+	if size <= 0 && len(hits) > 1 && hits[0].score == hits[1].score {
+		return nil
+	}
+
+	paths := make([]string, len(hits))
+	for i, h := range hits {
+		paths[i] = h.path
+	}
+	return paths
+}
+
+// splitSegments 切割路径, 返回所有非空的路径片段
+func splitSegments(p string) []string {
+	return strings.FieldsFunc(p, func(r rune) bool { return r == '/' })
+}
+
+// commonSuffix 计算两个片段数组从后往前连续相同的片段数
+func commonSuffix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
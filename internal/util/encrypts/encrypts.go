@@ -1,8 +1,15 @@
 package encrypts
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
 	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
 )
 
 // Md5Hash 对字符串 raw 进行 md5 哈希运算, 返回十六进制
@@ -11,3 +18,22 @@ func Md5Hash(raw string) string {
 	hash.Write([]byte(raw))
 	return hex.EncodeToString(hash.Sum(nil))
 }
+
+// Totp 根据 base32 编码的密钥生成 t 时刻的 6 位 TOTP 动态验证码 (RFC 6238, 30 秒步长)
+func Totp(secret string, t time.Time) (string, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(secret), " ", ""))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return "", fmt.Errorf("otp 密钥解析失败: %v", err)
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(t.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", code%1000000), nil
+}
@@ -0,0 +1,35 @@
+package encrypts_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/encrypts"
+)
+
+func TestTotp(t *testing.T) {
+	// RFC 6238 附录 B 测试向量 (取后 6 位)
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tt := range tests {
+		got, err := encrypts.Totp(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Totp(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	if _, err := encrypts.Totp("not-base32!", time.Now()); err == nil {
+		t.Errorf("Totp() expect error, got nil")
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 )
 
 const (
@@ -70,7 +71,7 @@ func MapBody(body map[string]any) io.ReadCloser {
 	}
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
-		log.Printf("MapBody 转换失败, body: %v, err : %v", body, err)
+		logs.Warn("MapBody 转换失败, body: %v, err : %v", body, err)
 		return nil
 	}
 	return io.NopCloser(bytes.NewBuffer(bodyBytes))
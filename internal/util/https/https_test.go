@@ -1,11 +1,16 @@
 package https_test
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"path"
 	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 )
 
 func TestRelativeRedirect(t *testing.T) {
@@ -19,3 +24,36 @@ func TestRelativeRedirect(t *testing.T) {
 	loc = fmt.Sprintf("%s://%s%s/%s", req.URL.Scheme, req.URL.Host, dirPath, loc)
 	log.Println(loc)
 }
+
+func TestRequestIdPropagation(t *testing.T) {
+	got := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("X-Request-Id")
+	}))
+	defer srv.Close()
+
+	ctx := logs.WithRequest(context.Background(), "req-1", nil)
+	header := http.Header{"Accept": {"*/*"}}
+	resp, err := https.Get(srv.URL).Header(header).Context(ctx).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if id := <-got; id != "req-1" {
+		t.Errorf("上游收到的请求 id = %q, want req-1", id)
+	}
+	if header.Get("X-Request-Id") != "" {
+		t.Error("不应修改调用方传入的请求头")
+	}
+
+	// 请求头中已携带请求 id 时保持不变
+	header.Set("X-Request-Id", "client-id")
+	resp, err = https.Get(srv.URL).Header(header).Context(ctx).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if id := <-got; id != "client-id" {
+		t.Errorf("上游收到的请求 id = %q, want client-id", id)
+	}
+}
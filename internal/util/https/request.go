@@ -2,11 +2,16 @@ package https
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"path"
 	"strings"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 )
 
 type RequestHolder struct {
@@ -28,6 +33,12 @@ type RequestHolder struct {
 
 	// closeConn 请求结束后是否关闭连接
 	closeConn bool
+
+	// timeout 整个请求的超时时间 (包括读取响应体), 为 0 时不限制
+	timeout time.Duration
+
+	// ctx 发起请求的上下文, 用于向上游传递请求 id
+	ctx context.Context
 }
 
 // Request 构造自定义请求
@@ -92,6 +103,20 @@ func (r *RequestHolder) CloseConn() *RequestHolder {
 	return r
 }
 
+// Timeout 设置整个请求的超时时间, 包括重定向以及读取响应体
+func (r *RequestHolder) Timeout(timeout time.Duration) *RequestHolder {
+	r.timeout = timeout
+	return r
+}
+
+// Context 设置发起请求的上下文, ctx 绑定了请求 id 时, 通过 X-Request-Id 请求头传递给上游
+//
+// 响应可能在 ctx 结束后才被读取 (如异步预取), 请求不会随 ctx 一起取消
+func (r *RequestHolder) Context(ctx context.Context) *RequestHolder {
+	r.ctx = ctx
+	return r
+}
+
 // Do 发起请求 自动重定向
 func (r *RequestHolder) Do() (*http.Response, error) {
 	r.redirect = true
@@ -116,6 +141,11 @@ func (r *RequestHolder) DoRedirect() (string, *http.Response, error) {
 // 如果一个请求有多次重定向并且进行了 autoRedirect,
 // 则最后一次重定向的 url 会作为第一个参数返回
 func (r *RequestHolder) execute() (string, *http.Response, error) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if r.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+	}
+
 	var inner func(method, url string, header http.Header, body io.ReadCloser, autoRedirect bool, depth int) (string, *http.Response, error)
 	inner = func(method, url string, header http.Header, body io.ReadCloser, autoRedirect bool, depth int) (string, *http.Response, error) {
 		if depth >= MaxRedirectDepth {
@@ -130,7 +160,7 @@ func (r *RequestHolder) execute() (string, *http.Response, error) {
 				return "", nil, fmt.Errorf("读取请求体失败: %v", err)
 			}
 		}
-		req, err := http.NewRequest(method, url, bytes.NewBuffer(bodyBytes))
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(bodyBytes))
 		if err != nil {
 			return "", nil, fmt.Errorf("创建请求失败: %v", err)
 		}
@@ -165,5 +195,33 @@ func (r *RequestHolder) execute() (string, *http.Response, error) {
 		return inner(method, loc, header, newBody, autoRedirect, depth+1)
 	}
 
-	return inner(r.method, r.url, r.header, r.body, r.redirect, 0)
+	header := r.header
+	if id := logs.RequestIdOf(r.ctx); id != "" && header.Get(constant.RequestIdHeader) == "" {
+		if header == nil {
+			header = make(http.Header)
+		} else {
+			header = header.Clone()
+		}
+		header.Set(constant.RequestIdHeader, id)
+	}
+
+	finalUrl, resp, err := inner(r.method, r.url, header, r.body, r.redirect, 0)
+	if err != nil || resp == nil {
+		cancel()
+		return finalUrl, resp, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return finalUrl, resp, err
+}
+
+// cancelBody 响应体关闭时, 释放请求的 context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close 关闭响应体
+func (cb *cancelBody) Close() error {
+	defer cb.cancel()
+	return cb.ReadCloser.Close()
 }
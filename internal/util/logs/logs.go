@@ -1,49 +1,90 @@
 package logs
 
 import (
+	"context"
 	"fmt"
-	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 )
 
+// Logger 输出附带请求信息的日志
+type Logger struct {
+	ri *requestInfo
+}
+
+// Ctx 获取 ctx 绑定的请求对应的日志输出器, ctx 未绑定请求时与包级别的日志函数一致
+func Ctx(ctx context.Context) Logger {
+	return Logger{ri: requestOf(ctx)}
+}
+
 // Info 输出蓝色 Info 日志
 func Info(format string, v ...any) {
-	s := fmt.Sprintf("[INFO] "+format, v...)
-	fmt.Println(now() + colors.ToBlue(s))
+	Logger{}.Info(format, v...)
 }
 
 // Success 输出绿色 Success 日志
 func Success(format string, v ...any) {
-	s := fmt.Sprintf("[SUCCESS] "+format, v...)
-	fmt.Println(now() + colors.ToGreen(s))
+	Logger{}.Success(format, v...)
 }
 
 // Warn 输出黄色 Warn 日志
 func Warn(format string, v ...any) {
-	s := fmt.Sprintf("[WARN] "+format, v...)
-	fmt.Println(now() + colors.ToYellow(s))
+	Logger{}.Warn(format, v...)
 }
 
 // Error 输出红色 Error 日志
 func Error(format string, v ...any) {
-	s := fmt.Sprintf("[ERROR] "+format, v...)
-	fmt.Println(now() + colors.ToRed(s))
+	Logger{}.Error(format, v...)
 }
 
-// Tip 输出灰色 Tip 日志
+// Tip 输出灰色 Tip 日志, 属于 debug 级别
 func Tip(format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	fmt.Println(now() + colors.ToGray(s))
+	Logger{}.Tip(format, v...)
 }
 
 // Progress 输出紫色 Progress 日志
 func Progress(format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	fmt.Println(now() + colors.ToPurple(s))
+	output(nil, LevelInfo, colors.Purple, "", fmt.Sprintf(format, v...), nil)
+}
+
+// Print 以指定的级别和颜色输出日志
+func Print(level Level, c colors.C, format string, v ...any) {
+	output(nil, level, c, "", fmt.Sprintf(format, v...), nil)
+}
+
+// Access 输出 info 级别的访问日志
+//
+// 文本格式下输出 line, json 格式下输出 msg 为 access 的结构化字段
+func Access(line string, fields ...Field) {
+	Logger{}.Access(line, fields...)
+}
+
+// Info 输出蓝色 Info 日志
+func (l Logger) Info(format string, v ...any) {
+	output(l.ri, LevelInfo, colors.Blue, "[INFO] ", fmt.Sprintf(format, v...), nil)
+}
+
+// Success 输出绿色 Success 日志
+func (l Logger) Success(format string, v ...any) {
+	output(l.ri, LevelInfo, colors.Green, "[SUCCESS] ", fmt.Sprintf(format, v...), nil)
+}
+
+// Warn 输出黄色 Warn 日志
+func (l Logger) Warn(format string, v ...any) {
+	output(l.ri, LevelWarn, colors.Yellow, "[WARN] ", fmt.Sprintf(format, v...), nil)
+}
+
+// Error 输出红色 Error 日志
+func (l Logger) Error(format string, v ...any) {
+	output(l.ri, LevelError, colors.Red, "[ERROR] ", fmt.Sprintf(format, v...), nil)
+}
+
+// Tip 输出灰色 Tip 日志, 属于 debug 级别
+func (l Logger) Tip(format string, v ...any) {
+	output(l.ri, LevelDebug, colors.Gray, "", fmt.Sprintf(format, v...), nil)
 }
 
-// now 返回当前时间戳
-func now() string {
-	return time.Now().Format("2006-01-02 15:04:05") + " "
+// Access 输出 info 级别的访问日志
+func (l Logger) Access(line string, fields ...Field) {
+	output(l.ri, LevelInfo, "", "", line, append([]Field{F("msg", "access")}, fields...))
 }
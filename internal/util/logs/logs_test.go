@@ -0,0 +1,154 @@
+package logs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+func TestLevelAndJson(t *testing.T) {
+	var buf bytes.Buffer
+	logs.Setup(logs.Options{Level: logs.LevelInfo, Json: true, File: &buf})
+	defer logs.Setup(logs.Options{Level: logs.LevelDebug})
+
+	logs.Tip("不会输出的 debug 日志")
+	logs.Warn("警告: %d", 1)
+	logs.Access("访问日志", logs.F("status", 302), logs.F("uri", "/emby/videos/1/stream?a=<b>"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("期望输出 2 行日志, 实际: %q", lines)
+	}
+
+	var warn map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &warn); err != nil {
+		t.Fatal(err)
+	}
+	if warn["level"] != "warn" || warn["msg"] != "警告: 1" {
+		t.Fatalf("warn 日志内容错误: %s", lines[0])
+	}
+
+	var access map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &access); err != nil {
+		t.Fatal(err)
+	}
+	if access["msg"] != "access" || access["status"] != float64(302) || access["uri"] != "/emby/videos/1/stream?a=<b>" {
+		t.Fatalf("access 日志内容错误: %s", lines[1])
+	}
+}
+
+func TestRotateWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ge2o.log")
+	rw, err := logs.NewRotateWriter(path, 1, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rw.Close()
+
+	// 同一秒内连续滚动多次, 备份文件不能互相覆盖
+	chunk := bytes.Repeat([]byte("a"), 600*1024)
+	for range 4 {
+		if _, err := rw.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("期望滚动出 4 个日志文件, 实际: %d", len(entries))
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() != int64(len(chunk)) {
+		t.Fatalf("当前日志文件大小错误: %d", stat.Size())
+	}
+}
+
+func TestRequestContextAndTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logs.Setup(logs.Options{Level: logs.LevelInfo, File: &buf})
+	defer logs.Setup(logs.Options{Level: logs.LevelDebug})
+
+	trace := logs.NewTrace(2)
+	ctx := logs.WithRequest(context.Background(), "req-1", trace)
+	logs.Ctx(ctx).Tip("路径转换: %s", "/a => /b")
+	logs.Ctx(ctx).Info("请求 openlist")
+
+	done := make(chan struct{})
+	go func() {
+		// 其他 goroutine 持有 ctx 时同样附带请求 id
+		logs.Ctx(ctx).Warn("异步任务")
+		close(done)
+	}()
+	<-done
+	logs.Info("未携带请求上下文")
+	logs.Ctx(context.Background()).Info("请求结束")
+
+	if got := logs.RequestIdOf(ctx); got != "req-1" {
+		t.Errorf("RequestIdOf() = %s, want req-1", got)
+	}
+	out := buf.String()
+	for _, want := range []string{"[req-1] [INFO] 请求 openlist", "[req-1] [WARN] 异步任务"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("日志中缺少: %s\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"[req-1] [INFO] 未携带请求上下文", "[req-1] [INFO] 请求结束", "路径转换"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("日志中不应包含: %s\n%s", unwanted, out)
+		}
+	}
+
+	lines := trace.Lines()
+	want := []string{"[debug] 路径转换: /a => /b", "[info] 请求 openlist", "... 1 more lines"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("追踪记录错误: %q", lines)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	defer logs.Setup(logs.Options{Level: logs.LevelDebug})
+	logs.Setup(logs.Options{Level: logs.LevelDebug, RedactPatterns: []*regexp.Regexp{regexp.MustCompile(`secret-\w+`)}})
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/emby/videos/1/stream?api_key=abc123&Static=true", "/emby/videos/1/stream?api_key=***&Static=true"},
+		{"/Items/1/PlaybackInfo?X-Emby-Token=abc&ApiKey=def", "/Items/1/PlaybackInfo?X-Emby-Token=***&ApiKey=***"},
+		{"https://oss.example.com/a.mp4?sign=1700000000-abc-0-md5", "https://oss.example.com/a.mp4?sign=***"},
+		{"[CDN Auth] sign=1700000000-abc, ts=1700000000", "[CDN Auth] sign=***, ts=1700000000"},
+		{"ItemInfo{Id: [1], ApiKey: [abc123], ApiKeyType: [query]}", "ItemInfo{Id: [1], ApiKey: [***], ApiKeyType: [query]}"},
+		{`{"AccessToken":"abc","User":"u"}`, `{"AccessToken":"***","User":"u"}`},
+		{`MediaBrowser Client="Emby Web", Token="abc123"`, `MediaBrowser Client="Emby Web", Token="***"`},
+		{"X-Emby-Token: abc123", "X-Emby-Token: ***"},
+		{"Range=bytes=0-;X-Emby-Token=abc;", "Range=bytes=0-;X-Emby-Token=***;"},
+		{"\x1b[33m?api_key=abc\x1b[0m", "\x1b[33m?api_key=***\x1b[0m"},
+		{`Post "https://api.telegram.org/bot123456:AA-bc_d/sendMessage": EOF`, `Post "https://api.telegram.org/bot***/sendMessage": EOF`},
+		{"custom secret-abc here", "custom *** here"},
+		{"/emby/Items/1/Images/Primary?tag=abc", "/emby/Items/1/Images/Primary?tag=abc"},
+	}
+	for _, tt := range tests {
+		if got := logs.Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	logs.Setup(logs.Options{Level: logs.LevelDebug, DisableRedact: true})
+	if got := logs.Redact("?api_key=abc"); got != "?api_key=abc" {
+		t.Errorf("关闭脱敏后不应修改内容: %s", got)
+	}
+}
@@ -0,0 +1,193 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+)
+
+// Level 日志级别
+type Level int
+
+// 日志级别, 低于配置级别的日志不会输出
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelNames 日志级别名称
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// String 日志级别名称
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel 根据名称解析日志级别
+func ParseLevel(name string) (Level, error) {
+	for l, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return l, nil
+		}
+	}
+	return LevelDebug, fmt.Errorf("不支持的日志级别: %s, 可选值: debug, info, warn, error", name)
+}
+
+// Field 结构化日志的字段
+type Field struct {
+	Key   string
+	Value any
+}
+
+// F 构造一个结构化日志字段
+func F(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Options 日志输出配置
+type Options struct {
+	Level Level     // 最低输出级别
+	Json  bool      // 是否以 json 格式输出
+	File  io.Writer // 额外输出的日志文件, 为空时只输出到控制台
+
+	DisableRedact  bool             // 是否关闭敏感信息脱敏
+	RedactPatterns []*regexp.Regexp // 在默认规则之外额外的脱敏规则
+}
+
+var (
+	// opts 当前的日志输出配置
+	opts = Options{Level: LevelDebug}
+
+	// outMu 保证日志按行完整输出
+	outMu sync.Mutex
+
+	// ansiReg 匹配 ANSI 颜色控制符
+	ansiReg = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// Setup 设置日志输出配置
+func Setup(o Options) {
+	outMu.Lock()
+	defer outMu.Unlock()
+	opts = o
+}
+
+// Enabled 判断指定级别的日志是否会被输出
+func Enabled(level Level) bool {
+	outMu.Lock()
+	defer outMu.Unlock()
+	return level >= opts.Level
+}
+
+// output 按照配置格式化并输出一行日志, ri 不为空时附带请求信息
+//
+// 文本格式下, 控制台输出带颜色的日志, 文件中输出去除颜色的日志
+func output(ri *requestInfo, level Level, c colors.C, prefix, msg string, fields []Field) {
+	now := time.Now()
+	// 日志不会输出也不会被追踪时, 跳过脱敏
+	traced := ri != nil && ri.trace != nil
+	if !traced && !Enabled(level) {
+		return
+	}
+
+	msg, fields = Redact(msg), redactFields(fields)
+	if traced && fields == nil {
+		ri.trace.add(level, msg)
+	}
+
+	outMu.Lock()
+	defer outMu.Unlock()
+	if level < opts.Level {
+		return
+	}
+
+	if ri != nil {
+		if opts.Json {
+			fields = append([]Field{F("request_id", ri.id)}, fields...)
+		} else {
+			prefix = "[" + ri.id + "] " + prefix
+		}
+	}
+
+	if opts.Json {
+		line := jsonLine(now, level, ansiReg.ReplaceAllString(msg, ""), fields)
+		os.Stdout.Write(line)
+		if opts.File != nil {
+			opts.File.Write(line)
+		}
+		return
+	}
+
+	text := prefix + msg
+	if c != "" {
+		text = colors.WrapColor(c, text)
+	}
+	line := now.Format("2006-01-02 15:04:05") + " " + text + "\n"
+	io.WriteString(os.Stdout, line)
+	if opts.File != nil {
+		io.WriteString(opts.File, ansiReg.ReplaceAllString(line, ""))
+	}
+}
+
+// redactFields 隐藏结构化字段中字符串值的敏感信息
+func redactFields(fields []Field) []Field {
+	if len(fields) == 0 {
+		return fields
+	}
+	res := make([]Field, len(fields))
+	for i, f := range fields {
+		if str, ok := f.Value.(string); ok {
+			f.Value = Redact(str)
+		}
+		res[i] = f
+	}
+	return res
+}
+
+// jsonLine 生成一行 json 格式的日志, 字段按传入顺序输出, 字段中不包含 msg 时输出 msg
+func jsonLine(now time.Time, level Level, msg string, fields []Field) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeJsonValue(&buf, now.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJsonValue(&buf, level.String())
+	if !slices.ContainsFunc(fields, func(f Field) bool { return f.Key == "msg" }) {
+		buf.WriteString(`,"msg":`)
+		writeJsonValue(&buf, msg)
+	}
+	for _, f := range fields {
+		buf.WriteByte(',')
+		writeJsonValue(&buf, f.Key)
+		buf.WriteByte(':')
+		writeJsonValue(&buf, f.Value)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// writeJsonValue 将值序列化为 json 写入缓冲区, 不转义 html 字符
+func writeJsonValue(buf *bytes.Buffer, v any) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		enc.Encode(fmt.Sprint(v))
+	}
+	// Encode 会在末尾追加换行符
+	buf.Truncate(buf.Len() - 1)
+}
@@ -0,0 +1,84 @@
+package logs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RedactMask 敏感信息被替换成的内容
+const RedactMask = "***"
+
+// secretNames 敏感的参数名称, 用于生成默认的脱敏规则
+var secretNames = strings.Join([]string{
+	"api_key", "apikey", "x-emby-token", "x-mediabrowser-token", "x-ge2o-admin-token", "admin_token",
+	"access_token", "accesstoken", "token", "password", "sign", "auth_key", "signature",
+	"security-token", "x-oss-signature", "x-oss-credential", "x-oss-security-token",
+	"x-amz-signature", "x-amz-credential", "x-amz-security-token",
+}, "|")
+
+// DefaultRedactPatterns 默认的脱敏规则, 规则中所有捕获组匹配到的内容会被替换成 RedactMask
+var DefaultRedactPatterns = []*regexp.Regexp{
+	// url 参数或 key=value 形式: ?api_key=xxx, &sign=xxx, sign=xxx
+	regexp.MustCompile(`(?i)(?:^|[?&;,\s])(?:` + secretNames + `)=([^&;\s"',\]\x1b]+)`),
+	// 结构体格式化形式: ApiKey: [xxx]
+	regexp.MustCompile(`(?i)\b(?:` + secretNames + `):\s*\[([^\]]+)\]`),
+	// json 形式: "AccessToken":"xxx"
+	regexp.MustCompile(`(?i)"(?:` + secretNames + `)"\s*:\s*"([^"]+)"`),
+	// 请求头形式: X-Emby-Token: xxx
+	regexp.MustCompile(`(?i)\b(?:x-emby-token|x-mediabrowser-token|x-ge2o-admin-token|authorization)\s*:\s*([^\s",\x1b]+)`),
+	// emby 认证请求头: Token="xxx"
+	regexp.MustCompile(`(?i)\btoken="([^"]+)"`),
+	// Telegram 机器人接口地址: /bot123456:xxx/sendMessage
+	regexp.MustCompile(`/bot(\d+:[\w-]+)`),
+}
+
+// Redact 使用当前配置的脱敏规则隐藏字符串中的敏感信息
+func Redact(s string) string {
+	outMu.Lock()
+	patterns, enabled := opts.RedactPatterns, !opts.DisableRedact
+	outMu.Unlock()
+	if !enabled {
+		return s
+	}
+	return redact(s, patterns)
+}
+
+// redact 依次应用默认规则以及额外规则, 替换捕获组的内容,
+// 规则中没有捕获组时替换整个匹配的内容
+func redact(s string, extra []*regexp.Regexp) string {
+	for _, patterns := range [][]*regexp.Regexp{DefaultRedactPatterns, extra} {
+		for _, reg := range patterns {
+			s = maskMatches(s, reg)
+		}
+	}
+	return s
+}
+
+// maskMatches 替换字符串中单个规则匹配的内容
+func maskMatches(s string, reg *regexp.Regexp) string {
+	matches := reg.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		// 没有捕获组时, 替换整个匹配内容
+		groups := m[2:]
+		if len(groups) == 0 {
+			groups = m[:2]
+		}
+		for i := 0; i+1 < len(groups); i += 2 {
+			start, end := groups[i], groups[i+1]
+			if start < last || start == end {
+				continue
+			}
+			sb.WriteString(s[last:start])
+			sb.WriteString(RedactMask)
+			last = end
+		}
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
@@ -0,0 +1,78 @@
+package logs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// requestInfo 请求上下文中携带的请求信息
+type requestInfo struct {
+	id    string
+	trace *Trace
+}
+
+// requestKey 请求信息在 context 中的 key
+type requestKey struct{}
+
+// WithRequest 将请求 id 绑定到 ctx 中, 通过 Ctx(ctx) 输出的日志都会附带上请求 id;
+// trace 不为空时, 同时将日志 (包括低于配置级别的日志) 记录到 trace 中
+//
+// 直接调用包级别的日志函数 (如 Info) 输出的日志不附带请求 id, 也不会记录到 trace 中
+func WithRequest(ctx context.Context, id string, trace *Trace) context.Context {
+	return context.WithValue(ctx, requestKey{}, &requestInfo{id: id, trace: trace})
+}
+
+// RequestIdOf 获取 ctx 中绑定的请求 id, 未绑定时返回空串
+func RequestIdOf(ctx context.Context) string {
+	if ri := requestOf(ctx); ri != nil {
+		return ri.id
+	}
+	return ""
+}
+
+// requestOf 获取 ctx 中绑定的请求信息
+func requestOf(ctx context.Context) *requestInfo {
+	if ctx == nil {
+		return nil
+	}
+	ri, _ := ctx.Value(requestKey{}).(*requestInfo)
+	return ri
+}
+
+// Trace 记录单个请求处理过程中输出的日志
+type Trace struct {
+	mu      sync.Mutex
+	lines   []string
+	limit   int
+	dropped int
+}
+
+// NewTrace 创建请求追踪记录, 最多记录 limit 行日志
+func NewTrace(limit int) *Trace {
+	return &Trace{limit: limit}
+}
+
+// add 记录一行日志, 超出上限的日志只计数
+func (t *Trace) add(level Level, msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.lines) >= t.limit {
+		t.dropped++
+		return
+	}
+	msg = strings.Join(strings.Fields(ansiReg.ReplaceAllString(msg, "")), " ")
+	t.lines = append(t.lines, "["+level.String()+"] "+msg)
+}
+
+// Lines 获取已记录的日志, 超出上限时最后一行提示被丢弃的行数
+func (t *Trace) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := append([]string(nil), t.lines...)
+	if t.dropped > 0 {
+		res = append(res, "... "+strconv.Itoa(t.dropped)+" more lines")
+	}
+	return res
+}
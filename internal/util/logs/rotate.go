@@ -0,0 +1,137 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeLayout 滚动后的日志文件名中的时间格式, 精确到纳秒避免同一秒内多次滚动时文件名冲突
+const backupTimeLayout = "20060102-150405.000000000"
+
+// RotateWriter 按大小滚动的日志文件
+//
+// 文件大小超过上限时, 将当前文件重命名为 name-<时间>.ext 并重新创建,
+// 同时清理超过保留天数的旧文件
+type RotateWriter struct {
+	path    string
+	maxSize int64
+	maxAge  time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotateWriter 创建滚动日志文件, maxSizeMB 为单个文件的大小上限,
+// maxAgeDays 为旧文件的保留天数, 为 0 时不清理
+func NewRotateWriter(path string, maxSizeMB, maxAgeDays int) (*RotateWriter, error) {
+	rw := &RotateWriter{
+		path:    path,
+		maxSize: int64(maxSizeMB) * 1024 * 1024,
+		maxAge:  time.Duration(maxAgeDays) * 24 * time.Hour,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %v", err)
+	}
+	if err := rw.open(); err != nil {
+		return nil, err
+	}
+	go rw.cleanExpired()
+	return rw, nil
+}
+
+// Write 实现 io.Writer 接口
+func (rw *RotateWriter) Write(p []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.maxSize > 0 && rw.size > 0 && rw.size+int64(len(p)) > rw.maxSize {
+		// 滚动失败时, 只要原文件仍然可用就继续写入
+		if err := rw.rotate(); err != nil && rw.file == nil {
+			return 0, err
+		}
+	}
+	n, err := rw.file.Write(p)
+	rw.size += int64(n)
+	return n, err
+}
+
+// Close 关闭日志文件
+func (rw *RotateWriter) Close() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.file == nil {
+		return nil
+	}
+	return rw.file.Close()
+}
+
+// open 以追加模式打开日志文件
+func (rw *RotateWriter) open() error {
+	file, err := os.OpenFile(rw.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %v", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取日志文件信息失败: %v", err)
+	}
+	rw.file, rw.size = file, stat.Size()
+	return nil
+}
+
+// rotate 将当前文件重命名为备份文件, 并重新创建日志文件, 调用方需持有锁
+//
+// 重命名失败时重新打开原文件继续写入, 重新打开也失败时 rw.file 置为 nil
+func (rw *RotateWriter) rotate() error {
+	rw.file.Close()
+	ext := filepath.Ext(rw.path)
+	now := time.Now()
+	backup := strings.TrimSuffix(rw.path, ext) + "-" + now.Format(backupTimeLayout) + ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(backup); err != nil {
+			break
+		}
+		backup = strings.TrimSuffix(rw.path, ext) + "-" + now.Add(time.Duration(i)).Format(backupTimeLayout) + ext
+	}
+
+	renameErr := os.Rename(rw.path, backup)
+	if err := rw.open(); err != nil {
+		rw.file = nil
+		return err
+	}
+	if renameErr != nil {
+		return fmt.Errorf("滚动日志文件失败: %v", renameErr)
+	}
+	go rw.cleanExpired()
+	return nil
+}
+
+// cleanExpired 移除超过保留天数的备份文件
+func (rw *RotateWriter) cleanExpired() {
+	if rw.maxAge <= 0 {
+		return
+	}
+	ext := filepath.Ext(rw.path)
+	prefix := strings.TrimSuffix(filepath.Base(rw.path), ext) + "-"
+	dir := filepath.Dir(rw.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		t, err := time.ParseInLocation(backupTimeLayout, ts, time.Local)
+		if err == nil && time.Since(t) > rw.maxAge {
+			os.Remove(filepath.Join(dir, name))
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package mappings
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Type 映射规则类型
+type Type string
+
+const (
+	TypePrefix   Type = "prefix"   // 前缀替换
+	TypeContains Type = "contains" // 片段替换, 替换第一个出现的片段
+	TypeRegex    Type = "regex"    // 正则替换, 支持捕获组 $1 ${name}
+	TypeTemplate Type = "template" // 模板替换, 支持 {name} 以及 {name*} 占位符
+)
+
+// validTypes 用于校验用户配置的规则类型是否合法
+var validTypes = map[Type]struct{}{
+	TypePrefix: {}, TypeContains: {}, TypeRegex: {}, TypeTemplate: {},
+}
+
+// templatePlaceholderReg 匹配模板中的占位符
+//
+// {name} 匹配单个路径片段, {name*} 匹配剩余的任意字符
+var templatePlaceholderReg = regexp.MustCompile(`\{(\w+)(\*?)\}`)
+
+// Rule 单条映射规则
+//
+// 支持两种配置形式:
+//
+//  1. 字符串: "/movie:/电影" 或 "/movie => /电影"
+//  2. 结构体: { from: /movie, to: /电影, type: prefix, ignore-case: true }
+type Rule struct {
+	// From 匹配的源路径 (前缀, 片段, 正则或模板)
+	From string `yaml:"from"`
+	// To 替换后的目标路径
+	To string `yaml:"to"`
+	// Type 规则类型, 不配置时使用映射器的默认类型
+	Type Type `yaml:"type"`
+	// IgnoreCase 匹配时是否忽略大小写
+	IgnoreCase bool `yaml:"ignore-case"`
+
+	// raw 字符串形式的原始配置
+	raw string
+	// reg 正则和模板规则编译后的表达式
+	reg *regexp.Regexp
+	// to 模板规则转换后的替换串
+	to string
+}
+
+// Parse 将字符串形式的规则转换为 Rule, 规则在编译时才会真正进行解析
+func Parse(raw string) Rule {
+	return Rule{raw: raw}
+}
+
+// Strings 将多个字符串形式的规则转换为 Rule 切片
+func Strings(raws ...string) []Rule {
+	res := make([]Rule, len(raws))
+	for i, raw := range raws {
+		res[i] = Parse(raw)
+	}
+	return res
+}
+
+// UnmarshalYAML 同时兼容字符串和结构体两种配置形式
+func (r *Rule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = Parse(node.Value)
+		return nil
+	}
+
+	type plain Rule
+	var p plain
+	if err := node.Decode(&p); err != nil {
+		return err
+	}
+	*r = Rule(p)
+	return nil
+}
+
+// String 输出规则的可读形式
+func (r *Rule) String() string {
+	if r.raw != "" {
+		return r.raw
+	}
+	s := fmt.Sprintf("[%s] %s => %s", r.Type, r.From, r.To)
+	if r.IgnoreCase {
+		s += " (ignore-case)"
+	}
+	return s
+}
+
+// Compile 校验并编译规则, dft 为规则未指定类型时使用的默认类型
+func (r *Rule) Compile(dft Type) error {
+	if r.raw != "" {
+		from, to, err := splitRaw(r.raw)
+		if err != nil {
+			return err
+		}
+		r.From, r.To = from, to
+	}
+
+	r.Type = Type(strings.ToLower(strings.TrimSpace(string(r.Type))))
+	if r.Type == "" {
+		r.Type = dft
+	}
+	if _, ok := validTypes[r.Type]; !ok {
+		return fmt.Errorf("不支持的映射类型: [%s], 规则: %s", r.Type, r.String())
+	}
+	if r.From == "" {
+		return fmt.Errorf("映射规则的 from 不能为空: %s", r.String())
+	}
+
+	switch r.Type {
+	case TypeRegex:
+		expr := r.From
+		if r.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		reg, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("正则编译失败: %s, err: %v", r.String(), err)
+		}
+		r.reg, r.to = reg, r.To
+	case TypeTemplate:
+		reg, to, err := compileTemplate(r.From, r.To, r.IgnoreCase)
+		if err != nil {
+			return fmt.Errorf("模板编译失败: %s, err: %v", r.String(), err)
+		}
+		r.reg, r.to = reg, to
+	}
+	return nil
+}
+
+// Map 使用当前规则映射路径, 映射失败时返回 false
+//
+// 规则必须先经过 Compile 才能使用
+func (r *Rule) Map(path string) (string, bool) {
+	switch r.Type {
+	case TypePrefix:
+		if r.IgnoreCase {
+			if len(path) < len(r.From) || !strings.EqualFold(path[:len(r.From)], r.From) {
+				return "", false
+			}
+		} else if !strings.HasPrefix(path, r.From) {
+			return "", false
+		}
+		return r.To + path[len(r.From):], true
+	case TypeContains:
+		idx := indexOf(path, r.From, r.IgnoreCase)
+		if idx == -1 {
+			return "", false
+		}
+		return path[:idx] + r.To + path[idx+len(r.From):], true
+	case TypeRegex, TypeTemplate:
+		if r.reg == nil {
+			return "", false
+		}
+		loc := r.reg.FindStringSubmatchIndex(path)
+		if loc == nil {
+			return "", false
+		}
+		dst := r.reg.ExpandString(nil, r.to, path, loc)
+		return path[:loc[0]] + string(dst) + path[loc[1]:], true
+	}
+	return "", false
+}
+
+// Mapper 按配置顺序依次匹配规则的映射器, 至多映射一次
+type Mapper struct {
+	rules []*Rule
+}
+
+// New 编译规则并初始化一个映射器
+//
+// 任意一条规则编译失败都会返回错误
+func New(rules []Rule, dft Type) (*Mapper, error) {
+	m := &Mapper{rules: make([]*Rule, 0, len(rules))}
+	for i := range rules {
+		r := rules[i]
+		if err := r.Compile(dft); err != nil {
+			return nil, err
+		}
+		m.rules = append(m.rules, &r)
+	}
+	return m, nil
+}
+
+// Map 从上到下按顺序匹配规则, 返回第一个成功映射的结果以及命中的规则
+func (m *Mapper) Map(path string) (string, *Rule, bool) {
+	if m == nil {
+		return "", nil, false
+	}
+	for _, r := range m.rules {
+		if res, ok := r.Map(path); ok {
+			return res, r, true
+		}
+	}
+	return "", nil, false
+}
+
+// Rules 返回映射器中所有已编译的规则
+func (m *Mapper) Rules() []*Rule {
+	if m == nil {
+		return nil
+	}
+	return m.rules
+}
+
+// Len 返回映射器中的规则数
+func (m *Mapper) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.rules)
+}
+
+// splitRaw 切割字符串形式的规则
+//
+// 优先使用 => 进行分割, 否则使用 : 分割,
+// 使用 : 分割时会跳过 Windows 盘符 (D:\) 以及 url 协议 (http://) 中的冒号
+func splitRaw(raw string) (string, string, error) {
+	if from, to, ok := strings.Cut(raw, "=>"); ok {
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if from == "" {
+			return "", "", fmt.Errorf("映射配置不规范: %s", raw)
+		}
+		return from, to, nil
+	}
+
+	seps := make([]int, 0, 1)
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != ':' || isDriveColon(raw, i) || strings.HasPrefix(raw[i:], "://") {
+			continue
+		}
+		seps = append(seps, i)
+	}
+	if len(seps) != 1 {
+		return "", "", fmt.Errorf("映射配置不规范: %s, 无法根据 ':' 进行分割, 请使用 '=>' 或者结构化配置", raw)
+	}
+
+	from, to := strings.TrimSpace(raw[:seps[0]]), strings.TrimSpace(raw[seps[0]+1:])
+	if from == "" || to == "" {
+		return "", "", fmt.Errorf("映射配置不规范: %s", raw)
+	}
+	return from, to, nil
+}
+
+// isDriveColon 判断 raw[i] 处的冒号是否属于 Windows 盘符
+func isDriveColon(raw string, i int) bool {
+	if i < 1 || i+1 >= len(raw) {
+		return false
+	}
+	if c := raw[i-1]; (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+		return false
+	}
+	if raw[i+1] != '\\' && raw[i+1] != '/' {
+		return false
+	}
+	// 盘符字母必须位于开头或者紧跟在分隔符之后
+	return i == 1 || raw[i-2] == ':' || raw[i-2] == ' '
+}
+
+// indexOf 查找子串位置, 支持忽略大小写
+func indexOf(s, sub string, ignoreCase bool) int {
+	if !ignoreCase {
+		return strings.Index(s, sub)
+	}
+	return strings.Index(strings.ToLower(s), strings.ToLower(sub))
+}
+
+// compileTemplate 将模板转换为正则表达式以及对应的替换串
+func compileTemplate(from, to string, ignoreCase bool) (*regexp.Regexp, string, error) {
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	if ignoreCase {
+		expr.WriteString("(?i)")
+	}
+
+	names := make(map[string]struct{})
+	last := 0
+	for _, loc := range templatePlaceholderReg.FindAllStringSubmatchIndex(from, -1) {
+		expr.WriteString(regexp.QuoteMeta(from[last:loc[0]]))
+		name := from[loc[2]:loc[3]]
+		if _, ok := names[name]; ok {
+			return nil, "", fmt.Errorf("占位符重复: {%s}", name)
+		}
+		names[name] = struct{}{}
+		if loc[5] > loc[4] {
+			expr.WriteString("(?P<" + name + ">.*)")
+		} else {
+			expr.WriteString("(?P<" + name + ">[^/]+)")
+		}
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(from[last:]))
+
+	reg, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, "", err
+	}
+
+	var tplErr error
+	res := templatePlaceholderReg.ReplaceAllStringFunc(strings.ReplaceAll(to, "$", "$$"), func(s string) string {
+		name := templatePlaceholderReg.FindStringSubmatch(s)[1]
+		if _, ok := names[name]; !ok {
+			tplErr = errors.Join(tplErr, fmt.Errorf("未定义的占位符: {%s}", name))
+		}
+		return "${" + name + "}"
+	})
+	if tplErr != nil {
+		return nil, "", tplErr
+	}
+	return reg, res, nil
+}
@@ -0,0 +1,92 @@
+package mappings_test
+
+import (
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/mappings"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMapperMap(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []mappings.Rule
+		dft    mappings.Type
+		path   string
+		want   string
+		wantOk bool
+	}{
+		{name: "prefix-colon", rules: mappings.Strings("/movie:/电影"), dft: mappings.TypePrefix, path: "/movie/a.mkv", want: "/电影/a.mkv", wantOk: true},
+		{name: "prefix-arrow", rules: mappings.Strings("/movie => /电影"), dft: mappings.TypePrefix, path: "/movie/a.mkv", want: "/电影/a.mkv", wantOk: true},
+		{name: "prefix-miss", rules: mappings.Strings("/movie:/电影"), dft: mappings.TypePrefix, path: "/series/a.mkv", wantOk: false},
+		{name: "prefix-order", rules: mappings.Strings("/movie/4k:/4K", "/movie:/电影"), dft: mappings.TypePrefix, path: "/movie/4k/a.mkv", want: "/4K/a.mkv", wantOk: true},
+		{name: "windows-drive", rules: mappings.Strings(`D:\movie:/电影`), dft: mappings.TypePrefix, path: `D:\movie\a.mkv`, want: `/电影\a.mkv`, wantOk: true},
+		{name: "windows-both-drive", rules: mappings.Strings(`D:\movie:E:\film`), dft: mappings.TypePrefix, path: `D:\movie\a.mkv`, want: `E:\film\a.mkv`, wantOk: true},
+		{name: "contains-url", rules: mappings.Strings("https://test-res.com:8094 => http://localhost:8095"), dft: mappings.TypeContains, path: "https://test-res.com:8094/1.mp4", want: "http://localhost:8095/1.mp4", wantOk: true},
+		{name: "contains-middle", rules: mappings.Strings("12138 => 10086"), dft: mappings.TypeContains, path: "https://test-res.com:12138/a.mp4", want: "https://test-res.com:10086/a.mp4", wantOk: true},
+		{name: "prefix-ignore-case", rules: []mappings.Rule{{From: "/Movie", To: "/电影", IgnoreCase: true}}, dft: mappings.TypePrefix, path: "/MOVIE/a.mkv", want: "/电影/a.mkv", wantOk: true},
+		{name: "regex-groups", rules: []mappings.Rule{{From: `^/media/(movie|show)s?/`, To: "/$1/", Type: mappings.TypeRegex}}, dft: mappings.TypePrefix, path: "/media/movies/a.mkv", want: "/movie/a.mkv", wantOk: true},
+		{name: "regex-ignore-case", rules: []mappings.Rule{{From: `^/media/(?P<kind>movie)`, To: "/${kind}", Type: mappings.TypeRegex, IgnoreCase: true}}, dft: mappings.TypePrefix, path: "/Media/MOVIE/a.mkv", want: "/MOVIE/a.mkv", wantOk: true},
+		{name: "template", rules: []mappings.Rule{{From: "/{disk}/movie/{rest*}", To: "/电影/{disk}/{rest}", Type: mappings.TypeTemplate}}, dft: mappings.TypePrefix, path: "/115/movie/A (2020)/a.mkv", want: "/电影/115/A (2020)/a.mkv", wantOk: true},
+		{name: "template-prefix", rules: []mappings.Rule{{From: "/{disk}/movie", To: "/电影-{disk}", Type: mappings.TypeTemplate}}, dft: mappings.TypePrefix, path: "/115/movie/a.mkv", want: "/电影-115/a.mkv", wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := mappings.New(tt.rules, tt.dft)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			got, _, ok := m.Map(tt.path)
+			if ok != tt.wantOk {
+				t.Fatalf("Map() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if got != tt.want {
+				t.Errorf("Map() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []mappings.Rule
+	}{
+		{name: "no-separator", rules: mappings.Strings("/movie")},
+		{name: "ambiguous-colon", rules: mappings.Strings("/a:/b:/c")},
+		{name: "bad-type", rules: []mappings.Rule{{From: "/a", To: "/b", Type: "glob"}}},
+		{name: "bad-regex", rules: []mappings.Rule{{From: "(", To: "/b", Type: mappings.TypeRegex}}},
+		{name: "undefined-placeholder", rules: []mappings.Rule{{From: "/{a}", To: "/{b}", Type: mappings.TypeTemplate}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := mappings.New(tt.rules, mappings.TypePrefix); err == nil {
+				t.Errorf("New() expect error, got nil")
+			}
+		})
+	}
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	raw := `
+- /movie:/电影
+- from: ^/show/(.*)$
+  to: /电视剧/$1
+  type: regex
+  ignore-case: true
+`
+	var rules []mappings.Rule
+	if err := yaml.Unmarshal([]byte(raw), &rules); err != nil {
+		t.Fatal(err)
+	}
+	m, err := mappings.New(rules, mappings.TypePrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := m.Map("/movie/a.mkv"); got != "/电影/a.mkv" {
+		t.Errorf("Map() = %v", got)
+	}
+	if got, _, _ := m.Map("/SHOW/b.mkv"); got != "/电视剧/b.mkv" {
+		t.Errorf("Map() = %v", got)
+	}
+}
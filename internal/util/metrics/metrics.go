@@ -0,0 +1,252 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType Prometheus 文本格式的响应类型
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets 直方图默认的分桶上界 (秒)
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// 指标类型
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// family 同名指标的集合, 按标签值区分不同的序列
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	fn      func() float64 // 不为空时, 输出时实时计算指标值
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series 一组标签值对应的指标数据
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64 // 直方图各分桶的计数 (非累积)
+	count       uint64
+}
+
+var (
+	// families 已注册的指标, 按注册顺序输出
+	families   []*family
+	familiesMu sync.Mutex
+)
+
+// register 注册指标
+func register(f *family) *family {
+	f.series = map[string]*series{}
+	familiesMu.Lock()
+	defer familiesMu.Unlock()
+	for _, exist := range families {
+		if exist.name == f.name {
+			panic("重复注册的指标: " + f.name)
+		}
+	}
+	families = append(families, f)
+	return f
+}
+
+// with 获取标签值对应的序列, 不存在时创建, 调用方需持有锁
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("指标 %s 的标签数量错误, 期望: %d, 实际: %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues)}
+		if f.kind == kindHistogram {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter 只增不减的计数器
+type Counter struct{ f *family }
+
+// NewCounter 注册一个计数器, labels 为标签名称
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{register(&family{name: name, help: help, kind: kindCounter, labels: labels})}
+}
+
+// Inc 计数加 1, labelValues 需要与注册时的标签一一对应
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 计数增加 v, v 小于 0 时忽略
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	c.f.with(labelValues).value += v
+}
+
+// Gauge 可任意设置的瞬时值
+type Gauge struct{ f *family }
+
+// NewGauge 注册一个瞬时值指标, labels 为标签名称
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{register(&family{name: name, help: help, kind: kindGauge, labels: labels})}
+}
+
+// NewGaugeFunc 注册一个无标签的瞬时值指标, 输出时调用 fn 计算指标值
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(&family{name: name, help: help, kind: kindGauge, fn: fn})
+}
+
+// Set 设置指标值
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.with(labelValues).value = v
+}
+
+// Histogram 直方图, 统计观测值的分布
+type Histogram struct{ f *family }
+
+// NewHistogram 注册一个直方图, buckets 为空时使用 DefaultBuckets
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Histogram{register(&family{name: name, help: help, kind: kindHistogram, labels: labels, buckets: buckets})}
+}
+
+// Observe 记录一次观测值
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.with(labelValues)
+	if i, _ := slices.BinarySearch(h.f.buckets, v); i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.value += v
+	s.count++
+}
+
+// Write 将所有已注册的指标以 Prometheus 文本格式写入 w
+func Write(w io.Writer) error {
+	familiesMu.Lock()
+	fs := slices.Clone(families)
+	familiesMu.Unlock()
+
+	var sb strings.Builder
+	for _, f := range fs {
+		f.writeTo(&sb)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeTo 输出单个指标的所有序列
+func (f *family) writeTo(sb *strings.Builder) {
+	fmt.Fprintf(sb, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(sb, "# TYPE %s %s\n", f.name, f.kind)
+	if f.fn != nil {
+		fmt.Fprintf(sb, "%s %s\n", f.name, formatFloat(f.fn()))
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		labels := formatLabels(f.labels, s.labelValues)
+		if f.kind != kindHistogram {
+			fmt.Fprintf(sb, "%s%s %s\n", f.name, wrapLabels(labels), formatFloat(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += s.counts[i]
+			le := `le="` + formatFloat(upper) + `"`
+			fmt.Fprintf(sb, "%s_bucket%s %d\n", f.name, wrapLabels(joinLabels(labels, le)), cumulative)
+		}
+		fmt.Fprintf(sb, "%s_bucket%s %d\n", f.name, wrapLabels(joinLabels(labels, `le="+Inf"`)), s.count)
+		fmt.Fprintf(sb, "%s_sum%s %s\n", f.name, wrapLabels(labels), formatFloat(s.value))
+		fmt.Fprintf(sb, "%s_count%s %d\n", f.name, wrapLabels(labels), s.count)
+	}
+}
+
+// formatLabels 将标签格式化为 name="value" 的形式, 以逗号分隔
+func formatLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabelValue(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+// joinLabels 追加一个格式化后的标签
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+// wrapLabels 为非空的标签加上花括号
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+// labelValueReplacer 标签值中需要转义的字符
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue 转义标签值
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+// escapeHelp 转义帮助信息
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// formatFloat 按 Prometheus 的格式输出浮点数
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
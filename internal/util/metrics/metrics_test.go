@@ -0,0 +1,51 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+)
+
+func TestWrite(t *testing.T) {
+	counter := metrics.NewCounter("test_requests_total", "请求数", "route", "code")
+	counter.Inc(`^/emby/videos/\d+$`, "302")
+	counter.Add(2, `^/emby/videos/\d+$`, "302")
+	counter.Inc(`say "hi"`, "200")
+
+	gauge := metrics.NewGauge("test_playlists", "播放列表个数")
+	gauge.Set(3)
+
+	metrics.NewGaugeFunc("test_cache_size_bytes", "缓存大小", func() float64 { return 1024 })
+
+	hist := metrics.NewHistogram("test_duration_seconds", "耗时", []float64{0.1, 1}, "route")
+	hist.Observe(0.05, "a")
+	hist.Observe(0.1, "a")
+	hist.Observe(5, "a")
+
+	var sb strings.Builder
+	if err := metrics.Write(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+
+	wants := []string{
+		"# TYPE test_requests_total counter",
+		`test_requests_total{route="^/emby/videos/\\d+$",code="302"} 3`,
+		`test_requests_total{route="say \"hi\"",code="200"} 1`,
+		"# TYPE test_playlists gauge",
+		"test_playlists 3",
+		"test_cache_size_bytes 1024",
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{route="a",le="0.1"} 2`,
+		`test_duration_seconds_bucket{route="a",le="1"} 2`,
+		`test_duration_seconds_bucket{route="a",le="+Inf"} 3`,
+		`test_duration_seconds_sum{route="a"} 5.15`,
+		`test_duration_seconds_count{route="a"} 3`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("输出中缺少: %s\n完整输出:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkAuth 校验管理接口密钥, 校验不通过时直接响应错误
+//
+// 返回 true 表示校验通过
+func checkAuth(c *gin.Context) bool {
+	// 管理接口不缓存
+	c.Header(cache.HeaderKeyExpired, "-1")
+
+	if !config.C.Admin.Enabled() {
+		c.String(http.StatusNotFound, "管理接口未开放")
+		return false
+	}
+
+	if !Authorized(c) {
+		logs.Ctx(c).Warn("管理接口鉴权失败, ip: %s, uri: %s", c.ClientIP(), c.Request.URL.Path)
+		c.String(http.StatusUnauthorized, "管理接口鉴权失败")
+		return false
+	}
+	return true
+}
+
+// Authorized 判断请求是否携带了正确的管理接口密钥, 不会响应任何内容
+func Authorized(c *gin.Context) bool {
+	if !config.C.Admin.Enabled() {
+		return false
+	}
+	token := c.GetHeader(constant.AdminTokenHeader)
+	if token == "" {
+		token = c.Query(constant.AdminTokenQuery)
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(config.C.Admin.Token)) == 1
+}
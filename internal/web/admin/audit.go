@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audit 查询播放审计日志
+//
+// query 参数: from, to 日期范围 (格式 2006-01-02, 默认当天), user 用户 id,
+// format 为 csv 时以 csv 文件导出, 否则响应 json
+func Audit(c *gin.Context) {
+	if !checkAuth(c) {
+		return
+	}
+	if !config.C.Audit.Enable {
+		c.String(http.StatusNotFound, "审计日志未启用")
+		return
+	}
+
+	from, to, err := audit.ParseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	records, err := audit.Query(config.C.Audit.Dir, from, to, c.Query("user"))
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, records)
+		return
+	}
+	fileName := fmt.Sprintf("audit_%s_%s.csv", from.Format(audit.DateLayout), to.Format(audit.DateLayout))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	c.Status(http.StatusOK)
+	if err := audit.WriteCSV(c.Writer, records); err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Breakers 查询所有 openlist 熔断器的状态
+func Breakers(c *gin.Context) {
+	if !checkAuth(c) {
+		return
+	}
+	c.JSON(http.StatusOK, openlist.Breakers())
+}
+
+// ResetBreakers 手动恢复 openlist 熔断器
+//
+// query 参数 key 指定要恢复的熔断器, 不传则恢复所有熔断器
+func ResetBreakers(c *gin.Context) {
+	if !checkAuth(c) {
+		return
+	}
+	if c.Request.Method != http.MethodPost {
+		c.String(http.StatusMethodNotAllowed, "请使用 POST 请求")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reset": openlist.ResetBreaker(c.Query("key"))})
+}
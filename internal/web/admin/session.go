@@ -0,0 +1,17 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sessions 查询所有活跃的播放会话
+func Sessions(c *gin.Context) {
+	if !checkAuth(c) {
+		return
+	}
+	c.JSON(http.StatusOK, emby.Sessions())
+}
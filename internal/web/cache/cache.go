@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/encrypts"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
@@ -32,7 +34,7 @@ var CacheKeyIgnoreParams = map[string]struct{}{
 	// Common
 	"Range": {}, "Host": {}, "Referrer": {}, "Connection": {},
 	"Accept": {}, "Accept-Encoding": {}, "Accept-Language": {}, "Cache-Control": {},
-	"Upgrade-Insecure-Requests": {}, "Referer": {}, "Origin": {},
+	"Upgrade-Insecure-Requests": {}, "Referer": {}, "Origin": {}, "X-Request-Id": {},
 
 	// StreamMusic
 	"X-Streammusic-Audioid": {}, "X-Streammusic-Savepath": {},
@@ -66,8 +68,23 @@ func CacheableRouteMarker() gin.HandlerFunc {
 	}
 }
 
+// HitHook 缓存命中钩子, 在回放匹配 Pattern 的缓存响应之前执行
+type HitHook struct {
+	// Pattern 匹配请求 uri 的正则表达式
+	Pattern string
+	// Handle 钩子处理函数, code, header 为缓存的响应信息, meta 为处理器通过
+	// constant.CacheMetaGinKey 附加的信息; 调用 c.Abort 之后不再回放缓存的响应
+	Handle func(c *gin.Context, code int, header http.Header, meta any)
+}
+
 // RequestCacher 请求缓存中间件
-func RequestCacher() gin.HandlerFunc {
+//
+// hooks 用于在缓存命中时补充处理器中的副作用 (如会话校验, 审计日志)
+func RequestCacher(hooks ...HitHook) gin.HandlerFunc {
+	patterns := make([]*regexp.Regexp, len(hooks))
+	for i, hook := range hooks {
+		patterns[i] = regexp.MustCompile(hook.Pattern)
+	}
 	return func(c *gin.Context) {
 		// 1 判断请求是否需要缓存
 		if c.Writer.Header().Get(HeaderKeyExpired) == "-1" {
@@ -84,6 +101,15 @@ func RequestCacher() gin.HandlerFunc {
 
 		// 3 尝试获取缓存
 		if rc, ok := getCache(cacheKey); ok {
+			cacheHits.Inc()
+			for i, hook := range hooks {
+				if patterns[i].MatchString(c.Request.RequestURI) {
+					hook.Handle(c, rc.code, rc.header.header, rc.header.meta)
+				}
+			}
+			if c.IsAborted() {
+				return
+			}
 			if https.IsRedirectCode(rc.code) {
 				// 适配重定向请求
 				c.Redirect(rc.code, rc.header.header.Get("Location"))
@@ -96,6 +122,8 @@ func RequestCacher() gin.HandlerFunc {
 			return
 		}
 
+		cacheMisses.Inc()
+
 		// 4 使用自定义的响应器
 		customWriter := &respCacheWriter{body: &bytes.Buffer{}, ResponseWriter: c.Writer}
 		c.Writer = customWriter
@@ -116,6 +144,10 @@ func RequestCacher() gin.HandlerFunc {
 			spaceKey: header.Get(HeaderKeySpaceKey),
 			header:   header.Clone(),
 		}
+		respHeader.meta, _ = c.Get(constant.CacheMetaGinKey)
+		// 请求 id 以及追踪信息只属于当前请求
+		respHeader.header.Del(constant.RequestIdHeader)
+		respHeader.header.Del(constant.TraceHeader)
 		defer header.Del(HeaderKeyExpired)
 		defer header.Del(HeaderKeySpace)
 		defer header.Del(HeaderKeySpaceKey)
@@ -172,7 +204,7 @@ func calcCacheKey(c *gin.Context) (string, error) {
 	headerStr := header.String()
 	preEnc := strs.Sort(c.Request.URL.RawQuery + body + headerStr)
 	if headerStr != "" {
-		logs.Tip("headers to encode cacheKey: %s", colors.ToYellow(headerStr))
+		logs.Ctx(c).Tip("headers to encode cacheKey: %s", colors.ToYellow(headerStr))
 	}
 
 	// 为防止字典排序后, 不同的 uri 冲突, 这里在排序完的字符串前再加上原始的 uri
@@ -182,6 +214,12 @@ func calcCacheKey(c *gin.Context) (string, error) {
 		c.Request.URL.RawQuery, "",
 	)
 
-	hash := encrypts.Md5Hash(method + uriNoArgs + preEnc)
+	// 多 emby 后端时, 不同后端的相同请求不能共用缓存
+	backend := ""
+	if e, ok := c.Get(constant.EmbyBackendGinKey); ok {
+		backend = e.(*config.Emby).Name
+	}
+
+	hash := encrypts.Md5Hash(backend + method + uriNoArgs + preEnc)
 	return hash, nil
 }
@@ -3,6 +3,7 @@ package cache
 import (
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
@@ -26,6 +27,8 @@ const (
 )
 
 // currentCacheSize 当前内存中的缓存大小 (Byte)
+//
+// 由维护缓存的 goroutine 更新, 其他 goroutine 需要原子读取
 var currentCacheSize int64 = 0
 
 // DefaultExpired 默认的请求过期时间
@@ -61,7 +64,7 @@ func loopMaintainCache() {
 
 		cacheMap.Range(func(key, value any) bool {
 			rc := value.(*respCache)
-			if nowMillis > rc.expired || validCnt == MaxCacheNum || currentCacheSize > MaxCacheSize {
+			if nowMillis > rc.expired || validCnt == MaxCacheNum || atomic.LoadInt64(&currentCacheSize) > MaxCacheSize {
 				toDelete = append(toDelete, rc)
 			} else {
 				validCnt++
@@ -71,7 +74,7 @@ func loopMaintainCache() {
 
 		for _, rc := range toDelete {
 			cacheMap.Delete(rc.cacheKey)
-			currentCacheSize -= int64(len(rc.body))
+			atomic.AddInt64(&currentCacheSize, -int64(len(rc.body)))
 			delSpaceCache(rc.header.space, rc.header.spaceKey)
 		}
 	}
@@ -81,7 +84,7 @@ func loopMaintainCache() {
 	// 同时淘汰掉过期缓存
 	putrespCache := func(rc *respCache) {
 		cacheMap.Store(rc.cacheKey, rc)
-		currentCacheSize += int64(len(rc.body))
+		atomic.AddInt64(&currentCacheSize, int64(len(rc.body)))
 		space, spaceKey := rc.header.space, rc.header.spaceKey
 		if strs.AllNotEmpty(space, spaceKey) {
 			putSpaceCache(space, spaceKey, rc)
@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"sync/atomic"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+)
+
+var (
+	// cacheHits 命中缓存的请求数
+	cacheHits = metrics.NewCounter("ge2o_cache_hits_total", "命中请求缓存的请求数")
+
+	// cacheMisses 未命中缓存的请求数
+	cacheMisses = metrics.NewCounter("ge2o_cache_misses_total", "未命中请求缓存的请求数")
+)
+
+func init() {
+	metrics.NewGaugeFunc("ge2o_cache_entries", "内存中的请求缓存个数", func() float64 {
+		cnt := 0
+		cacheMap.Range(func(key, value any) bool {
+			cnt++
+			return true
+		})
+		return float64(cnt)
+	})
+	metrics.NewGaugeFunc("ge2o_cache_size_bytes", "内存中的请求缓存响应体总大小 (Byte)", func() float64 {
+		return float64(atomic.LoadInt64(&currentCacheSize))
+	})
+}
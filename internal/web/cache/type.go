@@ -49,6 +49,7 @@ type respHeader struct {
 	space    string      // 缓存空间名称
 	spaceKey string      // 缓存空间 key
 	header   http.Header // 原始请求的克隆请求头
+	meta     any         // 处理器附加的信息, 缓存命中时交给钩子处理
 }
 
 // Code 响应码
@@ -0,0 +1,201 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist/localtree"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/admin"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 检查结果状态
+const (
+	HealthOk   = "ok"
+	HealthWarn = "warn" // 不影响就绪状态的警告, 如证书即将过期
+	HealthFail = "fail"
+)
+
+// SslExpiryWarnDays 证书剩余有效天数低于该值时, 检查结果为 warn
+const SslExpiryWarnDays = 7
+
+// startTime 程序启动时间
+var startTime = time.Now()
+
+// HealthCheck 单项依赖的检查结果
+type HealthCheck struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Detail    any    `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Healthz 存活探针, 进程能够响应请求即为存活
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  HealthOk,
+		"version": constant.CurrentVersion,
+		"uptime":  time.Since(startTime).Truncate(time.Second).String(),
+	})
+}
+
+// ReadyzCacheTtl 就绪检查结果的缓存时间, 避免频繁探测时反复请求 emby 和 openlist
+const ReadyzCacheTtl = 5 * time.Second
+
+var (
+	// readyzResults 缓存的就绪检查结果
+	readyzResults []HealthCheck
+	// readyzCheckedAt 就绪检查结果的生成时间
+	readyzCheckedAt time.Time
+	// readyzMu 同一时间只执行一次就绪检查
+	readyzMu sync.Mutex
+)
+
+// Readyz 就绪探针, 检查 emby 可用性, openlist token 有效性,
+// 本地目录树首次同步状态以及 ssl 证书有效期, 任意一项失败时响应 503
+//
+// 检查结果缓存 ReadyzCacheTtl, 未携带管理接口密钥时隐藏错误详情, 避免暴露内部地址
+func Readyz(c *gin.Context) {
+	results := cachedReadiness()
+	authorized := admin.Authorized(c)
+
+	status, code := HealthOk, http.StatusOK
+	for i, res := range results {
+		if res.Status == HealthFail {
+			status, code = HealthFail, http.StatusServiceUnavailable
+		}
+		if !authorized {
+			res.Detail = nil
+			if res.Error != "" {
+				res.Error = "检查失败, 携带管理接口密钥可查看详细信息"
+			}
+			results[i] = res
+		}
+	}
+	c.JSON(code, gin.H{"status": status, "checks": results})
+}
+
+// cachedReadiness 获取就绪检查结果的副本, 缓存过期时重新检查
+func cachedReadiness() []HealthCheck {
+	readyzMu.Lock()
+	defer readyzMu.Unlock()
+	if readyzResults == nil || time.Since(readyzCheckedAt) >= ReadyzCacheTtl {
+		readyzResults, readyzCheckedAt = runReadinessChecks(), time.Now()
+	}
+	return slices.Clone(readyzResults)
+}
+
+// runReadinessChecks 并发执行所有检查项
+func runReadinessChecks() []HealthCheck {
+	checks := readinessChecks()
+	results := make([]HealthCheck, len(checks))
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			res := check.fn()
+			res.Name = check.name
+			res.LatencyMs = time.Since(start).Milliseconds()
+			if res.Status == "" {
+				res.Status = HealthOk
+			}
+			results[i] = res
+		}()
+	}
+	wg.Wait()
+
+	for _, res := range results {
+		if res.Status == HealthFail {
+			logs.Warn("就绪检查失败 [%s]: %s", res.Name, res.Error)
+		}
+	}
+	return results
+}
+
+// namedCheck 具名的检查项
+type namedCheck struct {
+	name string
+	fn   func() HealthCheck
+}
+
+// readinessChecks 根据配置生成需要执行的检查项
+func readinessChecks() []namedCheck {
+	checks := make([]namedCheck, 0)
+
+	for _, e := range config.C.Emby.All() {
+		checks = append(checks, namedCheck{"emby:" + e.Name, func() HealthCheck {
+			return errCheck(emby.Ping(e))
+		}})
+	}
+
+	for _, ins := range config.C.Openlist.Instances {
+		checks = append(checks, namedCheck{"openlist:" + ins.Name, func() HealthCheck {
+			return errCheck(openlist.CheckToken(ins))
+		}})
+	}
+
+	if config.C.Openlist.LocalTreeGen.Enable {
+		checks = append(checks, namedCheck{"localtree", checkLocalTree})
+	}
+
+	if config.C.Ssl.Enable {
+		checks = append(checks, namedCheck{"ssl", checkSslCert})
+	}
+	return checks
+}
+
+// errCheck 根据错误生成检查结果
+func errCheck(err error) HealthCheck {
+	if err != nil {
+		return HealthCheck{Status: HealthFail, Error: err.Error()}
+	}
+	return HealthCheck{Status: HealthOk}
+}
+
+// checkLocalTree 本地目录树完成首次同步后才算就绪
+func checkLocalTree() HealthCheck {
+	status := localtree.Status()
+	res := HealthCheck{Status: HealthOk, Detail: status}
+	if !status.Synced {
+		res.Status, res.Error = HealthFail, "目录树尚未完成首次同步"
+	}
+	return res
+}
+
+// checkSslCert 检查 ssl 证书有效期, 已过期时检查失败, 即将过期时警告
+func checkSslCert() HealthCheck {
+	ssl := config.C.Ssl
+	pair, err := tls.LoadX509KeyPair(ssl.CrtPath(), ssl.KeyPath())
+	if err != nil {
+		return HealthCheck{Status: HealthFail, Error: fmt.Sprintf("读取证书失败: %v", err)}
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return HealthCheck{Status: HealthFail, Error: fmt.Sprintf("解析证书失败: %v", err)}
+	}
+
+	daysLeft := int(time.Until(leaf.NotAfter).Hours() / 24)
+	res := HealthCheck{Status: HealthOk, Detail: gin.H{"expires_at": leaf.NotAfter, "days_left": daysLeft}}
+	switch {
+	case time.Now().After(leaf.NotAfter):
+		res.Status, res.Error = HealthFail, "证书已过期"
+	case daysLeft < SslExpiryWarnDays:
+		res.Status = HealthWarn
+	}
+	return res
+}
@@ -0,0 +1,104 @@
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupHealth 初始化一个响应 503 的模拟 emby 服务器, 返回请求计数
+func setupHealth(t *testing.T, protectMetrics bool) *atomic.Int32 {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	gin.SetMode(gin.TestMode)
+	e := &config.Emby{Host: srv.URL}
+	if err := e.Init(); err != nil {
+		t.Fatal(err)
+	}
+	config.C = &config.Config{
+		Emby:     e,
+		Openlist: &config.Openlist{LocalTreeGen: &config.LocalTreeGen{}},
+		Ssl:      &config.Ssl{},
+		Admin:    &config.Admin{Token: "secret", ProtectMetrics: protectMetrics},
+	}
+	return &hits
+}
+
+// serve 使用处理器 handler 处理请求, adminToken 不为空时携带管理接口密钥
+func serve(handler gin.HandlerFunc, uri, adminToken string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, uri, nil)
+	if adminToken != "" {
+		c.Request.Header.Set(constant.AdminTokenHeader, adminToken)
+	}
+	handler(c)
+	return w
+}
+
+func TestReadyz(t *testing.T) {
+	hits := setupHealth(t, false)
+
+	w := serve(web.Readyz, "/ge2o/readyz", "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("响应码 = %d, want 503", w.Code)
+	}
+	var res struct {
+		Status string
+		Checks []web.HealthCheck
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Checks) != 1 || res.Checks[0].Status != web.HealthFail {
+		t.Fatalf("检查结果 = %+v", res.Checks)
+	}
+	if strings.Contains(w.Body.String(), "503") || strings.Contains(w.Body.String(), "127.0.0.1") {
+		t.Errorf("未鉴权的响应暴露了错误详情: %s", w.Body.String())
+	}
+
+	// 缓存时间内不重复检查, 携带密钥时返回错误详情
+	w = serve(web.Readyz, "/ge2o/readyz", "secret")
+	if hits.Load() != 1 {
+		t.Errorf("emby 请求次数 = %d, want 1", hits.Load())
+	}
+	if !strings.Contains(w.Body.String(), "503") {
+		t.Errorf("鉴权后的响应缺少错误详情: %s", w.Body.String())
+	}
+}
+
+func TestServeMetricsAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		protectMetrics bool
+		adminToken     string
+		want           int
+	}{
+		{name: "未开启保护", protectMetrics: false, want: http.StatusOK},
+		{name: "缺少密钥", protectMetrics: true, want: http.StatusUnauthorized},
+		{name: "密钥错误", protectMetrics: true, adminToken: "wrong", want: http.StatusUnauthorized},
+		{name: "密钥正确", protectMetrics: true, adminToken: "secret", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHealth(t, tt.protectMetrics)
+			if w := serve(web.ServeMetrics, "/metrics", tt.adminToken); w.Code != tt.want {
+				t.Errorf("响应码 = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,13 @@
 package web
 
 import (
-	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 	"github.com/gin-gonic/gin"
 )
@@ -19,16 +20,26 @@ func CustomLogger(port string) gin.HandlerFunc {
 		c.Next()
 
 		// 记录日志
-		fmt.Printf("%s %s | %s | %s | %s | %s %s | %s %s\n",
-			colors.ToYellow("[ge2o:"+constant.CurrentVersion+"]"),
-			start.Format("2006-01-02 15:04:05"),
-			colorStatusCode(c.Writer.Status()),
-			time.Since(start),
+		latency := time.Since(start)
+		status := c.Writer.Status()
+		route := c.GetString(MatchRouteKey)
+		uri := logs.Redact(c.Request.RequestURI)
+		line := strings.Join([]string{
+			colors.ToYellow("[ge2o:" + constant.CurrentVersion + "]"),
+			colorStatusCode(status),
+			latency.String(),
 			c.ClientIP(),
-			colors.ToBlue(port),
-			colors.ToBlue(c.GetString(MatchRouteKey)),
-			colors.ToBlue(c.Request.Method),
-			c.Request.RequestURI,
+			colors.ToBlue(port) + " " + colors.ToBlue(route),
+			colors.ToBlue(c.Request.Method) + " " + uri,
+		}, " | ")
+		logs.Ctx(c).Access(line,
+			logs.F("status", status),
+			logs.F("latency_ms", float64(latency.Microseconds())/1000),
+			logs.F("ip", c.ClientIP()),
+			logs.F("port", port),
+			logs.F("route", route),
+			logs.F("method", c.Request.Method),
+			logs.F("uri", uri),
 		)
 	}
 }
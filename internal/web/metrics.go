@@ -0,0 +1,56 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/admin"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// httpRequests 按匹配路由统计的请求数
+	httpRequests = metrics.NewCounter("ge2o_http_requests_total", "按匹配路由统计的请求数", "route", "method", "code")
+
+	// httpRequestDuration 按匹配路由统计的请求耗时
+	httpRequestDuration = metrics.NewHistogram("ge2o_http_request_duration_seconds", "按匹配路由统计的请求耗时 (秒)", nil, "route")
+)
+
+// RouteUnmatched 未匹配到路由规则的请求 (如命中请求缓存, 被中间件拦截) 的路由标签
+const RouteUnmatched = "unmatched"
+
+// MetricsRecorder 记录请求数以及请求耗时
+func MetricsRecorder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.GetString(MatchRouteKey)
+		if route == "" {
+			route = RouteUnmatched
+		}
+		httpRequests.Inc(route, c.Request.Method, strconv.Itoa(c.Writer.Status()))
+		httpRequestDuration.Observe(time.Since(start).Seconds(), route)
+	}
+}
+
+// ServeMetrics 以 Prometheus 文本格式响应所有指标
+//
+// 开启 admin.protect-metrics 后需要携带管理接口密钥
+func ServeMetrics(c *gin.Context) {
+	if config.C.Admin.ProtectMetrics && !admin.Authorized(c) {
+		logs.Ctx(c).Warn("指标接口鉴权失败, ip: %s", c.ClientIP())
+		c.String(http.StatusUnauthorized, "指标接口鉴权失败")
+		return
+	}
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", metrics.ContentType)
+	if err := metrics.Write(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
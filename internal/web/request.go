@@ -0,0 +1,123 @@
+package web
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/randoms"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/admin"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxRequestIdLen 客户端传递的请求 id 最大长度, 超出时重新生成
+	maxRequestIdLen = 128
+
+	// maxTraceLines 单个请求最多追踪的日志行数
+	maxTraceLines = 200
+
+	// maxTraceBytes 追踪信息响应头的总字节数上限, 避免超出反向代理的响应头大小限制 (nginx 默认 4k ~ 8k)
+	maxTraceBytes = 3 * 1024
+)
+
+// RequestIdSetter 为请求分配请求 id, 并绑定到请求的 context 中,
+// 通过 logs.Ctx(c) 输出的日志会附带上请求 id
+//
+// 优先使用客户端传递的 X-Request-Id 请求头;
+// 携带管理接口密钥以及 X-Ge2o-Trace 请求头时, 开启追踪模式,
+// 请求处理过程中的所有日志会以 X-Ge2o-Trace 响应头返回
+func RequestIdSetter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(constant.RequestIdHeader)
+		if !validRequestId(id) {
+			id = randoms.RandomHex(16)
+		}
+		c.Set(constant.RequestIdGinKey, id)
+		c.Header(constant.RequestIdHeader, id)
+		// 代理到 emby 以及转发给 openlist 的请求会复制客户端请求头, 一并传递请求 id
+		c.Request.Header.Set(constant.RequestIdHeader, id)
+
+		var tw *traceWriter
+		var trace *logs.Trace
+		if c.GetHeader(constant.TraceHeader) != "" && admin.Authorized(c) {
+			trace = logs.NewTrace(maxTraceLines)
+			tw = &traceWriter{ResponseWriter: c.Writer, trace: trace}
+			c.Writer = tw
+		}
+		c.Request = c.Request.WithContext(logs.WithRequest(c.Request.Context(), id, trace))
+
+		c.Next()
+
+		if tw != nil {
+			// 处理器没有写入响应体时, 由 gin 在最后写入响应头
+			tw.writeTrace()
+		}
+	}
+}
+
+// validRequestId 校验客户端传递的请求 id, 只允许可见的 ascii 字符
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// traceWriter 在写入响应头之前, 将追踪的日志写入响应头
+type traceWriter struct {
+	gin.ResponseWriter
+	trace *logs.Trace
+	once  sync.Once
+}
+
+// writeTrace 将追踪的日志写入响应头, 只会执行一次, 响应头已发送时忽略
+func (tw *traceWriter) writeTrace() {
+	tw.once.Do(func() {
+		if tw.ResponseWriter.Written() {
+			return
+		}
+		header := tw.Header()
+		lines, size := tw.trace.Lines(), 0
+		for i, line := range lines {
+			// 每个响应头额外占用 ": " 以及 "\r\n" 4 个字节
+			size += len(constant.TraceHeader) + len(line) + 4
+			if size > maxTraceBytes {
+				header.Add(constant.TraceHeader, "... "+strconv.Itoa(len(lines)-i)+" more lines truncated")
+				break
+			}
+			header.Add(constant.TraceHeader, line)
+		}
+	})
+}
+
+// WriteHeaderNow 发送响应头
+func (tw *traceWriter) WriteHeaderNow() {
+	tw.writeTrace()
+	tw.ResponseWriter.WriteHeaderNow()
+}
+
+// Write 写入响应体
+func (tw *traceWriter) Write(data []byte) (int, error) {
+	tw.writeTrace()
+	return tw.ResponseWriter.Write(data)
+}
+
+// WriteString 写入字符串响应体
+func (tw *traceWriter) WriteString(s string) (int, error) {
+	tw.writeTrace()
+	return tw.ResponseWriter.WriteString(s)
+}
+
+// Flush 刷新响应
+func (tw *traceWriter) Flush() {
+	tw.writeTrace()
+	tw.ResponseWriter.Flush()
+}
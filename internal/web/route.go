@@ -5,6 +5,7 @@ import (
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/m3u8"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/admin"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +21,22 @@ func initRulePatterns() {
 		// websocket
 		{constant.Reg_Socket, emby.ProxySocket()},
 
+		// 管理接口
+		{constant.Reg_AdminBreakersReset, admin.ResetBreakers},
+		{constant.Reg_AdminBreakers, admin.Breakers},
+		{constant.Reg_AdminSessions, admin.Sessions},
+		{constant.Reg_AdminAudit, admin.Audit},
+
+		// 接收 emby webhook 事件
+		{constant.Reg_Webhook, emby.HandleWebhook},
+
+		// Prometheus 指标
+		{constant.Reg_Metrics, ServeMetrics},
+
+		// 存活与就绪探针
+		{constant.Reg_Healthz, Healthz},
+		{constant.Reg_Readyz, Readyz},
+
 		// PlaybackInfo 接口
 		{constant.Reg_PlaybackInfo, emby.TransferPlaybackInfo},
 
@@ -30,6 +47,7 @@ func initRulePatterns() {
 
 		// Items 接口
 		{constant.Reg_UserItems, emby.LoadCacheItems},
+		{constant.Reg_JellyfinItems, emby.LoadCacheItems},
 		// 代理 Items 并添加转码版本信息
 		{constant.Reg_UserEpisodeItems, emby.ProxyAddItemsPreviewInfo},
 		// 随机列表接口
@@ -1,9 +1,13 @@
 package web
 
 import (
+	"context"
 	"crypto/tls"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
@@ -14,80 +18,135 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// server 监听中的服务
+type server struct {
+	name string // 服务名称, 用于日志输出
+	port string // 监听端口
+	srv  *http.Server
+	tls  bool // 是否为 https 服务
+}
+
+// serve 开始监听, 直到服务被关闭
+func (s *server) serve() error {
+	logs.Info("在端口【%s】上启动 %s 服务", s.port, s.name)
+	if !s.tls {
+		return s.srv.ListenAndServe()
+	}
+	ssl := config.C.Ssl
+	return s.srv.ListenAndServeTLS(ssl.CrtPath(), ssl.KeyPath())
+}
+
 // Listen 监听指定端口
-func Listen() error {
+//
+// ctx 被取消时停止接收新的连接, 并在 drainTimeout 内等待处理中的请求完成,
+// 超时后强制关闭剩余的连接; 任意服务监听失败时, 同样关闭所有服务并返回错误
+func Listen(ctx context.Context, drainTimeout time.Duration) error {
 	initRulePatterns()
 
-	errChanHTTP, errChanHTTPS := make(chan error, 1), make(chan error, 1)
+	servers := make([]*server, 0)
 	if !config.C.Ssl.Enable {
-		go listenHTTP(errChanHTTP)
+		servers = append(servers, newHTTPServer(webport.HTTP))
 	} else if config.C.Ssl.SinglePort {
-		go listenHTTPS(errChanHTTPS)
+		servers = append(servers, newHTTPSServer())
 	} else {
-		go listenHTTP(errChanHTTP)
-		go listenHTTPS(errChanHTTPS)
+		servers = append(servers, newHTTPServer(webport.HTTP), newHTTPSServer())
 	}
 
+	// 为配置了独立端口的 emby 后端额外监听 http 服务
+	for _, eb := range config.C.Emby.Backends {
+		if eb.Port != "" {
+			servers = append(servers, newHTTPServer(eb.Port))
+		}
+	}
+
+	errChan := make(chan error, len(servers))
+	for _, s := range servers {
+		go func() {
+			if err := s.serve(); !errors.Is(err, http.ErrServerClosed) {
+				errChan <- fmt.Errorf("%s 服务异常: %v", s.name, err)
+			}
+		}()
+	}
+
+	var serveErr error
 	select {
-	case err := <-errChanHTTP:
-		log.Fatal("http 服务异常: ", err)
-	case err := <-errChanHTTPS:
-		log.Fatal("https 服务异常: ", err)
+	case <-ctx.Done():
+		logs.Info("收到退出信号, 停止接收新的请求, 等待处理中的请求完成...")
+	case serveErr = <-errChan:
+		logs.Error("%v, 正在关闭所有服务...", serveErr)
 	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.srv.Shutdown(shutdownCtx); err != nil {
+				s.srv.Close()
+				errs[i] = fmt.Errorf("%s 服务: %v", s.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if serveErr != nil {
+		return serveErr
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("等待处理中的请求超时, 已强制关闭剩余连接: %v", err)
+	}
+	logs.Success("所有服务已停止")
 	return nil
 }
 
 // initRouter 初始化路由引擎
 func initRouter(r *gin.Engine) {
 	r.Use(referrerPolicySetter())
+	r.Use(emby.BackendSelector())
 	r.Use(emby.ApiKeyChecker())
 	r.Use(emby.DownloadStrategyChecker())
 	if config.C.Cache.Enable {
 		r.Use(cache.CacheableRouteMarker())
-		r.Use(cache.RequestCacher())
+		r.Use(cache.RequestCacher(emby.CacheHitHooks()...))
 	}
 	initRoutes(r)
 }
 
-// listenHTTP 在指定端口上监听 http 服务
-//
-// 出现错误时, 会写入 errChan 中
-func listenHTTP(errChan chan error) {
+// newEngine 初始化指定端口的路由引擎
+func newEngine(port string) *gin.Engine {
 	r := gin.New()
+	// gin.Context 作为 context.Context 使用时, 读取请求 context 中的值 (如请求 id)
+	r.ContextWithFallback = true
 	r.Use(gin.Recovery())
-	r.Use(CustomLogger(webport.HTTP))
+	r.Use(RequestIdSetter())
+	r.Use(CustomLogger(port))
+	r.Use(MetricsRecorder())
 	r.Use(func(c *gin.Context) {
-		c.Set(webport.GinKey, webport.HTTP)
+		c.Set(webport.GinKey, port)
 	})
 	initRouter(r)
-	logs.Info("在端口【%s】上启动 HTTP 服务", webport.HTTP)
-	err := r.Run("0.0.0.0:" + webport.HTTP)
-	errChan <- err
-	close(errChan)
+	return r
 }
 
-// listenHTTPS 在指定端口上监听 https 服务
-//
-// 出现错误时, 会写入 errChan 中
-func listenHTTPS(errChan chan error) {
-	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(CustomLogger(webport.HTTPS))
-	r.Use(func(c *gin.Context) {
-		c.Set(webport.GinKey, webport.HTTPS)
-	})
-	initRouter(r)
-	logs.Info("在端口【%s】上启动 HTTPS 服务", webport.HTTPS)
-	ssl := config.C.Ssl
+// newHTTPServer 初始化指定端口上的 http 服务
+func newHTTPServer(port string) *server {
+	return &server{
+		name: "HTTP",
+		port: port,
+		srv:  &http.Server{Addr: "0.0.0.0:" + port, Handler: newEngine(port)},
+	}
+}
 
+// newHTTPSServer 初始化 https 服务
+func newHTTPSServer() *server {
 	srv := &http.Server{
 		Addr:    "0.0.0.0:" + webport.HTTPS,
-		Handler: r,
+		Handler: newEngine(webport.HTTPS),
 	}
 	// 禁用 HTTP/2
 	srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
-
-	err := srv.ListenAndServeTLS(ssl.CrtPath(), ssl.KeyPath())
-	errChan <- err
-	close(errChan)
+	return &server{name: "HTTPS", port: webport.HTTPS, srv: srv, tls: true}
 }
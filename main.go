@@ -1,27 +1,38 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/audit"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist/localtree"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/path"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
 	"github.com/gin-gonic/gin"
 )
 
 var ginMode = gin.DebugMode
 
+// shutdownTimeout 程序退出时等待处理中的请求以及后台任务完成的最长时间
+var shutdownTimeout = 20 * time.Second
+
 func main() {
 	go func() { http.ListenAndServe(":60360", nil) }()
 
@@ -38,11 +49,55 @@ func main() {
 		log.Fatal(colors.ToRed(err.Error()))
 	}
 
+	go func() {
+		if err := emby.DiscoverPathMappings(); err != nil {
+			logs.Warn("[映射探测] 探测失败: %v", err)
+		}
+	}()
+
 	logs.Info("正在启动服务...")
 	gin.SetMode(ginMode)
-	if err := web.Listen(); err != nil {
-		log.Fatal(colors.ToRed(err.Error()))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	code := 0
+	if err := web.Listen(ctx, shutdownTimeout); err != nil {
+		logs.Error("%v", err)
+		code = 1
+	}
+	stop()
+	if !cleanup() {
+		code = 1
+	}
+	os.Exit(code)
+}
+
+// cleanup 服务停止后, 停止后台任务并将内存中的状态写入磁盘
+//
+// 返回 false 表示存在未能正常完成的清理步骤
+func cleanup() bool {
+	ok := true
+	logs.Info("正在停止本地目录树同步...")
+	if err := localtree.Stop(shutdownTimeout); err != nil {
+		logs.Error("%v", err)
+		ok = false
+	}
+
+	logs.Info("正在写入缓存以及运行状态...")
+	cache.WaitingForHandleChan()
+	emby.SaveSessions()
+	emby.SaveResolutions()
+	path.SaveLearned()
+	path.SaveResolved()
+	if err := audit.Close(); err != nil {
+		logs.Error("关闭审计日志失败: %v", err)
+		ok = false
 	}
+
+	if ok {
+		logs.Success("程序已退出")
+	}
+	return ok
 }
 
 // parseFlag 转换命令行参数
@@ -51,6 +106,7 @@ func parseFlag() (dataRoot string) {
 	phs := flag.Int("ps", 8094, "HTTPS 服务监听端口")
 	printVersion := flag.Bool("version", false, "查看程序版本")
 	dr := flag.String("dr", ".", "程序数据根目录")
+	st := flag.Int("st", 20, "程序退出时等待处理中的请求完成的最长时间 (秒)")
 	flag.Parse()
 
 	if *printVersion {
@@ -72,6 +128,9 @@ func parseFlag() (dataRoot string) {
 	}
 	webport.HTTP = strconv.Itoa(*ph)
 	webport.HTTPS = strconv.Itoa(*phs)
+	if *st > 0 {
+		shutdownTimeout = time.Duration(*st) * time.Second
+	}
 	return
 }
 